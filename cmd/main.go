@@ -1,24 +1,29 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"log"
-	"tasked/internal/auth"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"tasked/internal/clock"
 	"tasked/internal/config"
-	"tasked/internal/handler"
-	"tasked/internal/middleware"
+	"tasked/internal/health"
+	"tasked/internal/logging"
+	"tasked/internal/ratelimit"
 	"tasked/internal/repository"
-	"tasked/internal/services"
+	"tasked/internal/server"
+	"tasked/internal/storage"
+	"tasked/internal/tracing"
+	"time"
 
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
 
 	_ "tasked/docs"
-
-	swaggerFiles "github.com/swaggo/files"
-	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
 // @title           Tasked API
@@ -32,50 +37,148 @@ import (
 // @description Escribe "Bearer" seguido de un espacio y el JWT token.
 func main() {
 	godotenv.Load()
-	cfg := config.Load()
-
-	db, err := sql.Open("postgres", cfg.DatabaseUrl)
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
-		log.Fatal(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	command := ""
+	if len(args) > 0 {
+		command = args[0]
 	}
-	defer db.Close()
-
-	tokenManager := auth.NewTokenManager(cfg.JWTSecret, cfg.JWTExpiryHrs)
-
-	userRepo := repository.NewUserRepository(db)
-	userService := services.NewUserService(userRepo)
-	userHandler := handler.NewUserHandler(userService, tokenManager)
-
-	taskRepo := repository.NewTaskRepository(db)
-	taskService := services.NewTaskService(taskRepo)
-	taskHandler := handler.NewTaskHandler(taskService)
-
-	router := gin.Default()
-	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		AllowCredentials: true,
-	}))
-	router.Use(middleware.Logger())
 
-	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	if command == "config" {
+		if len(args) != 2 || args[1] != "print" {
+			fmt.Fprintln(os.Stderr, "usage: tasked config print")
+			os.Exit(2)
+		}
+		if err := cfg.Print(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := cfg.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if command != "" && command != "migrate" {
+		fmt.Fprintf(os.Stderr, "unknown command %q, use migrate or config print\n", command)
+		os.Exit(2)
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
 
-	router.POST("/login", userHandler.Login)
-	router.POST("/users", userHandler.CreateUser)
+	logLevel, err := logging.ParseLevel(cfg.Log.Level)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid LOG_LEVEL %q: %v\n", cfg.Log.Level, err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logging.New(os.Stdout, logLevel))
 
-	authMiddleware := middleware.AuthRequired(tokenManager)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.ExporterEndpoint, cfg.Tracing.ServiceName)
+	if err != nil {
+		slog.Error("failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
 
-	router.GET("/users/:id", authMiddleware, userHandler.GetUser)
-	router.PUT("/users/:id", authMiddleware, userHandler.UpdateUser)
-	router.DELETE("/users/:id", authMiddleware, userHandler.DeleteUser)
+	var (
+		db      *sql.DB
+		dialect storage.Dialect
+		repos   *repository.Repositories
+		checks  []health.Check
+	)
+	switch cfg.Storage {
+	case "memory":
+		if command == "migrate" {
+			fmt.Fprintln(os.Stderr, "migrate requires STORAGE=database")
+			os.Exit(1)
+		}
+		slog.Warn("using in-memory storage; data is lost on restart")
+		repos = repository.NewMemoryRepositories(clock.System)
+	case "database", "postgres":
+		openCtx, cancelOpen := context.WithTimeout(context.Background(), 5*time.Second)
+		database, err := storage.Open(openCtx, cfg.Database.URL)
+		cancelOpen()
+		if err != nil {
+			slog.Error("failed to connect to database", "error", err)
+			os.Exit(1)
+		}
+		defer database.Close()
+		db, dialect = database.DB, database.Dialect
+		if dialect == storage.Postgres {
+			db.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+			db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+			db.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+			db.SetConnMaxIdleTime(cfg.Database.ConnMaxIdleTime)
+		}
+
+		migrator, err := database.Migrator()
+		if err != nil {
+			slog.Error("failed to load migrations", "error", err)
+			os.Exit(1)
+		}
+		if command == "migrate" {
+			if err := migrator.Run(context.Background(), args[1:], os.Stdout); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+		if cfg.Database.MigrateOnStart {
+			applied, err := migrator.Up(context.Background())
+			for _, migration := range applied {
+				slog.Info("applied migration", "version", migration.Version, "name", migration.Name)
+			}
+			if err != nil {
+				slog.Error("failed to migrate database", "error", err)
+				os.Exit(1)
+			}
+		}
+
+		repos = database.Repositories()
+		checks = []health.Check{health.Database(db), health.Migrations(migrator)}
+	}
 
-	router.GET("/tasks/:id", authMiddleware, taskHandler.GetTask)
-	router.GET("/users/:id/tasks", authMiddleware, taskHandler.ListTasksByUser)
-	router.PUT("/tasks/:id", authMiddleware, taskHandler.UpdateTask)
-	router.PATCH("/tasks/:id/status", authMiddleware, taskHandler.UpdateStatus)
-	router.DELETE("/tasks/:id", authMiddleware, taskHandler.DeleteTask)
-	router.POST("/tasks", authMiddleware, taskHandler.CreateTask)
+	var opts []server.Option
+	if cfg.RateLimit.Backend == "postgres" {
+		if dialect != storage.Postgres {
+			slog.Error("RATE_LIMIT_BACKEND=postgres requires a postgres:// DATABASE_URL")
+			os.Exit(1)
+		}
+		opts = append(opts, server.WithRateLimitStore(ratelimit.NewPostgresStore(db, clock.System)))
+	}
+	srv := server.New(cfg, server.Dependencies{DB: db, Repositories: repos, Checks: checks}, opts...)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- srv.Start(ctx)
+	}()
+	slog.Info("listening", "addr", ":"+cfg.Server.Port)
+
+	select {
+	case err := <-serverErr:
+		slog.Error("server failed", "error", err)
+		os.Exit(1)
+	case <-ctx.Done():
+	}
+	stop()
 
-	router.Run(":" + cfg.Port)
+	slog.Info("shutting down", "drain_delay", cfg.Server.DrainDelay, "timeout", cfg.Server.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.DrainDelay+cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("failed to drain requests", "error", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("failed to flush traces", "error", err)
+	}
 }
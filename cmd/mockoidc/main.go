@@ -0,0 +1,40 @@
+// Command mockoidc serves a local OpenID Connect provider for trying the SSO
+// login without a real identity provider. Run it and start the API with
+//
+//	OIDC_ISSUER=http://localhost:9000 OIDC_CLIENT_ID=tasked OIDC_CLIENT_SECRET=secret
+//
+// then open http://localhost:8080/auth/oidc/login.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"tasked/internal/auth/oidctest"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:9000", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9000", "issuer URL, must match the listen address")
+	clientID := flag.String("client-id", "tasked", "accepted client ID")
+	clientSecret := flag.String("client-secret", "secret", "accepted client secret")
+	subject := flag.String("sub", "mock-user", "subject of the signed-in user")
+	email := flag.String("email", "mock@example.com", "email of the signed-in user")
+	emailVerified := flag.Bool("email-verified", true, "whether the email is reported as verified")
+	username := flag.String("username", "mock", "preferred_username of the signed-in user")
+	flag.Parse()
+
+	server, err := oidctest.New(*issuer, *clientID, *clientSecret)
+	if err != nil {
+		log.Fatal(err)
+	}
+	server.SetUser(oidctest.User{
+		Subject:           *subject,
+		Email:             *email,
+		EmailVerified:     *emailVerified,
+		PreferredUsername: *username,
+	})
+
+	log.Printf("mock OIDC provider for %s listening on %s", *issuer, *addr)
+	log.Fatal(http.ListenAndServe(*addr, server))
+}
@@ -2,38 +2,82 @@ package auth
 
 import (
 	"errors"
+	"tasked/internal/clock"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type TokenManager struct {
-	secret    string
-	expiryHrs int
+	secret string
+	ttl    time.Duration
+	clock  clock.Clock
 }
 
-func NewTokenManager(secret string, expiryHrs int) *TokenManager {
+func NewTokenManager(secret string, ttl time.Duration, clock clock.Clock) *TokenManager {
 	return &TokenManager{
-		secret:    secret,
-		expiryHrs: expiryHrs,
+		secret: secret,
+		ttl:    ttl,
+		clock:  clock,
 	}
 }
 
+// Tokens with a purpose are not access tokens and are refused by
+// ValidateToken.
+const (
+	PurposeMFAChallenge = "mfa_challenge"
+
+	MFAChallengeTTL = 5 * time.Minute
+)
+
 type CustomClaims struct {
 	UserID   int64  `json:"user_id"`
 	Email    string `json:"email"`
 	Username string `json:"username"`
+	Purpose  string `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func (tm *TokenManager) GenerateToken(userID int64, email, username string) (string, error) {
+// TTL is how long access tokens are valid.
+func (tm *TokenManager) TTL() time.Duration {
+	return tm.ttl
+}
+
+// GenerateToken issues an access token for the session sessionID, which is
+// carried as the jti claim.
+func (tm *TokenManager) GenerateToken(userID int64, email, username, sessionID string) (string, error) {
+	now := tm.clock.Now()
+	claims := CustomClaims{
+		UserID:   userID,
+		Email:    email,
+		Username: username,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        sessionID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(tm.TTL())),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    "tasked-api",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(tm.secret))
+}
+
+// GenerateMFAChallenge issues the short-lived token a user with two-factor
+// authentication gets after a correct password, to be exchanged for an
+// access token together with a TOTP or recovery code. challengeID is carried
+// as the jti claim so the challenge can only be redeemed once.
+func (tm *TokenManager) GenerateMFAChallenge(userID int64, email, username, challengeID string) (string, error) {
+	now := tm.clock.Now()
 	claims := CustomClaims{
 		UserID:   userID,
 		Email:    email,
 		Username: username,
+		Purpose:  PurposeMFAChallenge,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(tm.expiryHrs))),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        challengeID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(MFAChallengeTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
 			Issuer:    "tasked-api",
 		},
 	}
@@ -43,6 +87,28 @@ func (tm *TokenManager) GenerateToken(userID int64, email, username string) (str
 }
 
 func (tm *TokenManager) ValidateToken(tokenString string) (*CustomClaims, error) {
+	claims, err := tm.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+func (tm *TokenManager) ValidateMFAChallenge(tokenString string) (*CustomClaims, error) {
+	claims, err := tm.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != PurposeMFAChallenge {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+func (tm *TokenManager) parse(tokenString string) (*CustomClaims, error) {
 	claims := &CustomClaims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
@@ -50,7 +116,7 @@ func (tm *TokenManager) ValidateToken(tokenString string) (*CustomClaims, error)
 			return nil, errors.New("unexpected signing method")
 		}
 		return []byte(tm.secret), nil
-	})
+	}, jwt.WithTimeFunc(tm.clock.Now))
 
 	if err != nil {
 		return nil, err
@@ -69,5 +135,5 @@ func (tm *TokenManager) RefreshToken(oldToken string) (string, error) {
 		return "", err
 	}
 
-	return tm.GenerateToken(claims.UserID, claims.Email, claims.Username)
+	return tm.GenerateToken(claims.UserID, claims.Email, claims.Username, claims.ID)
 }
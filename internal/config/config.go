@@ -1,26 +1,273 @@
+// Package config loads the server settings. Each setting has a default,
+// which depends on Environment, which an optional YAML or TOML file
+// overrides, which the environment overrides, which command-line flags
+// override. The file is named by the
+// -config flag or CONFIG_FILE and its format chosen by extension; its keys
+// nest as in "server: {port: 8080}". Environment variables are the env
+// names below, and flags are the same names in lower case with dashes, as
+// in -jwt-secret.
 package config
 
-import "os"
+import (
+	"time"
+)
 
-type config struct {
-	DatabaseUrl  string
-	Port         string
-	JWTSecret    string
-	JWTExpiryHrs int
+const (
+	Development = "development"
+	Production  = "production"
+)
+
+type Config struct {
+	// Development or Production, which picks the defaults of the other
+	// settings; see Default.
+	Environment string `yaml:"environment" env:"ENVIRONMENT"`
+	// "database" keeps data in Database.URL, on Postgres or SQLite
+	// depending on its scheme; "memory" keeps it in process for demos and
+	// tests and loses it on restart. "postgres", its name from before
+	// SQLite was supported, is still accepted as "database".
+	Storage string `yaml:"storage" env:"STORAGE"`
+
+	Server      ServerConfig      `yaml:"server"`
+	Database    DatabaseConfig    `yaml:"database"`
+	JWT         JWTConfig         `yaml:"jwt"`
+	CORS        CORSConfig        `yaml:"cors"`
+	Log         LogConfig         `yaml:"log"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Mail        MailConfig        `yaml:"mail"`
+	Account     AccountConfig     `yaml:"account"`
+	OIDC        OIDCConfig        `yaml:"oidc"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Features    FeaturesConfig    `yaml:"features"`
 }
 
-func Load() *config {
-	return &config{
-		DatabaseUrl:  os.Getenv("DATABASE_URL"),
-		Port:         getEnv("PORT", "8080"),
-		JWTSecret:    os.Getenv("JWT_SECRET"),
-		JWTExpiryHrs: 24,
-	}
+type ServerConfig struct {
+	Port              string        `yaml:"port" env:"PORT"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	// How long in-flight requests get to finish after SIGTERM.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	// How long the readiness probe fails before the listener closes, so
+	// load balancers stop sending traffic first. Counted before
+	// ShutdownTimeout.
+	DrainDelay time.Duration `yaml:"drain_delay" env:"SHUTDOWN_DRAIN_DELAY"`
+	// Largest request body accepted, in bytes.
+	MaxBodyBytes int `yaml:"max_body_bytes" env:"SERVER_MAX_BODY_BYTES"`
+	// Sent as the Strict-Transport-Security max-age when positive. Only
+	// set it when clients reach the API over HTTPS.
+	HSTSMaxAge time.Duration `yaml:"hsts_max_age" env:"HSTS_MAX_AGE"`
+	// Addresses or CIDR ranges of the reverse proxies whose
+	// X-Forwarded-For is believed. Empty trusts none, so the client IP
+	// used for rate limits and sessions is the connection's.
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+}
+
+// The pool settings apply to Postgres; SQLite always uses one connection.
+type DatabaseConfig struct {
+	URL string `yaml:"url" env:"DATABASE_URL" secret:"url"`
+	// Apply pending migrations before serving; otherwise run
+	// "tasked migrate up" first.
+	MigrateOnStart  bool          `yaml:"migrate_on_start" env:"MIGRATE_ON_START"`
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+}
+
+type JWTConfig struct {
+	// HMAC key signing access tokens, at least 32 bytes.
+	Secret string `yaml:"secret" env:"JWT_SECRET" secret:"true"`
+	// How long access tokens and their sessions last.
+	Expiry time.Duration `yaml:"expiry" env:"JWT_EXPIRY"`
+}
+
+// Cross-origin requests from browsers. The API authenticates with bearer
+// tokens, so it needs no credentials (cookies) unless a proxy in front of it
+// uses them.
+type CORSConfig struct {
+	// Origins allowed to call the API, as in https://app.example.com. A "*"
+	// can stand for the first label of the host, as in
+	// https://*.example.com, or for the port, as in http://localhost:*. A
+	// lone "*" allows any origin, but not with AllowCredentials. Empty
+	// disables CORS.
+	AllowedOrigins   []string `yaml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+	AllowedMethods   []string `yaml:"allowed_methods" env:"CORS_ALLOWED_METHODS"`
+	AllowedHeaders   []string `yaml:"allowed_headers" env:"CORS_ALLOWED_HEADERS"`
+	ExposedHeaders   []string `yaml:"exposed_headers" env:"CORS_EXPOSED_HEADERS"`
+	AllowCredentials bool     `yaml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+	// How long browsers may cache a preflight response.
+	MaxAge time.Duration `yaml:"max_age" env:"CORS_MAX_AGE"`
+}
+
+type LogConfig struct {
+	// Minimum level logged: debug, info, warn or error.
+	Level string `yaml:"level" env:"LOG_LEVEL"`
+}
+
+type RateLimitConfig struct {
+	// "memory" for a single instance, "postgres" to share limits between
+	// instances.
+	Backend string `yaml:"backend" env:"RATE_LIMIT_BACKEND"`
+	// Login attempts allowed per minute for one client IP and one account.
+	LoginIPLimit      int `yaml:"login_ip_limit" env:"LOGIN_IP_LIMIT"`
+	LoginAccountLimit int `yaml:"login_account_limit" env:"LOGIN_ACCOUNT_LIMIT"`
+	// Consecutive failures before an account is locked, and the first and
+	// longest lock durations.
+	LockoutThreshold int           `yaml:"lockout_threshold" env:"LOCKOUT_THRESHOLD"`
+	LockoutBase      time.Duration `yaml:"lockout_base" env:"LOCKOUT_BASE"`
+	LockoutMax       time.Duration `yaml:"lockout_max" env:"LOCKOUT_MAX"`
+	// Password reset requests allowed per 15 minutes for one client IP and
+	// one email, so the reset form cannot be used to flood an inbox.
+	ResetIPLimit    int `yaml:"reset_ip_limit" env:"RESET_IP_LIMIT"`
+	ResetEmailLimit int `yaml:"reset_email_limit" env:"RESET_EMAIL_LIMIT"`
+}
+
+type IdempotencyConfig struct {
+	// How long a stored Idempotency-Key response can be replayed.
+	TTL time.Duration `yaml:"ttl" env:"IDEMPOTENCY_TTL"`
+}
+
+type MailConfig struct {
+	// Public URL of the frontend, used to build links sent by email.
+	AppBaseURL string `yaml:"app_base_url" env:"APP_BASE_URL"`
+	// "log" writes mail to LogPath (or the log when empty), "smtp"
+	// delivers it through the SMTP settings.
+	Mailer       string `yaml:"mailer" env:"MAILER"`
+	From         string `yaml:"from" env:"MAIL_FROM"`
+	LogPath      string `yaml:"log_path" env:"MAIL_LOG_PATH"`
+	SMTPHost     string `yaml:"smtp_host" env:"SMTP_HOST"`
+	SMTPPort     string `yaml:"smtp_port" env:"SMTP_PORT"`
+	SMTPUsername string `yaml:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword string `yaml:"smtp_password" env:"SMTP_PASSWORD" secret:"true"`
+}
+
+type AccountConfig struct {
+	// One of "off", "login" or "restricted"; see services.AccountService.
+	EmailVerification  string `yaml:"email_verification" env:"EMAIL_VERIFICATION"`
+	PasswordMinLength  int    `yaml:"password_min_length" env:"PASSWORD_MIN_LENGTH"`
+	PasswordMinClasses int    `yaml:"password_min_classes" env:"PASSWORD_MIN_CLASSES"`
+	PasswordBlocklist  bool   `yaml:"password_blocklist" env:"PASSWORD_BLOCKLIST"`
+	// Issuer shown by authenticator apps next to the account.
+	TOTPIssuer string `yaml:"totp_issuer" env:"TOTP_ISSUER"`
+}
+
+// Single sign-on through an OpenID Connect provider, enabled when Issuer is
+// set. RedirectURL must point at /auth/oidc/callback and be registered with
+// the provider.
+type OIDCConfig struct {
+	Issuer       string `yaml:"issuer" env:"OIDC_ISSUER"`
+	ClientID     string `yaml:"client_id" env:"OIDC_CLIENT_ID"`
+	ClientSecret string `yaml:"client_secret" env:"OIDC_CLIENT_SECRET" secret:"true"`
+	RedirectURL  string `yaml:"redirect_url" env:"OIDC_REDIRECT_URL"`
+	// Create an account on first sign-in when no user has the email.
+	AutoCreate bool `yaml:"auto_create" env:"OIDC_AUTO_CREATE"`
 }
 
-func getEnv(key, fallback string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+type TracingConfig struct {
+	// Base URL of an OTLP/HTTP collector receiving traces, such as
+	// http://localhost:4318. Spans are not exported when empty.
+	ExporterEndpoint string `yaml:"exporter_endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	ServiceName      string `yaml:"service_name" env:"OTEL_SERVICE_NAME"`
+}
+
+type FeaturesConfig struct {
+	// Serve the API documentation at /swagger.
+	Swagger bool `yaml:"swagger" env:"SWAGGER_ENABLED"`
+	// Serve Prometheus metrics at /metrics. The endpoint has no
+	// authentication, so production leaves it off unless the port is only
+	// reachable by the scraper.
+	Metrics bool `yaml:"metrics" env:"METRICS_ENABLED"`
+}
+
+// Default returns the development settings used when nothing overrides
+// them. It has no JWT secret, so it does not pass Validate as is.
+func Default() *Config {
+	return defaults(Development)
+}
+
+// defaults returns the settings for environment. Production allows no
+// cross-origin requests, hides the API documentation and sends HSTS until
+// configured otherwise; development allows frontends served from
+// localhost.
+func defaults(environment string) *Config {
+	cfg := &Config{
+		Environment: environment,
+		Storage:     "database",
+		Server: ServerConfig{
+			Port:              "8080",
+			ReadTimeout:       30 * time.Second,
+			ReadHeaderTimeout: 10 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+			MaxBodyBytes:      1 << 20,
+		},
+		Database: DatabaseConfig{
+			MigrateOnStart:  true,
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		JWT: JWTConfig{
+			Expiry: 24 * time.Hour,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"http://localhost:*", "http://127.0.0.1:*"},
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+			AllowedHeaders: []string{"Content-Type", "Authorization", "If-Match", "If-None-Match", "Idempotency-Key", "X-Request-ID", "traceparent", "tracestate", "baggage"},
+			ExposedHeaders: []string{"ETag", "Idempotent-Replayed", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "X-Request-ID"},
+			MaxAge:         time.Hour,
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		RateLimit: RateLimitConfig{
+			Backend:           "memory",
+			LoginIPLimit:      20,
+			LoginAccountLimit: 5,
+			LockoutThreshold:  5,
+			LockoutBase:       time.Minute,
+			LockoutMax:        time.Hour,
+			ResetIPLimit:      10,
+			ResetEmailLimit:   3,
+		},
+		Idempotency: IdempotencyConfig{
+			TTL: 24 * time.Hour,
+		},
+		Mail: MailConfig{
+			AppBaseURL: "http://localhost:8080",
+			Mailer:     "log",
+			From:       "Tasked <no-reply@tasked.local>",
+			SMTPPort:   "587",
+		},
+		Account: AccountConfig{
+			EmailVerification:  "off",
+			PasswordMinLength:  8,
+			PasswordMinClasses: 2,
+			PasswordBlocklist:  true,
+			TOTPIssuer:         "Tasked",
+		},
+		OIDC: OIDCConfig{
+			RedirectURL: "http://localhost:8080/auth/oidc/callback",
+			AutoCreate:  true,
+		},
+		Tracing: TracingConfig{
+			ServiceName: "tasked",
+		},
+		Features: FeaturesConfig{
+			Swagger: true,
+			Metrics: true,
+		},
+	}
+	if environment == Production {
+		cfg.CORS.AllowedOrigins = nil
+		cfg.Features.Swagger = false
+		cfg.Features.Metrics = false
+		cfg.Server.DrainDelay = 5 * time.Second
+		cfg.Server.HSTSMaxAge = 365 * 24 * time.Hour
 	}
-	return fallback
+	return cfg
 }
@@ -0,0 +1,247 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"go.yaml.in/yaml/v3"
+)
+
+// Load reads the settings from the config file, the environment and the
+// flags in args, which stop at the first argument that is not a flag. It
+// returns the arguments left, which name the command to run. Invalid
+// syntax is an error here; values that parse but make no sense are left to
+// Validate.
+func Load(args []string) (*Config, []string, error) {
+	return load(args, os.LookupEnv, os.Stderr)
+}
+
+func load(args []string, lookupEnv func(string) (string, bool), output io.Writer) (*Config, []string, error) {
+	flags := flag.NewFlagSet("tasked", flag.ContinueOnError)
+	flags.SetOutput(output)
+	configFile := flags.String("config", "", "YAML or TOML file with settings, overrides CONFIG_FILE")
+	values := make(map[string]*string)
+	for _, s := range Default().settings() {
+		values[s.flag()] = flags.String(s.flag(), "", fmt.Sprintf("%s, overrides %s", s.key, s.env))
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	path := *configFile
+	if path == "" {
+		path, _ = lookupEnv("CONFIG_FILE")
+	}
+	var file map[string]string
+	if path != "" {
+		var err error
+		if file, err = readFile(path); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// The environment picks the defaults the other settings override, so
+	// it is found first and everything applied again on its defaults.
+	cfg := Default()
+	if err := cfg.apply(path, file, lookupEnv, flags, values); err != nil {
+		return nil, nil, err
+	}
+	if cfg.Environment != Development {
+		environment := cfg.Environment
+		cfg = defaults(environment)
+		if err := cfg.apply(path, file, lookupEnv, flags, values); err != nil {
+			return nil, nil, err
+		}
+	}
+	return cfg, flags.Args(), nil
+}
+
+// apply sets the values from the config file, then the environment, then
+// the flags given.
+func (c *Config) apply(path string, file map[string]string, lookupEnv func(string) (string, bool), flags *flag.FlagSet, values map[string]*string) error {
+	settings := c.settings()
+	find := func(match func(setting) bool) (setting, bool) {
+		i := slices.IndexFunc(settings, match)
+		if i < 0 {
+			return setting{}, false
+		}
+		return settings[i], true
+	}
+
+	var errs []error
+	for _, key := range slices.Sorted(maps.Keys(file)) {
+		s, ok := find(func(s setting) bool { return s.key == key })
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown key %s", key))
+			continue
+		}
+		if err := s.set(file[key]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	for _, s := range settings {
+		if value, ok := lookupEnv(s.env); ok && value != "" {
+			if err := s.set(value); err != nil {
+				return fmt.Errorf("%s: %w", s.env, err)
+			}
+		}
+	}
+
+	var err error
+	flags.Visit(func(f *flag.Flag) {
+		s, ok := find(func(s setting) bool { return s.flag() == f.Name })
+		if !ok || err != nil {
+			return
+		}
+		if setErr := s.set(*values[f.Name]); setErr != nil {
+			err = fmt.Errorf("-%s: %w", f.Name, setErr)
+		}
+	})
+	return err
+}
+
+// setting is one field of Config, addressed by its dotted file key.
+type setting struct {
+	key    string
+	env    string
+	secret string
+	value  reflect.Value
+}
+
+func (s setting) flag() string {
+	return strings.ReplaceAll(strings.ToLower(s.env), "_", "-")
+}
+
+// settings lists the fields of c in declaration order.
+func (c *Config) settings() []setting {
+	var settings []setting
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		for i := range v.NumField() {
+			field := v.Type().Field(i)
+			key := prefix + field.Tag.Get("yaml")
+			if field.Type.Kind() == reflect.Struct {
+				walk(v.Field(i), key+".")
+				continue
+			}
+			settings = append(settings, setting{
+				key:    key,
+				env:    field.Tag.Get("env"),
+				secret: field.Tag.Get("secret"),
+				value:  v.Field(i),
+			})
+		}
+	}
+	walk(reflect.ValueOf(c).Elem(), "")
+	return settings
+}
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// set parses raw into the field. Lists are comma separated.
+func (s setting) set(raw string) error {
+	raw = strings.TrimSpace(raw)
+	switch {
+	case s.value.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		s.value.SetInt(int64(d))
+	case s.value.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		s.value.SetInt(int64(n))
+	case s.value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		s.value.SetBool(b)
+	case s.value.Kind() == reflect.Slice:
+		var items []string
+		for item := range strings.SplitSeq(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		s.value.Set(reflect.ValueOf(items))
+	default:
+		s.value.SetString(raw)
+	}
+	return nil
+}
+
+// format renders the field the way set parses it.
+func (s setting) format() string {
+	switch v := s.value.Interface().(type) {
+	case time.Duration:
+		return v.String()
+	case []string:
+		return strings.Join(v, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// readFile returns the settings in the file at path by dotted key.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]any
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf("config file %s: unknown format %q, use .yaml, .yml or .toml", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	flatten(tree, "", values)
+	return values, nil
+}
+
+// flatten turns nested tables into dotted keys with the values written as
+// they would be in the environment.
+func flatten(tree map[string]any, prefix string, values map[string]string) {
+	for key, value := range tree {
+		switch value := value.(type) {
+		case map[string]any:
+			flatten(value, prefix+key+".", values)
+		case nil:
+			values[prefix+key] = ""
+		case []any:
+			items := make([]string, len(value))
+			for i, item := range value {
+				items[i] = fmt.Sprint(item)
+			}
+			values[prefix+key] = strings.Join(items, ",")
+		default:
+			values[prefix+key] = fmt.Sprint(value)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"io"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+const redacted = "[redacted]"
+
+// Print writes the settings as a YAML config file, in the order they are
+// declared, with secrets replaced so the output can be shared.
+func (c *Config) Print(w io.Writer) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	sections := map[string]*yaml.Node{}
+	for _, s := range c.settings() {
+		parent := root
+		name := s.key
+		if section, field, ok := strings.Cut(s.key, "."); ok {
+			if sections[section] == nil {
+				sections[section] = &yaml.Node{Kind: yaml.MappingNode}
+				root.Content = append(root.Content, scalar(section), sections[section])
+			}
+			parent, name = sections[section], field
+		}
+		parent.Content = append(parent.Content, scalar(name), s.node())
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func scalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+}
+
+// node renders the field as YAML, redacting secrets. URLs keep everything
+// but their credentials, which is enough to tell which database is in use.
+func (s setting) node() *yaml.Node {
+	if items, ok := s.value.Interface().([]string); ok {
+		list := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for _, item := range items {
+			list.Content = append(list.Content, scalar(item))
+		}
+		return list
+	}
+	value := s.format()
+	switch {
+	case value == "":
+	case s.secret == "true":
+		value = redacted
+	case s.secret == "url":
+		value = redactURL(value)
+	}
+	n := scalar(value)
+	if s.value.Kind() == reflect.String {
+		// Quote strings such as "8080" or "off" that YAML would read back
+		// as another type.
+		n.Tag = "!!str"
+	}
+	return n
+}
+
+// secretParams are the query parameters that carry credentials in database
+// URLs.
+var secretParams = map[string]bool{
+	"password":    true,
+	"pass":        true,
+	"sslpassword": true,
+	"token":       true,
+}
+
+// redactURL masks the password and any credentials in the query string.
+// Values that are not URLs, such as key=value DSNs, are redacted whole.
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" {
+		return redacted
+	}
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return redacted
+	}
+	for key := range query {
+		if secretParams[strings.ToLower(key)] {
+			query[key] = []string{"xxxxx"}
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.Redacted()
+}
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// minJWTSecretLength is the HMAC-SHA256 key size; shorter secrets can be
+// brute-forced from a single token.
+const minJWTSecretLength = 32
+
+// Validate reports every setting that is missing, out of range or insecure,
+// naming each by its file key and environment variable.
+func (c *Config) Validate() error {
+	v := validator{}
+
+	v.check(c.Environment == Development || c.Environment == Production, "environment", "ENVIRONMENT", "must be development or production")
+
+	v.check(slices.Contains([]string{"database", "postgres", "memory"}, c.Storage), "storage", "STORAGE", "must be database, postgres or memory")
+	if c.Storage != "memory" {
+		v.check(c.Database.URL != "", "database.url", "DATABASE_URL", "is required with storage database")
+	}
+
+	port, err := strconv.Atoi(c.Server.Port)
+	v.check(err == nil && port > 0 && port < 65536, "server.port", "PORT", "must be a port number")
+	v.positive(c.Server.ReadTimeout, "server.read_timeout", "SERVER_READ_TIMEOUT")
+	v.positive(c.Server.ReadHeaderTimeout, "server.read_header_timeout", "SERVER_READ_HEADER_TIMEOUT")
+	v.positive(c.Server.WriteTimeout, "server.write_timeout", "SERVER_WRITE_TIMEOUT")
+	v.positive(c.Server.IdleTimeout, "server.idle_timeout", "SERVER_IDLE_TIMEOUT")
+	v.positive(c.Server.ShutdownTimeout, "server.shutdown_timeout", "SHUTDOWN_TIMEOUT")
+	v.check(c.Server.DrainDelay >= 0, "server.drain_delay", "SHUTDOWN_DRAIN_DELAY", "must not be negative")
+	v.check(c.Server.MaxBodyBytes > 0, "server.max_body_bytes", "SERVER_MAX_BODY_BYTES", "must be positive")
+	v.check(c.Server.HSTSMaxAge >= 0, "server.hsts_max_age", "HSTS_MAX_AGE", "must not be negative")
+	for _, proxy := range c.Server.TrustedProxies {
+		v.check(isIPOrCIDR(proxy), "server.trusted_proxies", "TRUSTED_PROXIES", fmt.Sprintf("%q is not an IP address or CIDR range", proxy))
+	}
+
+	v.check(c.Database.MaxOpenConns >= 0, "database.max_open_conns", "DB_MAX_OPEN_CONNS", "must not be negative")
+	v.check(c.Database.MaxIdleConns >= 0, "database.max_idle_conns", "DB_MAX_IDLE_CONNS", "must not be negative")
+	v.check(c.Database.ConnMaxLifetime >= 0, "database.conn_max_lifetime", "DB_CONN_MAX_LIFETIME", "must not be negative")
+	v.check(c.Database.ConnMaxIdleTime >= 0, "database.conn_max_idle_time", "DB_CONN_MAX_IDLE_TIME", "must not be negative")
+
+	switch {
+	case c.JWT.Secret == "":
+		v.fail("jwt.secret", "JWT_SECRET", "is required")
+	case len(c.JWT.Secret) < minJWTSecretLength:
+		v.fail("jwt.secret", "JWT_SECRET", fmt.Sprintf("must be at least %d bytes", minJWTSecretLength))
+	}
+	v.check(c.JWT.Expiry >= time.Minute && c.JWT.Expiry <= 30*24*time.Hour, "jwt.expiry", "JWT_EXPIRY", "must be between 1m and 720h")
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		v.check(origin == "*" || isOrigin(originPattern(origin)), "cors.allowed_origins", "CORS_ALLOWED_ORIGINS", fmt.Sprintf("%q is not an origin such as https://app.example.com or pattern such as https://*.example.com", origin))
+	}
+	if slices.Contains(c.CORS.AllowedOrigins, "*") {
+		v.check(!c.CORS.AllowCredentials, "cors.allow_credentials", "CORS_ALLOW_CREDENTIALS", "cannot be combined with the * origin")
+		v.check(c.Environment != Production, "cors.allowed_origins", "CORS_ALLOWED_ORIGINS", "cannot be * in production")
+	}
+	v.check(len(c.CORS.AllowedMethods) > 0, "cors.allowed_methods", "CORS_ALLOWED_METHODS", "must list at least one method")
+	v.check(c.CORS.MaxAge >= 0, "cors.max_age", "CORS_MAX_AGE", "must not be negative")
+
+	v.check(slices.Contains([]string{"debug", "info", "warn", "error"}, strings.ToLower(c.Log.Level)), "log.level", "LOG_LEVEL", "must be debug, info, warn or error")
+
+	v.check(slices.Contains([]string{"memory", "postgres"}, c.RateLimit.Backend), "rate_limit.backend", "RATE_LIMIT_BACKEND", "must be memory or postgres")
+	v.check(c.RateLimit.LoginIPLimit > 0, "rate_limit.login_ip_limit", "LOGIN_IP_LIMIT", "must be positive")
+	v.check(c.RateLimit.LoginAccountLimit > 0, "rate_limit.login_account_limit", "LOGIN_ACCOUNT_LIMIT", "must be positive")
+	v.check(c.RateLimit.ResetIPLimit > 0, "rate_limit.reset_ip_limit", "RESET_IP_LIMIT", "must be positive")
+	v.check(c.RateLimit.ResetEmailLimit > 0, "rate_limit.reset_email_limit", "RESET_EMAIL_LIMIT", "must be positive")
+	v.check(c.RateLimit.LockoutThreshold > 0, "rate_limit.lockout_threshold", "LOCKOUT_THRESHOLD", "must be positive")
+	v.positive(c.RateLimit.LockoutBase, "rate_limit.lockout_base", "LOCKOUT_BASE")
+	v.check(c.RateLimit.LockoutMax >= c.RateLimit.LockoutBase, "rate_limit.lockout_max", "LOCKOUT_MAX", "must not be shorter than lockout_base")
+
+	v.positive(c.Idempotency.TTL, "idempotency.ttl", "IDEMPOTENCY_TTL")
+
+	v.check(isHTTPURL(c.Mail.AppBaseURL), "mail.app_base_url", "APP_BASE_URL", "must be an http or https URL")
+	v.check(slices.Contains([]string{"log", "smtp"}, c.Mail.Mailer), "mail.mailer", "MAILER", "must be log or smtp")
+	if c.Mail.Mailer == "smtp" {
+		v.check(c.Mail.SMTPHost != "", "mail.smtp_host", "SMTP_HOST", "is required with mailer smtp")
+		v.check(c.Mail.From != "", "mail.from", "MAIL_FROM", "is required with mailer smtp")
+	}
+
+	v.check(slices.Contains([]string{"off", "login", "restricted"}, c.Account.EmailVerification), "account.email_verification", "EMAIL_VERIFICATION", "must be off, login or restricted")
+	v.check(c.Account.PasswordMinLength >= 8, "account.password_min_length", "PASSWORD_MIN_LENGTH", "must be at least 8")
+	v.check(c.Account.PasswordMinClasses >= 1 && c.Account.PasswordMinClasses <= 4, "account.password_min_classes", "PASSWORD_MIN_CLASSES", "must be between 1 and 4")
+
+	if c.OIDC.Issuer != "" {
+		v.check(isHTTPURL(c.OIDC.Issuer), "oidc.issuer", "OIDC_ISSUER", "must be an http or https URL")
+		v.check(c.OIDC.ClientID != "", "oidc.client_id", "OIDC_CLIENT_ID", "is required with an issuer")
+		v.check(c.OIDC.ClientSecret != "", "oidc.client_secret", "OIDC_CLIENT_SECRET", "is required with an issuer")
+		v.check(isHTTPURL(c.OIDC.RedirectURL), "oidc.redirect_url", "OIDC_REDIRECT_URL", "must be an http or https URL")
+	}
+
+	if c.Tracing.ExporterEndpoint != "" {
+		v.check(isHTTPURL(c.Tracing.ExporterEndpoint), "tracing.exporter_endpoint", "OTEL_EXPORTER_OTLP_ENDPOINT", "must be an http or https URL")
+	}
+
+	return errors.Join(v.errs...)
+}
+
+type validator struct {
+	errs []error
+}
+
+func (v *validator) fail(key, env, problem string) {
+	v.errs = append(v.errs, fmt.Errorf("%s (%s) %s", key, env, problem))
+}
+
+func (v *validator) check(ok bool, key, env, problem string) {
+	if !ok {
+		v.fail(key, env, problem)
+	}
+}
+
+func (v *validator) positive(d time.Duration, key, env string) {
+	v.check(d > 0, key, env, "must be positive")
+}
+
+func isIPOrCIDR(raw string) bool {
+	if _, err := netip.ParseAddr(raw); err == nil {
+		return true
+	}
+	_, err := netip.ParsePrefix(raw)
+	return err == nil
+}
+
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// isOrigin reports whether raw is a scheme, host and optional port with
+// nothing after them, as browsers send in the Origin header.
+func isOrigin(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && isHTTPURL(raw) && u.Path == "" && u.RawQuery == "" && u.User == nil && !strings.Contains(u.Host, "*")
+}
+
+// originPattern replaces the wildcard of an allowed origin pattern so that
+// the result parses as an origin when the wildcard stands for the first
+// label of the host or for the port, and not otherwise.
+func originPattern(origin string) string {
+	scheme, host, ok := strings.Cut(origin, "://")
+	if !ok || strings.Count(host, "*") > 1 {
+		return origin
+	}
+	if rest, ok := strings.CutPrefix(host, "*."); ok && !strings.Contains(rest, "*") && strings.Contains(rest, ".") {
+		host = "wildcard." + rest
+	} else if rest, ok := strings.CutSuffix(host, ":*"); ok && !strings.Contains(rest, "*") {
+		host = rest + ":1"
+	}
+	return scheme + "://" + host
+}
@@ -0,0 +1 @@
+ALTER TABLE users ADD CONSTRAINT users_password_key UNIQUE (password);
@@ -0,0 +1,6 @@
+-- Hashes are salted, so the constraint never held anything back; it only
+-- kept a unique index over credentials.
+ALTER TABLE users DROP CONSTRAINT users_password_key;
+
+-- Responses cached for replaying sign-ups included the password hash.
+DELETE FROM idempotency_keys WHERE scope LIKE '%:POST /users';
@@ -0,0 +1,8 @@
+// Package migrations embeds the schema migrations. NNN_name.sql applies
+// version NNN and NNN_name.down.sql reverts it; sqlc reads only the former.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
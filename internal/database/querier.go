@@ -6,20 +6,75 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 type Querier interface {
+	ClaimIdempotencyKey(ctx context.Context, arg ClaimIdempotencyKeyParams) (int64, error)
+	CompleteIdempotencyKey(ctx context.Context, arg CompleteIdempotencyKeyParams) error
+	ConsumeOIDCLoginState(ctx context.Context, stateHash string) (ConsumeOIDCLoginStateRow, error)
+	ConsumeUserToken(ctx context.Context, arg ConsumeUserTokenParams) (int64, error)
+	CountOverdueTasks(ctx context.Context) (int64, error)
+	CountTasksByStatus(ctx context.Context) ([]CountTasksByStatusRow, error)
+	CreateOIDCLoginState(ctx context.Context, arg CreateOIDCLoginStateParams) error
+	CreatePersonalAccessToken(ctx context.Context, arg CreatePersonalAccessTokenParams) (PersonalAccessToken, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	CreateTask(ctx context.Context, arg CreateTaskParams) (Task, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
-	DeleteTask(ctx context.Context, id int64) error
-	DeleteUser(ctx context.Context, id int64) error
+	CreateUserIdentity(ctx context.Context, arg CreateUserIdentityParams) error
+	CreateUserToken(ctx context.Context, arg CreateUserTokenParams) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error)
+	DeleteExpiredOIDCLoginStates(ctx context.Context) (int64, error)
+	DeleteExpiredUserTokens(ctx context.Context) (int64, error)
+	DeleteIdempotencyKey(ctx context.Context, arg DeleteIdempotencyKeyParams) error
+	DeleteIdleLoginFailures(ctx context.Context, lastFailureAt time.Time) (int64, error)
+	DeleteIdleRateLimitBuckets(ctx context.Context, updatedAt time.Time) (int64, error)
+	DeleteLoginFailure(ctx context.Context, key string) error
+	DeletePersonalAccessToken(ctx context.Context, arg DeletePersonalAccessTokenParams) (int64, error)
+	DeleteRecoveryCodes(ctx context.Context, userID int64) error
+	DeleteStaleIdempotencyKey(ctx context.Context, arg DeleteStaleIdempotencyKeyParams) (int64, error)
+	DeleteStaleSessions(ctx context.Context, revokedAt sql.NullTime) (int64, error)
+	DeleteTask(ctx context.Context, arg DeleteTaskParams) (int64, error)
+	DeleteUser(ctx context.Context, arg DeleteUserParams) (int64, error)
+	DeleteUserTokens(ctx context.Context, arg DeleteUserTokensParams) error
+	DisableUserTOTP(ctx context.Context, id int64) error
+	EnableUserTOTP(ctx context.Context, id int64) error
+	EnsureLoginFailure(ctx context.Context, key string) error
+	EnsureRateLimitBucket(ctx context.Context, arg EnsureRateLimitBucketParams) error
+	GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error)
+	GetLoginFailureForUpdate(ctx context.Context, key string) (GetLoginFailureForUpdateRow, error)
+	GetLoginLock(ctx context.Context, key string) (GetLoginLockRow, error)
+	GetPersonalAccessTokenByHash(ctx context.Context, tokenHash string) (PersonalAccessToken, error)
+	GetRateLimitBucketForUpdate(ctx context.Context, key string) (GetRateLimitBucketForUpdateRow, error)
 	GetTaskByID(ctx context.Context, id int64) (Task, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserByID(ctx context.Context, id int64) (User, error)
+	GetUserByIdentity(ctx context.Context, arg GetUserByIdentityParams) (User, error)
+	GetUserMFA(ctx context.Context, id int64) (GetUserMFARow, error)
+	GetUserTokenOwner(ctx context.Context, arg GetUserTokenOwnerParams) (int64, error)
+	ListActiveSessions(ctx context.Context, userID int64) ([]Session, error)
+	ListPersonalAccessTokens(ctx context.Context, userID int64) ([]PersonalAccessToken, error)
 	ListTasksByUser(ctx context.Context, userID int64) ([]Task, error)
+	MarkUserEmailVerified(ctx context.Context, id int64) error
+	PatchTask(ctx context.Context, arg PatchTaskParams) (Task, error)
+	RevokeOtherSessions(ctx context.Context, arg RevokeOtherSessionsParams) ([]string, error)
+	RevokeSession(ctx context.Context, arg RevokeSessionParams) (int64, error)
+	SetUserTOTPSecret(ctx context.Context, arg SetUserTOTPSecretParams) error
+	// Sessions created before the user's sessions_revoked_at, which a password
+	// change sets, count as revoked.
+	TouchActiveSession(ctx context.Context, id string) (Session, error)
+	TouchPersonalAccessToken(ctx context.Context, id int64) error
+	UpdateLoginFailure(ctx context.Context, arg UpdateLoginFailureParams) error
+	UpdateRateLimitBucket(ctx context.Context, arg UpdateRateLimitBucketParams) error
 	UpdateTask(ctx context.Context, arg UpdateTaskParams) (Task, error)
 	UpdateTaskStatus(ctx context.Context, arg UpdateTaskStatusParams) (Task, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
+	UseUserTOTPStep(ctx context.Context, arg UseUserTOTPStepParams) (int64, error)
+	UsernameExists(ctx context.Context, username string) (bool, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,26 @@
+-- name: ClaimIdempotencyKey :execrows
+INSERT INTO idempotency_keys (scope, key, fingerprint, created_at, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (scope, key) DO NOTHING;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE scope = $1 AND key = $2;
+
+-- name: CompleteIdempotencyKey :exec
+UPDATE idempotency_keys
+SET status = 'completed', response_status = $3, response_headers = $4, response_body = $5
+WHERE scope = $1 AND key = $2;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE scope = $1 AND key = $2;
+
+-- name: DeleteStaleIdempotencyKey :execrows
+DELETE FROM idempotency_keys
+WHERE scope = $1 AND key = $2
+  AND (expires_at < sqlc.arg(now) OR (status = 'in_progress' AND created_at < sqlc.arg(abandoned_before)));
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE expires_at < sqlc.arg(now);
@@ -0,0 +1,45 @@
+-- name: EnsureRateLimitBucket :exec
+INSERT INTO rate_limit_buckets (key, tokens)
+VALUES ($1, $2)
+ON CONFLICT (key) DO NOTHING;
+
+-- name: GetRateLimitBucketForUpdate :one
+SELECT tokens, updated_at, NOW()::timestamptz AS now FROM rate_limit_buckets
+WHERE key = $1
+FOR UPDATE;
+
+-- name: UpdateRateLimitBucket :exec
+UPDATE rate_limit_buckets
+SET tokens = $2, updated_at = $3
+WHERE key = $1;
+
+-- name: DeleteIdleRateLimitBuckets :execrows
+DELETE FROM rate_limit_buckets
+WHERE updated_at < $1;
+
+-- name: EnsureLoginFailure :exec
+INSERT INTO login_failures (key)
+VALUES ($1)
+ON CONFLICT (key) DO NOTHING;
+
+-- name: GetLoginFailureForUpdate :one
+SELECT failures, last_failure_at, locked_until, NOW()::timestamptz AS now FROM login_failures
+WHERE key = $1
+FOR UPDATE;
+
+-- name: UpdateLoginFailure :exec
+UPDATE login_failures
+SET failures = $2, last_failure_at = $3, locked_until = $4
+WHERE key = $1;
+
+-- name: GetLoginLock :one
+SELECT locked_until, NOW()::timestamptz AS now FROM login_failures
+WHERE key = $1;
+
+-- name: DeleteLoginFailure :exec
+DELETE FROM login_failures
+WHERE key = $1;
+
+-- name: DeleteIdleLoginFailures :execrows
+DELETE FROM login_failures
+WHERE last_failure_at < $1 AND (locked_until IS NULL OR locked_until < NOW());
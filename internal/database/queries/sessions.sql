@@ -0,0 +1,41 @@
+-- name: CreateSession :one
+INSERT INTO sessions (id, user_id, user_agent, ip, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: TouchActiveSession :one
+-- Sessions created before the user's sessions_revoked_at, which a password
+-- change sets, count as revoked.
+UPDATE sessions
+SET last_seen_at = NOW()
+FROM users
+WHERE sessions.id = $1
+  AND users.id = sessions.user_id
+  AND sessions.revoked_at IS NULL
+  AND sessions.expires_at > NOW()
+  AND (users.sessions_revoked_at IS NULL OR sessions.created_at >= users.sessions_revoked_at)
+RETURNING sessions.*;
+
+-- name: ListActiveSessions :many
+SELECT sessions.* FROM sessions
+JOIN users ON users.id = sessions.user_id
+WHERE sessions.user_id = $1
+  AND sessions.revoked_at IS NULL
+  AND sessions.expires_at > NOW()
+  AND (users.sessions_revoked_at IS NULL OR sessions.created_at >= users.sessions_revoked_at)
+ORDER BY sessions.last_seen_at DESC;
+
+-- name: RevokeSession :execrows
+UPDATE sessions
+SET revoked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeOtherSessions :many
+UPDATE sessions
+SET revoked_at = NOW()
+WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+RETURNING id;
+
+-- name: DeleteStaleSessions :execrows
+DELETE FROM sessions
+WHERE expires_at < NOW() OR revoked_at < $1;
@@ -5,7 +5,7 @@ WHERE id = $1;
 -- name: ListTasksByUser :many
 SELECT * FROM tasks
 WHERE user_id = $1
-ORDER BY created_at DESC;
+ORDER BY created_at DESC, id DESC;
 
 -- name: CreateTask :one
 INSERT INTO tasks (title, description, status, priority, user_id, due_date)
@@ -14,16 +14,36 @@ RETURNING *;
 
 -- name: UpdateTask :one
 UPDATE tasks
-SET title = $2, description = $3, status = $4, priority = $5, due_date = $6, updated_at = NOW()
-WHERE id = $1
+SET title = $2, description = $3, status = $4, priority = $5, due_date = $6, updated_at = NOW(), version = version + 1
+WHERE id = $1 AND (version = $7 OR $7 = 0)
 RETURNING *;
 
--- name: DeleteTask :exec
+-- name: DeleteTask :execrows
 DELETE FROM tasks
-WHERE id = $1;
+WHERE id = $1 AND (version = $2 OR $2 = 0);
 
 -- name: UpdateTaskStatus :one
 UPDATE tasks
-SET status = $2, updated_at = NOW()
-WHERE id = $1
+SET status = $2, updated_at = NOW(), version = version + 1
+WHERE id = $1 AND (version = $3 OR $3 = 0)
+RETURNING *;
+
+-- name: PatchTask :one
+UPDATE tasks
+SET title = COALESCE(sqlc.narg(title)::text, title),
+    description = CASE WHEN sqlc.arg(set_description)::boolean THEN sqlc.narg(description)::text ELSE description END,
+    status = COALESCE(sqlc.narg(status)::text, status),
+    priority = CASE WHEN sqlc.arg(set_priority)::boolean THEN sqlc.narg(priority)::text ELSE priority END,
+    due_date = CASE WHEN sqlc.arg(set_due_date)::boolean THEN sqlc.narg(due_date)::timestamptz ELSE due_date END,
+    updated_at = NOW(),
+    version = version + 1
+WHERE id = sqlc.arg(id) AND (version = sqlc.arg(version) OR sqlc.arg(version) = 0)
 RETURNING *;
+
+-- name: CountTasksByStatus :many
+SELECT COALESCE(status, '')::text AS status, COUNT(*) AS count FROM tasks
+GROUP BY status;
+
+-- name: CountOverdueTasks :one
+SELECT COUNT(*) FROM tasks
+WHERE due_date < NOW() AND status IS DISTINCT FROM 'completed';
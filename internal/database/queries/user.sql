@@ -13,10 +13,24 @@ RETURNING *;
 
 -- name: UpdateUser :one
 UPDATE users
-SET username = $2, email = $3, updated_at = NOW()
-WHERE id = $1
+SET username = $2,
+    email = $3,
+    email_verified_at = CASE WHEN email = $3 THEN email_verified_at END,
+    updated_at = NOW(),
+    version = version + 1
+WHERE id = $1 AND (version = $4 OR $4 = 0)
 RETURNING *;
 
--- name: DeleteUser :exec
-DELETE FROM users
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET password = $2, sessions_revoked_at = NOW(), updated_at = NOW(), version = version + 1
 WHERE id = $1;
+
+-- name: MarkUserEmailVerified :exec
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW(), version = version + 1
+WHERE id = $1;
+
+-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE id = $1 AND (version = $2 OR $2 = 0);
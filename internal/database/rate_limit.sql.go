@@ -0,0 +1,176 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: rate_limit.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const deleteIdleLoginFailures = `-- name: DeleteIdleLoginFailures :execrows
+DELETE FROM login_failures
+WHERE last_failure_at < $1 AND (locked_until IS NULL OR locked_until < NOW())
+`
+
+func (q *Queries) DeleteIdleLoginFailures(ctx context.Context, lastFailureAt time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteIdleLoginFailures, lastFailureAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteIdleRateLimitBuckets = `-- name: DeleteIdleRateLimitBuckets :execrows
+DELETE FROM rate_limit_buckets
+WHERE updated_at < $1
+`
+
+func (q *Queries) DeleteIdleRateLimitBuckets(ctx context.Context, updatedAt time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteIdleRateLimitBuckets, updatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteLoginFailure = `-- name: DeleteLoginFailure :exec
+DELETE FROM login_failures
+WHERE key = $1
+`
+
+func (q *Queries) DeleteLoginFailure(ctx context.Context, key string) error {
+	_, err := q.db.ExecContext(ctx, deleteLoginFailure, key)
+	return err
+}
+
+const ensureLoginFailure = `-- name: EnsureLoginFailure :exec
+INSERT INTO login_failures (key)
+VALUES ($1)
+ON CONFLICT (key) DO NOTHING
+`
+
+func (q *Queries) EnsureLoginFailure(ctx context.Context, key string) error {
+	_, err := q.db.ExecContext(ctx, ensureLoginFailure, key)
+	return err
+}
+
+const ensureRateLimitBucket = `-- name: EnsureRateLimitBucket :exec
+INSERT INTO rate_limit_buckets (key, tokens)
+VALUES ($1, $2)
+ON CONFLICT (key) DO NOTHING
+`
+
+type EnsureRateLimitBucketParams struct {
+	Key    string  `json:"key"`
+	Tokens float64 `json:"tokens"`
+}
+
+func (q *Queries) EnsureRateLimitBucket(ctx context.Context, arg EnsureRateLimitBucketParams) error {
+	_, err := q.db.ExecContext(ctx, ensureRateLimitBucket, arg.Key, arg.Tokens)
+	return err
+}
+
+const getLoginFailureForUpdate = `-- name: GetLoginFailureForUpdate :one
+SELECT failures, last_failure_at, locked_until, NOW()::timestamptz AS now FROM login_failures
+WHERE key = $1
+FOR UPDATE
+`
+
+type GetLoginFailureForUpdateRow struct {
+	Failures      int32        `json:"failures"`
+	LastFailureAt time.Time    `json:"last_failure_at"`
+	LockedUntil   sql.NullTime `json:"locked_until"`
+	Now           time.Time    `json:"now"`
+}
+
+func (q *Queries) GetLoginFailureForUpdate(ctx context.Context, key string) (GetLoginFailureForUpdateRow, error) {
+	row := q.db.QueryRowContext(ctx, getLoginFailureForUpdate, key)
+	var i GetLoginFailureForUpdateRow
+	err := row.Scan(
+		&i.Failures,
+		&i.LastFailureAt,
+		&i.LockedUntil,
+		&i.Now,
+	)
+	return i, err
+}
+
+const getLoginLock = `-- name: GetLoginLock :one
+SELECT locked_until, NOW()::timestamptz AS now FROM login_failures
+WHERE key = $1
+`
+
+type GetLoginLockRow struct {
+	LockedUntil sql.NullTime `json:"locked_until"`
+	Now         time.Time    `json:"now"`
+}
+
+func (q *Queries) GetLoginLock(ctx context.Context, key string) (GetLoginLockRow, error) {
+	row := q.db.QueryRowContext(ctx, getLoginLock, key)
+	var i GetLoginLockRow
+	err := row.Scan(&i.LockedUntil, &i.Now)
+	return i, err
+}
+
+const getRateLimitBucketForUpdate = `-- name: GetRateLimitBucketForUpdate :one
+SELECT tokens, updated_at, NOW()::timestamptz AS now FROM rate_limit_buckets
+WHERE key = $1
+FOR UPDATE
+`
+
+type GetRateLimitBucketForUpdateRow struct {
+	Tokens    float64   `json:"tokens"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Now       time.Time `json:"now"`
+}
+
+func (q *Queries) GetRateLimitBucketForUpdate(ctx context.Context, key string) (GetRateLimitBucketForUpdateRow, error) {
+	row := q.db.QueryRowContext(ctx, getRateLimitBucketForUpdate, key)
+	var i GetRateLimitBucketForUpdateRow
+	err := row.Scan(&i.Tokens, &i.UpdatedAt, &i.Now)
+	return i, err
+}
+
+const updateLoginFailure = `-- name: UpdateLoginFailure :exec
+UPDATE login_failures
+SET failures = $2, last_failure_at = $3, locked_until = $4
+WHERE key = $1
+`
+
+type UpdateLoginFailureParams struct {
+	Key           string       `json:"key"`
+	Failures      int32        `json:"failures"`
+	LastFailureAt time.Time    `json:"last_failure_at"`
+	LockedUntil   sql.NullTime `json:"locked_until"`
+}
+
+func (q *Queries) UpdateLoginFailure(ctx context.Context, arg UpdateLoginFailureParams) error {
+	_, err := q.db.ExecContext(ctx, updateLoginFailure,
+		arg.Key,
+		arg.Failures,
+		arg.LastFailureAt,
+		arg.LockedUntil,
+	)
+	return err
+}
+
+const updateRateLimitBucket = `-- name: UpdateRateLimitBucket :exec
+UPDATE rate_limit_buckets
+SET tokens = $2, updated_at = $3
+WHERE key = $1
+`
+
+type UpdateRateLimitBucketParams struct {
+	Key       string    `json:"key"`
+	Tokens    float64   `json:"tokens"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (q *Queries) UpdateRateLimitBucket(ctx context.Context, arg UpdateRateLimitBucketParams) error {
+	_, err := q.db.ExecContext(ctx, updateRateLimitBucket, arg.Key, arg.Tokens, arg.UpdatedAt)
+	return err
+}
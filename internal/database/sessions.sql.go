@@ -0,0 +1,187 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: sessions.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const createSession = `-- name: CreateSession :one
+INSERT INTO sessions (id, user_id, user_agent, ip, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, user_id, user_agent, ip, created_at, last_seen_at, expires_at, revoked_at
+`
+
+type CreateSessionParams struct {
+	ID        string    `json:"id"`
+	UserID    int64     `json:"user_id"`
+	UserAgent string    `json:"user_agent"`
+	Ip        string    `json:"ip"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
+	row := q.db.QueryRowContext(ctx, createSession,
+		arg.ID,
+		arg.UserID,
+		arg.UserAgent,
+		arg.Ip,
+		arg.ExpiresAt,
+	)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.UserAgent,
+		&i.Ip,
+		&i.CreatedAt,
+		&i.LastSeenAt,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const deleteStaleSessions = `-- name: DeleteStaleSessions :execrows
+DELETE FROM sessions
+WHERE expires_at < NOW() OR revoked_at < $1
+`
+
+func (q *Queries) DeleteStaleSessions(ctx context.Context, revokedAt sql.NullTime) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteStaleSessions, revokedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const listActiveSessions = `-- name: ListActiveSessions :many
+SELECT sessions.id, sessions.user_id, sessions.user_agent, sessions.ip, sessions.created_at, sessions.last_seen_at, sessions.expires_at, sessions.revoked_at FROM sessions
+JOIN users ON users.id = sessions.user_id
+WHERE sessions.user_id = $1
+  AND sessions.revoked_at IS NULL
+  AND sessions.expires_at > NOW()
+  AND (users.sessions_revoked_at IS NULL OR sessions.created_at >= users.sessions_revoked_at)
+ORDER BY sessions.last_seen_at DESC
+`
+
+func (q *Queries) ListActiveSessions(ctx context.Context, userID int64) ([]Session, error) {
+	rows, err := q.db.QueryContext(ctx, listActiveSessions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Session{}
+	for rows.Next() {
+		var i Session
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.UserAgent,
+			&i.Ip,
+			&i.CreatedAt,
+			&i.LastSeenAt,
+			&i.ExpiresAt,
+			&i.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeOtherSessions = `-- name: RevokeOtherSessions :many
+UPDATE sessions
+SET revoked_at = NOW()
+WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+RETURNING id
+`
+
+type RevokeOtherSessionsParams struct {
+	UserID int64  `json:"user_id"`
+	ID     string `json:"id"`
+}
+
+func (q *Queries) RevokeOtherSessions(ctx context.Context, arg RevokeOtherSessionsParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, revokeOtherSessions, arg.UserID, arg.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeSession = `-- name: RevokeSession :execrows
+UPDATE sessions
+SET revoked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+`
+
+type RevokeSessionParams struct {
+	ID     string `json:"id"`
+	UserID int64  `json:"user_id"`
+}
+
+func (q *Queries) RevokeSession(ctx context.Context, arg RevokeSessionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeSession, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const touchActiveSession = `-- name: TouchActiveSession :one
+UPDATE sessions
+SET last_seen_at = NOW()
+FROM users
+WHERE sessions.id = $1
+  AND users.id = sessions.user_id
+  AND sessions.revoked_at IS NULL
+  AND sessions.expires_at > NOW()
+  AND (users.sessions_revoked_at IS NULL OR sessions.created_at >= users.sessions_revoked_at)
+RETURNING sessions.id, sessions.user_id, sessions.user_agent, sessions.ip, sessions.created_at, sessions.last_seen_at, sessions.expires_at, sessions.revoked_at
+`
+
+// Sessions created before the user's sessions_revoked_at, which a password
+// change sets, count as revoked.
+func (q *Queries) TouchActiveSession(ctx context.Context, id string) (Session, error) {
+	row := q.db.QueryRowContext(ctx, touchActiveSession, id)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.UserAgent,
+		&i.Ip,
+		&i.CreatedAt,
+		&i.LastSeenAt,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,144 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: mfa.sql
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const createRecoveryCode = `-- name: CreateRecoveryCode :exec
+INSERT INTO user_recovery_codes (user_id, code_hash, created_at)
+VALUES (?, ?, ?)
+`
+
+type CreateRecoveryCodeParams struct {
+	UserID    int64     `json:"user_id"`
+	CodeHash  string    `json:"code_hash"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (q *Queries) CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error {
+	_, err := q.db.ExecContext(ctx, createRecoveryCode, arg.UserID, arg.CodeHash, arg.CreatedAt)
+	return err
+}
+
+const deleteRecoveryCodes = `-- name: DeleteRecoveryCodes :exec
+DELETE FROM user_recovery_codes
+WHERE user_id = ?
+`
+
+func (q *Queries) DeleteRecoveryCodes(ctx context.Context, userID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteRecoveryCodes, userID)
+	return err
+}
+
+const disableUserTOTP = `-- name: DisableUserTOTP :exec
+UPDATE users
+SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL, updated_at = ?1, version = version + 1
+WHERE id = ?2
+`
+
+type DisableUserTOTPParams struct {
+	Now time.Time `json:"now"`
+	ID  int64     `json:"id"`
+}
+
+func (q *Queries) DisableUserTOTP(ctx context.Context, arg DisableUserTOTPParams) error {
+	_, err := q.db.ExecContext(ctx, disableUserTOTP, arg.Now, arg.ID)
+	return err
+}
+
+const enableUserTOTP = `-- name: EnableUserTOTP :exec
+UPDATE users
+SET totp_enabled_at = ?1, updated_at = ?1, version = version + 1
+WHERE id = ?2
+`
+
+type EnableUserTOTPParams struct {
+	Now sql.NullTime `json:"now"`
+	ID  int64        `json:"id"`
+}
+
+func (q *Queries) EnableUserTOTP(ctx context.Context, arg EnableUserTOTPParams) error {
+	_, err := q.db.ExecContext(ctx, enableUserTOTP, arg.Now, arg.ID)
+	return err
+}
+
+const getUserMFA = `-- name: GetUserMFA :one
+SELECT totp_secret, totp_enabled_at, totp_last_step FROM users
+WHERE id = ?
+`
+
+type GetUserMFARow struct {
+	TotpSecret    sql.NullString `json:"totp_secret"`
+	TotpEnabledAt sql.NullTime   `json:"totp_enabled_at"`
+	TotpLastStep  sql.NullInt64  `json:"totp_last_step"`
+}
+
+func (q *Queries) GetUserMFA(ctx context.Context, id int64) (GetUserMFARow, error) {
+	row := q.db.QueryRowContext(ctx, getUserMFA, id)
+	var i GetUserMFARow
+	err := row.Scan(&i.TotpSecret, &i.TotpEnabledAt, &i.TotpLastStep)
+	return i, err
+}
+
+const setUserTOTPSecret = `-- name: SetUserTOTPSecret :exec
+UPDATE users
+SET totp_secret = ?1, totp_enabled_at = NULL, totp_last_step = NULL, updated_at = ?2
+WHERE id = ?3
+`
+
+type SetUserTOTPSecretParams struct {
+	TotpSecret sql.NullString `json:"totp_secret"`
+	Now        time.Time      `json:"now"`
+	ID         int64          `json:"id"`
+}
+
+func (q *Queries) SetUserTOTPSecret(ctx context.Context, arg SetUserTOTPSecretParams) error {
+	_, err := q.db.ExecContext(ctx, setUserTOTPSecret, arg.TotpSecret, arg.Now, arg.ID)
+	return err
+}
+
+const useRecoveryCode = `-- name: UseRecoveryCode :execrows
+UPDATE user_recovery_codes
+SET used_at = ?1
+WHERE user_id = ?2 AND code_hash = ?3 AND used_at IS NULL
+`
+
+type UseRecoveryCodeParams struct {
+	Now      sql.NullTime `json:"now"`
+	UserID   int64        `json:"user_id"`
+	CodeHash string       `json:"code_hash"`
+}
+
+func (q *Queries) UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useRecoveryCode, arg.Now, arg.UserID, arg.CodeHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const useUserTOTPStep = `-- name: UseUserTOTPStep :execrows
+UPDATE users
+SET totp_last_step = ?1
+WHERE id = ?2 AND (totp_last_step IS NULL OR totp_last_step < ?1)
+`
+
+type UseUserTOTPStepParams struct {
+	Step sql.NullInt64 `json:"step"`
+	ID   int64         `json:"id"`
+}
+
+func (q *Queries) UseUserTOTPStep(ctx context.Context, arg UseUserTOTPStepParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useUserTOTPStep, arg.Step, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
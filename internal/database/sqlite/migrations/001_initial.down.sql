@@ -0,0 +1,9 @@
+DROP TABLE sessions;
+DROP TABLE personal_access_tokens;
+DROP TABLE oidc_login_states;
+DROP TABLE user_identities;
+DROP TABLE user_recovery_codes;
+DROP TABLE user_tokens;
+DROP TABLE idempotency_keys;
+DROP TABLE tasks;
+DROP TABLE users;
@@ -0,0 +1,25 @@
+CREATE TABLE users_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username TEXT NOT NULL UNIQUE,
+    email TEXT NOT NULL UNIQUE,
+    password TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    email_verified_at DATETIME,
+    sessions_revoked_at DATETIME,
+    totp_secret TEXT,
+    totp_enabled_at DATETIME,
+    totp_last_step INTEGER
+);
+
+INSERT INTO users_old (
+    id, username, email, password, created_at, updated_at, version,
+    email_verified_at, sessions_revoked_at, totp_secret, totp_enabled_at, totp_last_step
+)
+SELECT
+    id, username, email, password, created_at, updated_at, version,
+    email_verified_at, sessions_revoked_at, totp_secret, totp_enabled_at, totp_last_step
+FROM users;
+DROP TABLE users;
+ALTER TABLE users_old RENAME TO users;
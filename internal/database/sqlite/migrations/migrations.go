@@ -0,0 +1,8 @@
+// Package migrations embeds the SQLite schema migrations, named like the
+// Postgres ones in internal/database/migrations.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,107 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+
+package sqlite
+
+import (
+	"database/sql"
+	"time"
+)
+
+type IdempotencyKey struct {
+	Scope           string        `json:"scope"`
+	Key             string        `json:"key"`
+	Fingerprint     string        `json:"fingerprint"`
+	Status          string        `json:"status"`
+	ResponseStatus  sql.NullInt64 `json:"response_status"`
+	ResponseHeaders string        `json:"response_headers"`
+	ResponseBody    []byte        `json:"response_body"`
+	CreatedAt       time.Time     `json:"created_at"`
+	ExpiresAt       time.Time     `json:"expires_at"`
+}
+
+type OidcLoginState struct {
+	StateHash    string    `json:"state_hash"`
+	CodeVerifier string    `json:"code_verifier"`
+	Nonce        string    `json:"nonce"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type PersonalAccessToken struct {
+	ID         int64        `json:"id"`
+	UserID     int64        `json:"user_id"`
+	Name       string       `json:"name"`
+	TokenHash  string       `json:"token_hash"`
+	Scopes     string       `json:"scopes"`
+	ExpiresAt  time.Time    `json:"expires_at"`
+	LastUsedAt sql.NullTime `json:"last_used_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+type Session struct {
+	ID         string       `json:"id"`
+	UserID     int64        `json:"user_id"`
+	UserAgent  string       `json:"user_agent"`
+	Ip         string       `json:"ip"`
+	CreatedAt  time.Time    `json:"created_at"`
+	LastSeenAt time.Time    `json:"last_seen_at"`
+	ExpiresAt  time.Time    `json:"expires_at"`
+	RevokedAt  sql.NullTime `json:"revoked_at"`
+}
+
+type Task struct {
+	ID          int64          `json:"id"`
+	Title       string         `json:"title"`
+	Description sql.NullString `json:"description"`
+	Status      sql.NullString `json:"status"`
+	Priority    sql.NullString `json:"priority"`
+	UserID      int64          `json:"user_id"`
+	DueDate     sql.NullTime   `json:"due_date"`
+	CompletedAt sql.NullTime   `json:"completed_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	Version     int64          `json:"version"`
+}
+
+type User struct {
+	ID                int64          `json:"id"`
+	Username          string         `json:"username"`
+	Email             string         `json:"email"`
+	Password          string         `json:"password"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	Version           int64          `json:"version"`
+	EmailVerifiedAt   sql.NullTime   `json:"email_verified_at"`
+	SessionsRevokedAt sql.NullTime   `json:"sessions_revoked_at"`
+	TotpSecret        sql.NullString `json:"totp_secret"`
+	TotpEnabledAt     sql.NullTime   `json:"totp_enabled_at"`
+	TotpLastStep      sql.NullInt64  `json:"totp_last_step"`
+}
+
+type UserIdentity struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Issuer    string    `json:"issuer"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type UserRecoveryCode struct {
+	ID        int64        `json:"id"`
+	UserID    int64        `json:"user_id"`
+	CodeHash  string       `json:"code_hash"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type UserToken struct {
+	ID        int64        `json:"id"`
+	UserID    int64        `json:"user_id"`
+	Purpose   string       `json:"purpose"`
+	TokenHash string       `json:"token_hash"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
@@ -0,0 +1,135 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: oidc.sql
+
+package sqlite
+
+import (
+	"context"
+	"time"
+)
+
+const consumeOIDCLoginState = `-- name: ConsumeOIDCLoginState :one
+DELETE FROM oidc_login_states
+WHERE state_hash = ?1 AND expires_at > ?2
+RETURNING code_verifier, nonce
+`
+
+type ConsumeOIDCLoginStateParams struct {
+	StateHash string    `json:"state_hash"`
+	Now       time.Time `json:"now"`
+}
+
+type ConsumeOIDCLoginStateRow struct {
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+}
+
+func (q *Queries) ConsumeOIDCLoginState(ctx context.Context, arg ConsumeOIDCLoginStateParams) (ConsumeOIDCLoginStateRow, error) {
+	row := q.db.QueryRowContext(ctx, consumeOIDCLoginState, arg.StateHash, arg.Now)
+	var i ConsumeOIDCLoginStateRow
+	err := row.Scan(&i.CodeVerifier, &i.Nonce)
+	return i, err
+}
+
+const createOIDCLoginState = `-- name: CreateOIDCLoginState :exec
+INSERT INTO oidc_login_states (state_hash, code_verifier, nonce, expires_at)
+VALUES (?, ?, ?, ?)
+`
+
+type CreateOIDCLoginStateParams struct {
+	StateHash    string    `json:"state_hash"`
+	CodeVerifier string    `json:"code_verifier"`
+	Nonce        string    `json:"nonce"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateOIDCLoginState(ctx context.Context, arg CreateOIDCLoginStateParams) error {
+	_, err := q.db.ExecContext(ctx, createOIDCLoginState,
+		arg.StateHash,
+		arg.CodeVerifier,
+		arg.Nonce,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const createUserIdentity = `-- name: CreateUserIdentity :exec
+INSERT INTO user_identities (user_id, issuer, subject, email, created_at)
+VALUES (?, ?, ?, ?, ?)
+`
+
+type CreateUserIdentityParams struct {
+	UserID    int64     `json:"user_id"`
+	Issuer    string    `json:"issuer"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (q *Queries) CreateUserIdentity(ctx context.Context, arg CreateUserIdentityParams) error {
+	_, err := q.db.ExecContext(ctx, createUserIdentity,
+		arg.UserID,
+		arg.Issuer,
+		arg.Subject,
+		arg.Email,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const deleteExpiredOIDCLoginStates = `-- name: DeleteExpiredOIDCLoginStates :execrows
+DELETE FROM oidc_login_states
+WHERE expires_at < ?1
+`
+
+func (q *Queries) DeleteExpiredOIDCLoginStates(ctx context.Context, now time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteExpiredOIDCLoginStates, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getUserByIdentity = `-- name: GetUserByIdentity :one
+SELECT users.id, users.username, users.email, users.password, users.created_at, users.updated_at, users.version, users.email_verified_at, users.sessions_revoked_at, users.totp_secret, users.totp_enabled_at, users.totp_last_step FROM users
+JOIN user_identities ON user_identities.user_id = users.id
+WHERE user_identities.issuer = ? AND user_identities.subject = ?
+`
+
+type GetUserByIdentityParams struct {
+	Issuer  string `json:"issuer"`
+	Subject string `json:"subject"`
+}
+
+func (q *Queries) GetUserByIdentity(ctx context.Context, arg GetUserByIdentityParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByIdentity, arg.Issuer, arg.Subject)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+		&i.EmailVerifiedAt,
+		&i.SessionsRevokedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const usernameExists = `-- name: UsernameExists :one
+SELECT EXISTS (SELECT 1 FROM users WHERE username = ?)
+`
+
+func (q *Queries) UsernameExists(ctx context.Context, username string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, usernameExists, username)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
@@ -0,0 +1,70 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type Querier interface {
+	ClaimIdempotencyKey(ctx context.Context, arg ClaimIdempotencyKeyParams) (int64, error)
+	CompleteIdempotencyKey(ctx context.Context, arg CompleteIdempotencyKeyParams) error
+	ConsumeOIDCLoginState(ctx context.Context, arg ConsumeOIDCLoginStateParams) (ConsumeOIDCLoginStateRow, error)
+	ConsumeUserToken(ctx context.Context, arg ConsumeUserTokenParams) (int64, error)
+	CountOverdueTasks(ctx context.Context, now sql.NullTime) (int64, error)
+	CountTasksByStatus(ctx context.Context) ([]CountTasksByStatusRow, error)
+	CreateOIDCLoginState(ctx context.Context, arg CreateOIDCLoginStateParams) error
+	CreatePersonalAccessToken(ctx context.Context, arg CreatePersonalAccessTokenParams) (PersonalAccessToken, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
+	CreateTask(ctx context.Context, arg CreateTaskParams) (Task, error)
+	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateUserIdentity(ctx context.Context, arg CreateUserIdentityParams) error
+	CreateUserToken(ctx context.Context, arg CreateUserTokenParams) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error)
+	DeleteExpiredOIDCLoginStates(ctx context.Context, now time.Time) (int64, error)
+	DeleteExpiredUserTokens(ctx context.Context, now time.Time) (int64, error)
+	DeleteIdempotencyKey(ctx context.Context, arg DeleteIdempotencyKeyParams) error
+	DeletePersonalAccessToken(ctx context.Context, arg DeletePersonalAccessTokenParams) (int64, error)
+	DeleteRecoveryCodes(ctx context.Context, userID int64) error
+	DeleteStaleIdempotencyKey(ctx context.Context, arg DeleteStaleIdempotencyKeyParams) (int64, error)
+	DeleteStaleSessions(ctx context.Context, arg DeleteStaleSessionsParams) (int64, error)
+	DeleteTask(ctx context.Context, arg DeleteTaskParams) (int64, error)
+	DeleteUser(ctx context.Context, arg DeleteUserParams) (int64, error)
+	DeleteUserTokens(ctx context.Context, arg DeleteUserTokensParams) error
+	DisableUserTOTP(ctx context.Context, arg DisableUserTOTPParams) error
+	EnableUserTOTP(ctx context.Context, arg EnableUserTOTPParams) error
+	GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error)
+	GetPersonalAccessTokenByHash(ctx context.Context, arg GetPersonalAccessTokenByHashParams) (PersonalAccessToken, error)
+	GetTaskByID(ctx context.Context, id int64) (Task, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserByID(ctx context.Context, id int64) (User, error)
+	GetUserByIdentity(ctx context.Context, arg GetUserByIdentityParams) (User, error)
+	GetUserMFA(ctx context.Context, id int64) (GetUserMFARow, error)
+	GetUserTokenOwner(ctx context.Context, arg GetUserTokenOwnerParams) (int64, error)
+	ListActiveSessions(ctx context.Context, arg ListActiveSessionsParams) ([]Session, error)
+	ListPersonalAccessTokens(ctx context.Context, userID int64) ([]PersonalAccessToken, error)
+	ListTasksByUser(ctx context.Context, userID int64) ([]Task, error)
+	MarkUserEmailVerified(ctx context.Context, arg MarkUserEmailVerifiedParams) error
+	PatchTask(ctx context.Context, arg PatchTaskParams) (Task, error)
+	RevokeOtherSessions(ctx context.Context, arg RevokeOtherSessionsParams) ([]string, error)
+	RevokeSession(ctx context.Context, arg RevokeSessionParams) (int64, error)
+	SetUserTOTPSecret(ctx context.Context, arg SetUserTOTPSecretParams) error
+	// Sessions created before the user's sessions_revoked_at, which a password
+	// change sets, count as revoked.
+	TouchActiveSession(ctx context.Context, arg TouchActiveSessionParams) (Session, error)
+	TouchPersonalAccessToken(ctx context.Context, arg TouchPersonalAccessTokenParams) error
+	UpdateTask(ctx context.Context, arg UpdateTaskParams) (Task, error)
+	UpdateTaskStatus(ctx context.Context, arg UpdateTaskStatusParams) (Task, error)
+	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
+	UseUserTOTPStep(ctx context.Context, arg UseUserTOTPStepParams) (int64, error)
+	UsernameExists(ctx context.Context, username string) (int64, error)
+}
+
+var _ Querier = (*Queries)(nil)
@@ -0,0 +1,26 @@
+-- name: ClaimIdempotencyKey :execrows
+INSERT INTO idempotency_keys (scope, key, fingerprint, created_at, expires_at)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (scope, key) DO NOTHING;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE scope = ? AND key = ?;
+
+-- name: CompleteIdempotencyKey :exec
+UPDATE idempotency_keys
+SET status = 'completed', response_status = ?, response_headers = ?, response_body = ?
+WHERE scope = ? AND key = ?;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE scope = ? AND key = ?;
+
+-- name: DeleteStaleIdempotencyKey :execrows
+DELETE FROM idempotency_keys
+WHERE scope = sqlc.arg(scope) AND key = sqlc.arg(key)
+  AND (expires_at < sqlc.arg(now) OR (status = 'in_progress' AND created_at < sqlc.arg(abandoned_before)));
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE expires_at < sqlc.arg(now);
@@ -0,0 +1,36 @@
+-- name: GetUserMFA :one
+SELECT totp_secret, totp_enabled_at, totp_last_step FROM users
+WHERE id = ?;
+
+-- name: SetUserTOTPSecret :exec
+UPDATE users
+SET totp_secret = sqlc.arg(totp_secret), totp_enabled_at = NULL, totp_last_step = NULL, updated_at = sqlc.arg(now)
+WHERE id = sqlc.arg(id);
+
+-- name: EnableUserTOTP :exec
+UPDATE users
+SET totp_enabled_at = sqlc.arg(now), updated_at = sqlc.arg(now), version = version + 1
+WHERE id = sqlc.arg(id);
+
+-- name: DisableUserTOTP :exec
+UPDATE users
+SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL, updated_at = sqlc.arg(now), version = version + 1
+WHERE id = sqlc.arg(id);
+
+-- name: UseUserTOTPStep :execrows
+UPDATE users
+SET totp_last_step = sqlc.arg(step)
+WHERE id = sqlc.arg(id) AND (totp_last_step IS NULL OR totp_last_step < sqlc.arg(step));
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO user_recovery_codes (user_id, code_hash, created_at)
+VALUES (?, ?, ?);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM user_recovery_codes
+WHERE user_id = ?;
+
+-- name: UseRecoveryCode :execrows
+UPDATE user_recovery_codes
+SET used_at = sqlc.arg(now)
+WHERE user_id = sqlc.arg(user_id) AND code_hash = sqlc.arg(code_hash) AND used_at IS NULL;
@@ -0,0 +1,24 @@
+-- name: GetUserByIdentity :one
+SELECT users.* FROM users
+JOIN user_identities ON user_identities.user_id = users.id
+WHERE user_identities.issuer = ? AND user_identities.subject = ?;
+
+-- name: CreateUserIdentity :exec
+INSERT INTO user_identities (user_id, issuer, subject, email, created_at)
+VALUES (?, ?, ?, ?, ?);
+
+-- name: CreateOIDCLoginState :exec
+INSERT INTO oidc_login_states (state_hash, code_verifier, nonce, expires_at)
+VALUES (?, ?, ?, ?);
+
+-- name: ConsumeOIDCLoginState :one
+DELETE FROM oidc_login_states
+WHERE state_hash = sqlc.arg(state_hash) AND expires_at > sqlc.arg(now)
+RETURNING code_verifier, nonce;
+
+-- name: DeleteExpiredOIDCLoginStates :execrows
+DELETE FROM oidc_login_states
+WHERE expires_at < sqlc.arg(now);
+
+-- name: UsernameExists :one
+SELECT EXISTS (SELECT 1 FROM users WHERE username = ?);
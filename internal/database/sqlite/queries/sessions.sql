@@ -0,0 +1,43 @@
+-- name: CreateSession :one
+INSERT INTO sessions (id, user_id, user_agent, ip, created_at, last_seen_at, expires_at)
+VALUES (sqlc.arg(id), sqlc.arg(user_id), sqlc.arg(user_agent), sqlc.arg(ip), sqlc.arg(now), sqlc.arg(now), sqlc.arg(expires_at))
+RETURNING *;
+
+-- name: TouchActiveSession :one
+-- Sessions created before the user's sessions_revoked_at, which a password
+-- change sets, count as revoked.
+UPDATE sessions
+SET last_seen_at = sqlc.arg(now)
+WHERE sessions.id = sqlc.arg(id)
+  AND sessions.revoked_at IS NULL
+  AND sessions.expires_at > sqlc.arg(now)
+  AND EXISTS (
+    SELECT 1 FROM users
+    WHERE users.id = sessions.user_id
+      AND (users.sessions_revoked_at IS NULL OR sessions.created_at >= users.sessions_revoked_at)
+  )
+RETURNING *;
+
+-- name: ListActiveSessions :many
+SELECT sessions.* FROM sessions
+JOIN users ON users.id = sessions.user_id
+WHERE sessions.user_id = sqlc.arg(user_id)
+  AND sessions.revoked_at IS NULL
+  AND sessions.expires_at > sqlc.arg(now)
+  AND (users.sessions_revoked_at IS NULL OR sessions.created_at >= users.sessions_revoked_at)
+ORDER BY sessions.last_seen_at DESC;
+
+-- name: RevokeSession :execrows
+UPDATE sessions
+SET revoked_at = sqlc.arg(now)
+WHERE id = sqlc.arg(id) AND user_id = sqlc.arg(user_id) AND revoked_at IS NULL;
+
+-- name: RevokeOtherSessions :many
+UPDATE sessions
+SET revoked_at = sqlc.arg(now)
+WHERE user_id = sqlc.arg(user_id) AND id <> sqlc.arg(except_id) AND revoked_at IS NULL
+RETURNING id;
+
+-- name: DeleteStaleSessions :execrows
+DELETE FROM sessions
+WHERE expires_at < sqlc.arg(now) OR revoked_at < sqlc.arg(revoked_before);
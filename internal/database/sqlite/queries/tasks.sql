@@ -0,0 +1,49 @@
+-- name: GetTaskByID :one
+SELECT * FROM tasks
+WHERE id = ?;
+
+-- name: ListTasksByUser :many
+SELECT * FROM tasks
+WHERE user_id = ?
+ORDER BY created_at DESC, id DESC;
+
+-- name: CreateTask :one
+INSERT INTO tasks (title, description, status, priority, user_id, due_date, created_at, updated_at)
+VALUES (sqlc.arg(title), sqlc.arg(description), sqlc.arg(status), sqlc.arg(priority), sqlc.arg(user_id), sqlc.arg(due_date), sqlc.arg(now), sqlc.arg(now))
+RETURNING *;
+
+-- name: UpdateTask :one
+UPDATE tasks
+SET title = sqlc.arg(title), description = sqlc.arg(description), status = sqlc.arg(status), priority = sqlc.arg(priority), due_date = sqlc.arg(due_date), updated_at = sqlc.arg(now), version = version + 1
+WHERE id = sqlc.arg(id) AND (version = sqlc.arg(version) OR sqlc.arg(version) = 0)
+RETURNING *;
+
+-- name: DeleteTask :execrows
+DELETE FROM tasks
+WHERE id = sqlc.arg(id) AND (version = sqlc.arg(version) OR sqlc.arg(version) = 0);
+
+-- name: UpdateTaskStatus :one
+UPDATE tasks
+SET status = sqlc.arg(status), updated_at = sqlc.arg(now), version = version + 1
+WHERE id = sqlc.arg(id) AND (version = sqlc.arg(version) OR sqlc.arg(version) = 0)
+RETURNING *;
+
+-- name: PatchTask :one
+UPDATE tasks
+SET title = COALESCE(sqlc.narg(title), title),
+    description = CASE WHEN CAST(sqlc.arg(set_description) AS BOOLEAN) THEN sqlc.narg(description) ELSE description END,
+    status = COALESCE(sqlc.narg(status), status),
+    priority = CASE WHEN CAST(sqlc.arg(set_priority) AS BOOLEAN) THEN sqlc.narg(priority) ELSE priority END,
+    due_date = CASE WHEN CAST(sqlc.arg(set_due_date) AS BOOLEAN) THEN sqlc.narg(due_date) ELSE due_date END,
+    updated_at = sqlc.arg(now),
+    version = version + 1
+WHERE id = sqlc.arg(id) AND (version = sqlc.arg(version) OR sqlc.arg(version) = 0)
+RETURNING *;
+
+-- name: CountTasksByStatus :many
+SELECT CAST(COALESCE(status, '') AS TEXT) AS status, COUNT(*) AS count FROM tasks
+GROUP BY status;
+
+-- name: CountOverdueTasks :one
+SELECT COUNT(*) FROM tasks
+WHERE due_date < sqlc.arg(now) AND status IS NOT 'completed';
@@ -0,0 +1,36 @@
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = ?;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = ?;
+
+-- name: CreateUser :one
+INSERT INTO users (username, email, password, created_at, updated_at)
+VALUES (sqlc.arg(username), sqlc.arg(email), sqlc.arg(password), sqlc.arg(now), sqlc.arg(now))
+RETURNING *;
+
+-- name: UpdateUser :one
+UPDATE users
+SET username = sqlc.arg(username),
+    email = sqlc.arg(email),
+    email_verified_at = CASE WHEN email = sqlc.arg(email) THEN email_verified_at END,
+    updated_at = sqlc.arg(now),
+    version = version + 1
+WHERE id = sqlc.arg(id) AND (version = sqlc.arg(version) OR sqlc.arg(version) = 0)
+RETURNING *;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET password = sqlc.arg(password), sessions_revoked_at = sqlc.arg(now), updated_at = sqlc.arg(now), version = version + 1
+WHERE id = sqlc.arg(id);
+
+-- name: MarkUserEmailVerified :exec
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, sqlc.arg(now)), updated_at = sqlc.arg(now), version = version + 1
+WHERE id = sqlc.arg(id);
+
+-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE id = sqlc.arg(id) AND (version = sqlc.arg(version) OR sqlc.arg(version) = 0);
@@ -0,0 +1,21 @@
+-- name: CreateUserToken :exec
+INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at, created_at)
+VALUES (?, ?, ?, ?, ?);
+
+-- name: ConsumeUserToken :one
+UPDATE user_tokens
+SET used_at = sqlc.arg(now)
+WHERE token_hash = sqlc.arg(token_hash) AND purpose = sqlc.arg(purpose) AND used_at IS NULL AND expires_at > sqlc.arg(now)
+RETURNING user_id;
+
+-- name: GetUserTokenOwner :one
+SELECT user_id FROM user_tokens
+WHERE token_hash = sqlc.arg(token_hash) AND purpose = sqlc.arg(purpose) AND used_at IS NULL AND expires_at > sqlc.arg(now);
+
+-- name: DeleteUserTokens :exec
+DELETE FROM user_tokens
+WHERE user_id = ? AND purpose = ?;
+
+-- name: DeleteExpiredUserTokens :execrows
+DELETE FROM user_tokens
+WHERE expires_at < sqlc.arg(now);
@@ -0,0 +1,208 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: sessions.sql
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const createSession = `-- name: CreateSession :one
+INSERT INTO sessions (id, user_id, user_agent, ip, created_at, last_seen_at, expires_at)
+VALUES (?1, ?2, ?3, ?4, ?5, ?5, ?6)
+RETURNING id, user_id, user_agent, ip, created_at, last_seen_at, expires_at, revoked_at
+`
+
+type CreateSessionParams struct {
+	ID        string    `json:"id"`
+	UserID    int64     `json:"user_id"`
+	UserAgent string    `json:"user_agent"`
+	Ip        string    `json:"ip"`
+	Now       time.Time `json:"now"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
+	row := q.db.QueryRowContext(ctx, createSession,
+		arg.ID,
+		arg.UserID,
+		arg.UserAgent,
+		arg.Ip,
+		arg.Now,
+		arg.ExpiresAt,
+	)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.UserAgent,
+		&i.Ip,
+		&i.CreatedAt,
+		&i.LastSeenAt,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const deleteStaleSessions = `-- name: DeleteStaleSessions :execrows
+DELETE FROM sessions
+WHERE expires_at < ?1 OR revoked_at < ?2
+`
+
+type DeleteStaleSessionsParams struct {
+	Now           time.Time    `json:"now"`
+	RevokedBefore sql.NullTime `json:"revoked_before"`
+}
+
+func (q *Queries) DeleteStaleSessions(ctx context.Context, arg DeleteStaleSessionsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteStaleSessions, arg.Now, arg.RevokedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const listActiveSessions = `-- name: ListActiveSessions :many
+SELECT sessions.id, sessions.user_id, sessions.user_agent, sessions.ip, sessions.created_at, sessions.last_seen_at, sessions.expires_at, sessions.revoked_at FROM sessions
+JOIN users ON users.id = sessions.user_id
+WHERE sessions.user_id = ?1
+  AND sessions.revoked_at IS NULL
+  AND sessions.expires_at > ?2
+  AND (users.sessions_revoked_at IS NULL OR sessions.created_at >= users.sessions_revoked_at)
+ORDER BY sessions.last_seen_at DESC
+`
+
+type ListActiveSessionsParams struct {
+	UserID int64     `json:"user_id"`
+	Now    time.Time `json:"now"`
+}
+
+func (q *Queries) ListActiveSessions(ctx context.Context, arg ListActiveSessionsParams) ([]Session, error) {
+	rows, err := q.db.QueryContext(ctx, listActiveSessions, arg.UserID, arg.Now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Session{}
+	for rows.Next() {
+		var i Session
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.UserAgent,
+			&i.Ip,
+			&i.CreatedAt,
+			&i.LastSeenAt,
+			&i.ExpiresAt,
+			&i.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeOtherSessions = `-- name: RevokeOtherSessions :many
+UPDATE sessions
+SET revoked_at = ?1
+WHERE user_id = ?2 AND id <> ?3 AND revoked_at IS NULL
+RETURNING id
+`
+
+type RevokeOtherSessionsParams struct {
+	Now      sql.NullTime `json:"now"`
+	UserID   int64        `json:"user_id"`
+	ExceptID string       `json:"except_id"`
+}
+
+func (q *Queries) RevokeOtherSessions(ctx context.Context, arg RevokeOtherSessionsParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, revokeOtherSessions, arg.Now, arg.UserID, arg.ExceptID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeSession = `-- name: RevokeSession :execrows
+UPDATE sessions
+SET revoked_at = ?1
+WHERE id = ?2 AND user_id = ?3 AND revoked_at IS NULL
+`
+
+type RevokeSessionParams struct {
+	Now    sql.NullTime `json:"now"`
+	ID     string       `json:"id"`
+	UserID int64        `json:"user_id"`
+}
+
+func (q *Queries) RevokeSession(ctx context.Context, arg RevokeSessionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeSession, arg.Now, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const touchActiveSession = `-- name: TouchActiveSession :one
+UPDATE sessions
+SET last_seen_at = ?1
+WHERE sessions.id = ?2
+  AND sessions.revoked_at IS NULL
+  AND sessions.expires_at > ?1
+  AND EXISTS (
+    SELECT 1 FROM users
+    WHERE users.id = sessions.user_id
+      AND (users.sessions_revoked_at IS NULL OR sessions.created_at >= users.sessions_revoked_at)
+  )
+RETURNING id, user_id, user_agent, ip, created_at, last_seen_at, expires_at, revoked_at
+`
+
+type TouchActiveSessionParams struct {
+	Now time.Time `json:"now"`
+	ID  string    `json:"id"`
+}
+
+// Sessions created before the user's sessions_revoked_at, which a password
+// change sets, count as revoked.
+func (q *Queries) TouchActiveSession(ctx context.Context, arg TouchActiveSessionParams) (Session, error) {
+	row := q.db.QueryRowContext(ctx, touchActiveSession, arg.Now, arg.ID)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.UserAgent,
+		&i.Ip,
+		&i.CreatedAt,
+		&i.LastSeenAt,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,196 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: user.sql
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const createUser = `-- name: CreateUser :one
+INSERT INTO users (username, email, password, created_at, updated_at)
+VALUES (?1, ?2, ?3, ?4, ?4)
+RETURNING id, username, email, password, created_at, updated_at, version, email_verified_at, sessions_revoked_at, totp_secret, totp_enabled_at, totp_last_step
+`
+
+type CreateUserParams struct {
+	Username string    `json:"username"`
+	Email    string    `json:"email"`
+	Password string    `json:"password"`
+	Now      time.Time `json:"now"`
+}
+
+func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, createUser,
+		arg.Username,
+		arg.Email,
+		arg.Password,
+		arg.Now,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+		&i.EmailVerifiedAt,
+		&i.SessionsRevokedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const deleteUser = `-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE id = ?1 AND (version = ?2 OR ?2 = 0)
+`
+
+type DeleteUserParams struct {
+	ID      int64 `json:"id"`
+	Version int64 `json:"version"`
+}
+
+func (q *Queries) DeleteUser(ctx context.Context, arg DeleteUserParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUser, arg.ID, arg.Version)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT id, username, email, password, created_at, updated_at, version, email_verified_at, sessions_revoked_at, totp_secret, totp_enabled_at, totp_last_step FROM users
+WHERE email = ?
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+		&i.EmailVerifiedAt,
+		&i.SessionsRevokedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, username, email, password, created_at, updated_at, version, email_verified_at, sessions_revoked_at, totp_secret, totp_enabled_at, totp_last_step FROM users
+WHERE id = ?
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+		&i.EmailVerifiedAt,
+		&i.SessionsRevokedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const markUserEmailVerified = `-- name: MarkUserEmailVerified :exec
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, ?1), updated_at = ?1, version = version + 1
+WHERE id = ?2
+`
+
+type MarkUserEmailVerifiedParams struct {
+	Now time.Time `json:"now"`
+	ID  int64     `json:"id"`
+}
+
+func (q *Queries) MarkUserEmailVerified(ctx context.Context, arg MarkUserEmailVerifiedParams) error {
+	_, err := q.db.ExecContext(ctx, markUserEmailVerified, arg.Now, arg.ID)
+	return err
+}
+
+const updateUser = `-- name: UpdateUser :one
+UPDATE users
+SET username = ?1,
+    email = ?2,
+    email_verified_at = CASE WHEN email = ?2 THEN email_verified_at END,
+    updated_at = ?3,
+    version = version + 1
+WHERE id = ?4 AND (version = ?5 OR ?5 = 0)
+RETURNING id, username, email, password, created_at, updated_at, version, email_verified_at, sessions_revoked_at, totp_secret, totp_enabled_at, totp_last_step
+`
+
+type UpdateUserParams struct {
+	Username string    `json:"username"`
+	Email    string    `json:"email"`
+	Now      time.Time `json:"now"`
+	ID       int64     `json:"id"`
+	Version  int64     `json:"version"`
+}
+
+func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUser,
+		arg.Username,
+		arg.Email,
+		arg.Now,
+		arg.ID,
+		arg.Version,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+		&i.EmailVerifiedAt,
+		&i.SessionsRevokedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET password = ?1, sessions_revoked_at = ?2, updated_at = ?2, version = version + 1
+WHERE id = ?3
+`
+
+type UpdateUserPasswordParams struct {
+	Password string       `json:"password"`
+	Now      sql.NullTime `json:"now"`
+	ID       int64        `json:"id"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.Password, arg.Now, arg.ID)
+	return err
+}
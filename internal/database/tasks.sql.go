@@ -10,10 +10,55 @@ import (
 	"database/sql"
 )
 
+const countOverdueTasks = `-- name: CountOverdueTasks :one
+SELECT COUNT(*) FROM tasks
+WHERE due_date < NOW() AND status IS DISTINCT FROM 'completed'
+`
+
+func (q *Queries) CountOverdueTasks(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countOverdueTasks)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countTasksByStatus = `-- name: CountTasksByStatus :many
+SELECT COALESCE(status, '')::text AS status, COUNT(*) AS count FROM tasks
+GROUP BY status
+`
+
+type CountTasksByStatusRow struct {
+	Status string `json:"status"`
+	Count  int64  `json:"count"`
+}
+
+func (q *Queries) CountTasksByStatus(ctx context.Context) ([]CountTasksByStatusRow, error) {
+	rows, err := q.db.QueryContext(ctx, countTasksByStatus)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []CountTasksByStatusRow{}
+	for rows.Next() {
+		var i CountTasksByStatusRow
+		if err := rows.Scan(&i.Status, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createTask = `-- name: CreateTask :one
 INSERT INTO tasks (title, description, status, priority, user_id, due_date)
 VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, title, description, status, priority, user_id, due_date, completed_at, created_at, updated_at
+RETURNING id, title, description, status, priority, user_id, due_date, completed_at, created_at, updated_at, version
 `
 
 type CreateTaskParams struct {
@@ -46,22 +91,31 @@ func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, e
 		&i.CompletedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Version,
 	)
 	return i, err
 }
 
-const deleteTask = `-- name: DeleteTask :exec
+const deleteTask = `-- name: DeleteTask :execrows
 DELETE FROM tasks
-WHERE id = $1
+WHERE id = $1 AND (version = $2 OR $2 = 0)
 `
 
-func (q *Queries) DeleteTask(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, deleteTask, id)
-	return err
+type DeleteTaskParams struct {
+	ID      int64 `json:"id"`
+	Version int64 `json:"version"`
+}
+
+func (q *Queries) DeleteTask(ctx context.Context, arg DeleteTaskParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteTask, arg.ID, arg.Version)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const getTaskByID = `-- name: GetTaskByID :one
-SELECT id, title, description, status, priority, user_id, due_date, completed_at, created_at, updated_at FROM tasks
+SELECT id, title, description, status, priority, user_id, due_date, completed_at, created_at, updated_at, version FROM tasks
 WHERE id = $1
 `
 
@@ -79,14 +133,15 @@ func (q *Queries) GetTaskByID(ctx context.Context, id int64) (Task, error) {
 		&i.CompletedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Version,
 	)
 	return i, err
 }
 
 const listTasksByUser = `-- name: ListTasksByUser :many
-SELECT id, title, description, status, priority, user_id, due_date, completed_at, created_at, updated_at FROM tasks
+SELECT id, title, description, status, priority, user_id, due_date, completed_at, created_at, updated_at, version FROM tasks
 WHERE user_id = $1
-ORDER BY created_at DESC
+ORDER BY created_at DESC, id DESC
 `
 
 func (q *Queries) ListTasksByUser(ctx context.Context, userID int64) ([]Task, error) {
@@ -109,6 +164,7 @@ func (q *Queries) ListTasksByUser(ctx context.Context, userID int64) ([]Task, er
 			&i.CompletedAt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Version,
 		); err != nil {
 			return nil, err
 		}
@@ -123,11 +179,67 @@ func (q *Queries) ListTasksByUser(ctx context.Context, userID int64) ([]Task, er
 	return items, nil
 }
 
+const patchTask = `-- name: PatchTask :one
+UPDATE tasks
+SET title = COALESCE($1::text, title),
+    description = CASE WHEN $2::boolean THEN $3::text ELSE description END,
+    status = COALESCE($4::text, status),
+    priority = CASE WHEN $5::boolean THEN $6::text ELSE priority END,
+    due_date = CASE WHEN $7::boolean THEN $8::timestamptz ELSE due_date END,
+    updated_at = NOW(),
+    version = version + 1
+WHERE id = $9 AND (version = $10 OR $10 = 0)
+RETURNING id, title, description, status, priority, user_id, due_date, completed_at, created_at, updated_at, version
+`
+
+type PatchTaskParams struct {
+	Title          sql.NullString `json:"title"`
+	SetDescription bool           `json:"set_description"`
+	Description    sql.NullString `json:"description"`
+	Status         sql.NullString `json:"status"`
+	SetPriority    bool           `json:"set_priority"`
+	Priority       sql.NullString `json:"priority"`
+	SetDueDate     bool           `json:"set_due_date"`
+	DueDate        sql.NullTime   `json:"due_date"`
+	ID             int64          `json:"id"`
+	Version        int64          `json:"version"`
+}
+
+func (q *Queries) PatchTask(ctx context.Context, arg PatchTaskParams) (Task, error) {
+	row := q.db.QueryRowContext(ctx, patchTask,
+		arg.Title,
+		arg.SetDescription,
+		arg.Description,
+		arg.Status,
+		arg.SetPriority,
+		arg.Priority,
+		arg.SetDueDate,
+		arg.DueDate,
+		arg.ID,
+		arg.Version,
+	)
+	var i Task
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.Priority,
+		&i.UserID,
+		&i.DueDate,
+		&i.CompletedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+	)
+	return i, err
+}
+
 const updateTask = `-- name: UpdateTask :one
 UPDATE tasks
-SET title = $2, description = $3, status = $4, priority = $5, due_date = $6, updated_at = NOW()
-WHERE id = $1
-RETURNING id, title, description, status, priority, user_id, due_date, completed_at, created_at, updated_at
+SET title = $2, description = $3, status = $4, priority = $5, due_date = $6, updated_at = NOW(), version = version + 1
+WHERE id = $1 AND (version = $7 OR $7 = 0)
+RETURNING id, title, description, status, priority, user_id, due_date, completed_at, created_at, updated_at, version
 `
 
 type UpdateTaskParams struct {
@@ -137,6 +249,7 @@ type UpdateTaskParams struct {
 	Status      sql.NullString `json:"status"`
 	Priority    sql.NullString `json:"priority"`
 	DueDate     sql.NullTime   `json:"due_date"`
+	Version     int64          `json:"version"`
 }
 
 func (q *Queries) UpdateTask(ctx context.Context, arg UpdateTaskParams) (Task, error) {
@@ -147,6 +260,7 @@ func (q *Queries) UpdateTask(ctx context.Context, arg UpdateTaskParams) (Task, e
 		arg.Status,
 		arg.Priority,
 		arg.DueDate,
+		arg.Version,
 	)
 	var i Task
 	err := row.Scan(
@@ -160,24 +274,26 @@ func (q *Queries) UpdateTask(ctx context.Context, arg UpdateTaskParams) (Task, e
 		&i.CompletedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Version,
 	)
 	return i, err
 }
 
 const updateTaskStatus = `-- name: UpdateTaskStatus :one
 UPDATE tasks
-SET status = $2, updated_at = NOW()
-WHERE id = $1
-RETURNING id, title, description, status, priority, user_id, due_date, completed_at, created_at, updated_at
+SET status = $2, updated_at = NOW(), version = version + 1
+WHERE id = $1 AND (version = $3 OR $3 = 0)
+RETURNING id, title, description, status, priority, user_id, due_date, completed_at, created_at, updated_at, version
 `
 
 type UpdateTaskStatusParams struct {
-	ID     int64          `json:"id"`
-	Status sql.NullString `json:"status"`
+	ID      int64          `json:"id"`
+	Status  sql.NullString `json:"status"`
+	Version int64          `json:"version"`
 }
 
 func (q *Queries) UpdateTaskStatus(ctx context.Context, arg UpdateTaskStatusParams) (Task, error) {
-	row := q.db.QueryRowContext(ctx, updateTaskStatus, arg.ID, arg.Status)
+	row := q.db.QueryRowContext(ctx, updateTaskStatus, arg.ID, arg.Status, arg.Version)
 	var i Task
 	err := row.Scan(
 		&i.ID,
@@ -190,6 +306,7 @@ func (q *Queries) UpdateTaskStatus(ctx context.Context, arg UpdateTaskStatusPara
 		&i.CompletedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Version,
 	)
 	return i, err
 }
@@ -12,7 +12,7 @@ import (
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (username, email, password)
 VALUES ($1, $2, $3)
-RETURNING id, username, email, password, created_at, updated_at
+RETURNING id, username, email, password, created_at, updated_at, version, email_verified_at, sessions_revoked_at, totp_secret, totp_enabled_at, totp_last_step
 `
 
 type CreateUserParams struct {
@@ -31,22 +31,36 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Password,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Version,
+		&i.EmailVerifiedAt,
+		&i.SessionsRevokedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
 
-const deleteUser = `-- name: DeleteUser :exec
+const deleteUser = `-- name: DeleteUser :execrows
 DELETE FROM users
-WHERE id = $1
+WHERE id = $1 AND (version = $2 OR $2 = 0)
 `
 
-func (q *Queries) DeleteUser(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, deleteUser, id)
-	return err
+type DeleteUserParams struct {
+	ID      int64 `json:"id"`
+	Version int64 `json:"version"`
+}
+
+func (q *Queries) DeleteUser(ctx context.Context, arg DeleteUserParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUser, arg.ID, arg.Version)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, username, email, password, created_at, updated_at FROM users
+SELECT id, username, email, password, created_at, updated_at, version, email_verified_at, sessions_revoked_at, totp_secret, totp_enabled_at, totp_last_step FROM users
 WHERE email = $1
 `
 
@@ -60,12 +74,18 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Password,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Version,
+		&i.EmailVerifiedAt,
+		&i.SessionsRevokedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, username, email, password, created_at, updated_at FROM users
+SELECT id, username, email, password, created_at, updated_at, version, email_verified_at, sessions_revoked_at, totp_secret, totp_enabled_at, totp_last_step FROM users
 WHERE id = $1
 `
 
@@ -79,25 +99,52 @@ func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
 		&i.Password,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Version,
+		&i.EmailVerifiedAt,
+		&i.SessionsRevokedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
 
-const updateUser = `-- name: UpdateUser :one
+const markUserEmailVerified = `-- name: MarkUserEmailVerified :exec
 UPDATE users
-SET username = $2, email = $3, updated_at = NOW()
+SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW(), version = version + 1
 WHERE id = $1
-RETURNING id, username, email, password, created_at, updated_at
+`
+
+func (q *Queries) MarkUserEmailVerified(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, markUserEmailVerified, id)
+	return err
+}
+
+const updateUser = `-- name: UpdateUser :one
+UPDATE users
+SET username = $2,
+    email = $3,
+    email_verified_at = CASE WHEN email = $3 THEN email_verified_at END,
+    updated_at = NOW(),
+    version = version + 1
+WHERE id = $1 AND (version = $4 OR $4 = 0)
+RETURNING id, username, email, password, created_at, updated_at, version, email_verified_at, sessions_revoked_at, totp_secret, totp_enabled_at, totp_last_step
 `
 
 type UpdateUserParams struct {
 	ID       int64  `json:"id"`
 	Username string `json:"username"`
 	Email    string `json:"email"`
+	Version  int64  `json:"version"`
 }
 
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, updateUser, arg.ID, arg.Username, arg.Email)
+	row := q.db.QueryRowContext(ctx, updateUser,
+		arg.ID,
+		arg.Username,
+		arg.Email,
+		arg.Version,
+	)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -106,6 +153,28 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Password,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Version,
+		&i.EmailVerifiedAt,
+		&i.SessionsRevokedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET password = $2, sessions_revoked_at = NOW(), updated_at = NOW(), version = version + 1
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID       int64  `json:"id"`
+	Password string `json:"password"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.ID, arg.Password)
+	return err
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// AccessTokenPrefix marks personal access tokens so they can be told apart
+// from JWTs, and recognised by secret scanners.
+const AccessTokenPrefix = "tsk_pat_"
+
+// Scopes a personal access token can be granted. tasks:write implies
+// tasks:read and admin implies every scope.
+const (
+	ScopeTasksRead  = "tasks:read"
+	ScopeTasksWrite = "tasks:write"
+	ScopeAdmin      = "admin"
+)
+
+var Scopes = []string{ScopeTasksRead, ScopeTasksWrite, ScopeAdmin}
+
+type PersonalAccessToken struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"userId"`
+	Name       string    `json:"name"`
+	Scopes     []string  `json:"scopes"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// HasScope reports whether granted allows scope.
+func HasScope(granted []string, scope string) bool {
+	if slices.Contains(granted, ScopeAdmin) || slices.Contains(granted, scope) {
+		return true
+	}
+	return scope == ScopeTasksRead && slices.Contains(granted, ScopeTasksWrite)
+}
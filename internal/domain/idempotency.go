@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+const (
+	IdempotencyInProgress = "in_progress"
+	IdempotencyCompleted  = "completed"
+)
+
+type IdempotencyRecord struct {
+	Scope           string
+	Key             string
+	Fingerprint     string
+	Status          string
+	ResponseStatus  int
+	ResponseHeaders map[string]string
+	ResponseBody    []byte
+	CreatedAt       time.Time
+	ExpiresAt       time.Time
+}
@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// Session is a sign-in from one device. Its ID is the jti of the JWTs
+// issued for it.
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     int64     `json:"userId"`
+	UserAgent  string    `json:"userAgent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
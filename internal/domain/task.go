@@ -12,4 +12,49 @@ type Task struct {
 	Duedate     time.Time `json:"dueDate"`
 	CompletedAt time.Time `json:"completedAt"`
 	UpdatedAt   time.Time `json:"updatedAt"`
+	Version     int64     `json:"version"`
+}
+
+// TaskStats counts tasks by status. Overdue tasks are past their due date
+// and not completed.
+type TaskStats struct {
+	ByStatus map[string]int64
+	Overdue  int64
+}
+
+// TaskPatch describes a partial update following JSON Merge Patch semantics.
+// A nil pointer leaves the field unchanged; for the clearable fields the
+// matching Set flag combined with a nil pointer clears the stored value.
+type TaskPatch struct {
+	Title          *string
+	Status         *string
+	SetDescription bool
+	Description    *string
+	SetPriority    bool
+	Priority       *string
+	SetDueDate     bool
+	DueDate        *string
+}
+
+// TaskOperation is a single step of a batch request. Op is one of "create",
+// "update", "status" or "delete"; Version is optional and, when set, must
+// match the stored task for update, status and delete steps.
+type TaskOperation struct {
+	Op          string
+	ID          int64
+	Version     int64
+	Title       string
+	Description string
+	Status      string
+	Priority    string
+	UserID      int64
+	DueDate     string
+}
+
+// TaskOperationResult reports the outcome of the TaskOperation at Index.
+type TaskOperationResult struct {
+	Index int
+	Op    string
+	Task  *Task
+	Err   error
 }
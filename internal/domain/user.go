@@ -3,10 +3,29 @@ package domain
 import "time"
 
 type User struct {
-	ID        int64     `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Password  string    `json:"password,omitempty"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	// The bcrypt hash. Handlers answer with handler.UserResponse; the tag
+	// keeps it out of any JSON that slips through anyway.
+	Password        string    `json:"-"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+	Version         int64     `json:"version"`
+	EmailVerifiedAt time.Time `json:"emailVerifiedAt"`
+	MFAEnabled      bool      `json:"mfaEnabled"`
 }
+
+// UserMFA is the TOTP state of a user. A secret without EnabledAt is an
+// enrollment that has not been confirmed yet.
+type UserMFA struct {
+	Secret    string
+	EnabledAt time.Time
+	LastStep  int64
+}
+
+const (
+	TokenPasswordReset     = "password_reset"
+	TokenEmailVerification = "email_verification"
+	TokenMFAChallenge      = "mfa_challenge"
+)
@@ -1,9 +1,105 @@
 package errors
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Error is an application error: a stable code clients can switch on, the
+// HTTP status it maps to, a message safe to show, optional details and the
+// underlying cause, which is logged but never sent to the client.
+//
+// The variables below are the kinds of error; derive specific errors from
+// them with the With and Wrap methods. errors.Is matches errors by code, so
+// errors.Is(err, ErrNotFound) holds for any not-found error.
+type Error struct {
+	Code    string
+	Status  int
+	Message string
+	Details map[string]any
+	Err     error
+}
 
 var (
-	ErrNotFound       = errors.New("resource not found")
-	ErrBadRequest     = errors.New("bad request")
-	ErrInternalServer = errors.New("internal server error")
+	ErrNotFound             = &Error{Code: "not_found", Status: http.StatusNotFound, Message: "resource not found"}
+	ErrBadRequest           = &Error{Code: "bad_request", Status: http.StatusBadRequest, Message: "bad request"}
+	ErrInternalServer       = &Error{Code: "internal", Status: http.StatusInternalServerError, Message: "internal server error"}
+	ErrPreconditionFailed   = &Error{Code: "precondition_failed", Status: http.StatusPreconditionFailed, Message: "precondition failed"}
+	ErrPreconditionRequired = &Error{Code: "precondition_required", Status: http.StatusPreconditionRequired, Message: "precondition required"}
+	ErrBatchAborted         = &Error{Code: "batch_aborted", Status: http.StatusFailedDependency, Message: "batch aborted"}
+	ErrInvalidToken         = &Error{Code: "invalid_token", Status: http.StatusBadRequest, Message: "invalid or expired token"}
+	ErrUnauthorized         = &Error{Code: "unauthorized", Status: http.StatusUnauthorized, Message: "authentication required"}
+	ErrInvalidCredentials   = &Error{Code: "invalid_credentials", Status: http.StatusUnauthorized, Message: "invalid credentials"}
+	ErrConflict             = &Error{Code: "conflict", Status: http.StatusConflict, Message: "conflict"}
+	ErrMethodNotAllowed     = &Error{Code: "method_not_allowed", Status: http.StatusMethodNotAllowed, Message: "method not allowed"}
+	ErrForbidden            = &Error{Code: "forbidden", Status: http.StatusForbidden, Message: "forbidden"}
+	ErrUnsupportedMedia     = &Error{Code: "unsupported_media_type", Status: http.StatusUnsupportedMediaType, Message: "unsupported media type"}
+	ErrPayloadTooLarge      = &Error{Code: "payload_too_large", Status: http.StatusRequestEntityTooLarge, Message: "request body too large"}
+	ErrTooManyRequests      = &Error{Code: "too_many_requests", Status: http.StatusTooManyRequests, Message: "too many requests"}
+	ErrBadGateway           = &Error{Code: "bad_gateway", Status: http.StatusBadGateway, Message: "upstream service unavailable"}
 )
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithMessage returns a copy of e with the message shown to clients.
+func (e *Error) WithMessage(message string) *Error {
+	c := *e
+	c.Message = message
+	return &c
+}
+
+// WithStatus returns a copy of e answered with another HTTP status.
+func (e *Error) WithStatus(status int) *Error {
+	c := *e
+	c.Status = status
+	return &c
+}
+
+// WithDetail returns a copy of e carrying an extra member in the problem
+// document, such as the offending field.
+func (e *Error) WithDetail(key string, value any) *Error {
+	c := *e
+	c.Details = make(map[string]any, len(e.Details)+1)
+	for k, v := range e.Details {
+		c.Details[k] = v
+	}
+	c.Details[key] = value
+	return &c
+}
+
+// Wrap returns a copy of e caused by err.
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+// BadRequest is ErrBadRequest with a formatted message.
+func BadRequest(format string, args ...any) *Error {
+	return ErrBadRequest.WithMessage(fmt.Sprintf(format, args...))
+}
+
+// From returns the application error in err's chain, or ErrInternalServer
+// wrapping err when there is none.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return ErrInternalServer.Wrap(err)
+}
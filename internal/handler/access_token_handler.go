@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"tasked/internal/domain"
+	apperrors "tasked/internal/errors"
+	"tasked/internal/middleware"
+	"tasked/internal/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultAccessTokenDays = 30
+
+type AccessTokenHandler struct {
+	service *services.AccessTokenService
+}
+
+func NewAccessTokenHandler(service *services.AccessTokenService) *AccessTokenHandler {
+	return &AccessTokenHandler{service: service}
+}
+
+// CreateToken godoc
+// @Summary Crear token de acceso personal
+// @Description Crea un token de larga duración para scripts y CI. El token solo se muestra en esta respuesta. Scopes: tasks:read, tasks:write, admin.
+// @Tags tokens
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body CreateAccessTokenRequest true "Nombre, scopes y duración"
+// @Success 201 {object} CreateAccessTokenResponse
+// @Failure 400 {object} Problem
+// @Failure 413 {object} Problem
+// @Failure 415 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 403 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /tokens [post]
+func (h *AccessTokenHandler) CreateToken(c *gin.Context) {
+	var req CreateAccessTokenRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.ExpiresInDays == 0 {
+		req.ExpiresInDays = defaultAccessTokenDays
+	}
+	lifetime := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+
+	token, pat, err := h.service.Create(c.Request.Context(), middleware.GetUserID(c), req.Name, req.Scopes, lifetime)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreateAccessTokenResponse{
+		Token:       token,
+		AccessToken: pat,
+	})
+}
+
+// ListTokens godoc
+// @Summary Listar tokens de acceso personal
+// @Description Retorna los tokens del usuario autenticado, sin el valor del token
+// @Tags tokens
+// @Security Bearer
+// @Produce json
+// @Success 200 {array} domain.PersonalAccessToken
+// @Failure 401 {object} Problem
+// @Failure 403 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /tokens [get]
+func (h *AccessTokenHandler) ListTokens(c *gin.Context) {
+	tokens, err := h.service.List(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// DeleteToken godoc
+// @Summary Revocar token de acceso personal
+// @Description Elimina un token del usuario autenticado; deja de aceptarse inmediatamente
+// @Tags tokens
+// @Security Bearer
+// @Produce json
+// @Param id path int true "Token ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 403 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /tokens/{id} [delete]
+func (h *AccessTokenHandler) DeleteToken(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		c.Error(apperrors.ErrBadRequest.WithMessage("invalid token id"))
+		return
+	}
+
+	err = h.service.Delete(c.Request.Context(), middleware.GetUserID(c), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "token deleted"})
+}
+
+type CreateAccessTokenRequest struct {
+	Name          string   `json:"name" binding:"required" example:"ci-deploy"`
+	Scopes        []string `json:"scopes" binding:"required" example:"tasks:read,tasks:write"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"min=0,max=366" example:"90"`
+}
+
+type CreateAccessTokenResponse struct {
+	Token       string                      `json:"token" example:"tsk_pat_3q2-7wE..."`
+	AccessToken *domain.PersonalAccessToken `json:"access_token"`
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"net/http"
+	"tasked/internal/middleware"
+	"tasked/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuthHandler struct {
+	accounts *services.AccountService
+}
+
+func NewAuthHandler(accounts *services.AccountService) *AuthHandler {
+	return &AuthHandler{accounts: accounts}
+}
+
+// ForgotPassword godoc
+// @Summary Solicitar restablecimiento de contraseña
+// @Description Envía un enlace de restablecimiento si el correo está registrado. El correo se envía en segundo plano, así que la respuesta y su tiempo son los mismos exista o no la cuenta.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body ForgotPasswordRequest true "Correo de la cuenta"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} Problem
+// @Failure 413 {object} Problem
+// @Failure 415 {object} Problem
+// @Failure 429 {object} Problem
+// @Router /auth/forgot-password [post]
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	h.accounts.ForgotPassword(c.Request.Context(), req.Email)
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the email is registered, a reset link has been sent"})
+}
+
+// ResetPassword godoc
+// @Summary Restablecer contraseña
+// @Description Cambia la contraseña usando el token de un solo uso recibido por correo y cierra las sesiones existentes
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body ResetPasswordRequest true "Token y nueva contraseña"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} Problem
+// @Failure 413 {object} Problem
+// @Failure 415 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /auth/reset-password [post]
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := h.accounts.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password updated"})
+}
+
+// VerifyEmail godoc
+// @Summary Verificar correo
+// @Description Confirma la dirección de correo usando el token recibido por correo
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body VerifyEmailRequest true "Token de verificación"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} Problem
+// @Failure 413 {object} Problem
+// @Failure 415 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /auth/verify-email [post]
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := h.accounts.VerifyEmail(c.Request.Context(), req.Token); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email verified"})
+}
+
+// ResendVerification godoc
+// @Summary Reenviar verificación de correo
+// @Description Envía un nuevo enlace de verificación al usuario autenticado
+// @Tags auth
+// @Security Bearer
+// @Produce json
+// @Success 202 {object} map[string]string
+// @Failure 401 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /auth/resend-verification [post]
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	err := h.accounts.ResendVerification(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "verification email sent"})
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email" example:"john@example.com"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required" example:"q6Xr0yJp..."`
+	Password string `json:"password" binding:"required" example:"N3w-passw0rd"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required" example:"q6Xr0yJp..."`
+}
@@ -0,0 +1,18 @@
+package handler
+
+import (
+	"tasked/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bindJSON decodes and validates the JSON body into req. When the body is
+// too large or invalid the error has been reported with c.Error and ok is
+// false.
+func bindJSON(c *gin.Context, req any) (ok bool) {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.Error(middleware.BodyError(err))
+		return false
+	}
+	return true
+}
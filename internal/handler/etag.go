@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	apperrors "tasked/internal/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatchVersion reads the version required by the If-Match header. It
+// returns 0 for "*", meaning any current version. Weak tags never match, as
+// If-Match uses strong comparison, and a list of several tags is narrowed to
+// the one naming the version that current reports. When the header is
+// missing or unusable the error has been reported with c.Error and ok is
+// false.
+func ifMatchVersion(c *gin.Context, current func(ctx context.Context) (int64, error)) (version int64, ok bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		c.Error(apperrors.ErrPreconditionRequired.WithMessage("If-Match header is required"))
+		return 0, false
+	}
+	if header == "*" {
+		return 0, true
+	}
+
+	var versions []int64
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		weak := strings.HasPrefix(tag, "W/")
+		opaque, valid := entityTag(strings.TrimPrefix(tag, "W/"))
+		if !valid {
+			c.Error(apperrors.ErrBadRequest.WithMessage("invalid If-Match header"))
+			return 0, false
+		}
+		// Tags we did not issue are valid but can never match.
+		v, err := strconv.ParseInt(opaque, 10, 64)
+		if weak || err != nil || v <= 0 || slices.Contains(versions, v) {
+			continue
+		}
+		versions = append(versions, v)
+	}
+
+	switch len(versions) {
+	case 0:
+		c.Error(apperrors.ErrPreconditionFailed.WithMessage("resource has been modified"))
+		return 0, false
+	case 1:
+		return versions[0], true
+	}
+	v, err := current(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return 0, false
+	}
+	if !slices.Contains(versions, v) {
+		c.Error(apperrors.ErrPreconditionFailed.WithMessage("resource has been modified"))
+		return 0, false
+	}
+	return v, true
+}
+
+// entityTag returns the opaque part of a strong entity tag such as "12".
+func entityTag(tag string) (string, bool) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return "", false
+	}
+	opaque := tag[1 : len(tag)-1]
+	for i := 0; i < len(opaque); i++ {
+		// etagc = %x21 / %x23-7E / obs-text
+		if ch := opaque[i]; ch == '"' || ch < 0x21 || ch == 0x7f {
+			return "", false
+		}
+	}
+	return opaque, true
+}
+
+// notModified answers with 304 when If-None-Match matches the current
+// version, using the weak comparison RFC 9110 requires for this header.
+func notModified(c *gin.Context, version int64) bool {
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	current := etag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			c.Header("ETag", current)
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"tasked/internal/health"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const readinessTimeout = 2 * time.Second
+
+type HealthHandler struct {
+	checks   []health.Check
+	draining atomic.Bool
+}
+
+func NewHealthHandler(checks ...health.Check) *HealthHandler {
+	return &HealthHandler{checks: checks}
+}
+
+// Drain makes the readiness probe fail so the instance stops receiving new
+// traffic while in-flight requests finish.
+func (h *HealthHandler) Drain() {
+	h.draining.Store(true)
+}
+
+type ReadinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Liveness godoc
+// @Summary Liveness
+// @Description Responde mientras el proceso esté en marcha, sin consultar dependencias
+// @Tags health
+// @Produce json
+// @Success 200 {object} ReadinessResponse
+// @Router /healthz [get]
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, ReadinessResponse{Status: "ok"})
+}
+
+// Readiness godoc
+// @Summary Readiness
+// @Description Comprueba la conexión a la base de datos y que las migraciones estén aplicadas. Responde 503 si alguna comprobación falla o si el servidor se está apagando.
+// @Tags health
+// @Produce json
+// @Success 200 {object} ReadinessResponse
+// @Failure 503 {object} ReadinessResponse
+// @Router /readyz [get]
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	if h.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, ReadinessResponse{Status: "shutting down"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	response := ReadinessResponse{Status: "ok", Checks: make(map[string]string, len(h.checks))}
+	for _, check := range h.checks {
+		if err := check.Check(ctx); err != nil {
+			c.Error(err)
+			response.Status = "unavailable"
+			response.Checks[check.Name] = "failing"
+			continue
+		}
+		response.Checks[check.Name] = "ok"
+	}
+
+	if response.Status != "ok" {
+		c.JSON(http.StatusServiceUnavailable, response)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
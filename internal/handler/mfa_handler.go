@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"tasked/internal/auth"
+	apperrors "tasked/internal/errors"
+	"tasked/internal/metrics"
+	"tasked/internal/middleware"
+	"tasked/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFAHandler struct {
+	service      *services.MFAService
+	sessions     *services.SessionService
+	tokenManager *auth.TokenManager
+}
+
+func NewMFAHandler(service *services.MFAService, sessions *services.SessionService, tokenManager *auth.TokenManager) *MFAHandler {
+	return &MFAHandler{
+		service:      service,
+		sessions:     sessions,
+		tokenManager: tokenManager,
+	}
+}
+
+// Enroll godoc
+// @Summary Iniciar la activación de 2FA
+// @Description Genera un secreto TOTP y la URI otpauth:// para mostrar como código QR. El 2FA no se activa hasta confirmar un código en /auth/mfa/verify.
+// @Tags mfa
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} MFAEnrollResponse
+// @Failure 401 {object} Problem
+// @Failure 409 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /auth/mfa/enroll [post]
+func (h *MFAHandler) Enroll(c *gin.Context) {
+	secret, uri, err := h.service.Enroll(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, MFAEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: uri,
+	})
+}
+
+// VerifyEnrollment godoc
+// @Summary Confirmar la activación de 2FA
+// @Description Activa el 2FA con un código del autenticador y retorna los códigos de recuperación, que solo se muestran esta vez
+// @Tags mfa
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body MFACodeRequest true "Código TOTP"
+// @Success 200 {object} MFARecoveryCodesResponse
+// @Failure 400 {object} Problem
+// @Failure 413 {object} Problem
+// @Failure 415 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 409 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /auth/mfa/verify [post]
+func (h *MFAHandler) VerifyEnrollment(c *gin.Context) {
+	var req MFACodeRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	codes, err := h.service.Activate(c.Request.Context(), middleware.GetUserID(c), req.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, MFARecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Disable godoc
+// @Summary Desactivar 2FA
+// @Description Desactiva el 2FA. Requiere la contraseña y un código TOTP o de recuperación.
+// @Tags mfa
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body MFADisableRequest true "Contraseña y código"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} Problem
+// @Failure 413 {object} Problem
+// @Failure 415 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /auth/mfa/disable [post]
+func (h *MFAHandler) Disable(c *gin.Context) {
+	var req MFADisableRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	err := h.service.Disable(c.Request.Context(), middleware.GetUserID(c), req.Password, req.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication disabled"})
+}
+
+// Challenge godoc
+// @Summary Completar el login con 2FA
+// @Description Canjea el mfa_token devuelto por /login y un código TOTP o de recuperación por un JWT token. Cada mfa_token sirve una sola vez y los códigos fallidos cuentan para el bloqueo de la cuenta.
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param request body MFAChallengeRequest true "Token de desafío y código"
+// @Success 200 {object} LoginResponse
+// @Failure 400 {object} Problem
+// @Failure 413 {object} Problem
+// @Failure 415 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 429 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /auth/mfa [post]
+func (h *MFAHandler) Challenge(c *gin.Context) {
+	var req MFAChallengeRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	claims, err := h.tokenManager.ValidateMFAChallenge(req.MFAToken)
+	if err != nil {
+		c.Error(apperrors.ErrUnauthorized.WithMessage("invalid or expired mfa token"))
+		return
+	}
+
+	if err := h.service.CompleteChallenge(c.Request.Context(), claims.UserID, claims.ID, req.Code); err != nil {
+		if errors.Is(err, apperrors.ErrInvalidCredentials) {
+			metrics.LoginAttempts.WithLabelValues(metrics.LoginMFA, metrics.LoginFailure).Inc()
+		}
+		c.Error(err)
+		return
+	}
+
+	token, err := startSession(c, h.tokenManager, h.sessions, claims.UserID, claims.Email, claims.Username)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	metrics.LoginAttempts.WithLabelValues(metrics.LoginMFA, metrics.LoginSuccess).Inc()
+	c.JSON(http.StatusOK, LoginResponse{
+		Token: token,
+		User: LoginUser{
+			ID:       claims.UserID,
+			Username: claims.Username,
+			Email:    claims.Email,
+		},
+	})
+}
+
+type MFAEnrollResponse struct {
+	Secret          string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	ProvisioningURI string `json:"provisioning_uri" example:"otpauth://totp/Tasked:john@example.com?secret=JBSWY3DPEHPK3PXP&issuer=Tasked"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required" example:"123456"`
+}
+
+type MFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes" example:"k3f9x-2mq7p"`
+}
+
+type MFADisableRequest struct {
+	Password string `json:"password" binding:"required" example:"S3cure-passw0rd"`
+	Code     string `json:"code" binding:"required" example:"123456"`
+}
+
+type MFAChallengeRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required" example:"eyJhbGciOiJIUzI1NiIs..."`
+	Code     string `json:"code" binding:"required" example:"123456"`
+}
+
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required" example:"true"`
+	MFAToken    string `json:"mfa_token" example:"eyJhbGciOiJIUzI1NiIs..."`
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"tasked/internal/auth"
+	apperrors "tasked/internal/errors"
+	"tasked/internal/metrics"
+	"tasked/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	oidcStateCookie     = "oidc_state"
+	oidcStateCookiePath = "/auth/oidc"
+	oidcStateCookieAge  = 600
+)
+
+type OIDCHandler struct {
+	service      *services.OIDCService
+	sessions     *services.SessionService
+	mfa          *services.MFAService
+	tokenManager *auth.TokenManager
+}
+
+func NewOIDCHandler(service *services.OIDCService, sessions *services.SessionService, mfa *services.MFAService, tokenManager *auth.TokenManager) *OIDCHandler {
+	return &OIDCHandler{
+		service:      service,
+		sessions:     sessions,
+		mfa:          mfa,
+		tokenManager: tokenManager,
+	}
+}
+
+// Login godoc
+// @Summary Iniciar sesión con el proveedor OIDC
+// @Description Redirige al proveedor de identidad usando el flujo authorization code con PKCE
+// @Tags auth
+// @Success 302
+// @Failure 502 {object} Problem
+// @Router /auth/oidc/login [get]
+func (h *OIDCHandler) Login(c *gin.Context) {
+	authURL, state, err := h.service.Begin(c.Request.Context())
+	if err != nil {
+		c.Error(apperrors.ErrBadGateway.WithMessage("identity provider unavailable").Wrap(err))
+		return
+	}
+
+	// Binds the login to this browser so a callback started elsewhere is
+	// rejected.
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, oidcStateCookieAge, oidcStateCookiePath, "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback godoc
+// @Summary Completar el inicio de sesión OIDC
+// @Description Canjea el código del proveedor y retorna un JWT token, o un MFAChallengeResponse si el usuario tiene 2FA activo. La cuenta existente con el mismo correo se vincula solo si ya lo verificó (si no, 409); si no existe se crea.
+// @Tags auth
+// @Produce json
+// @Param code query string true "Código de autorización"
+// @Param state query string true "State de la solicitud"
+// @Success 200 {object} LoginResponse
+// @Failure 400 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 403 {object} Problem
+// @Failure 409 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /auth/oidc/callback [get]
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	if providerErr := c.Query("error"); providerErr != "" {
+		c.Error(apperrors.ErrBadRequest.WithMessage("identity provider error: " + providerErr))
+		return
+	}
+
+	state := c.Query("state")
+	code := c.Query("code")
+	if state == "" || code == "" {
+		c.Error(apperrors.ErrBadRequest.WithMessage("missing code or state"))
+		return
+	}
+
+	cookie, err := c.Cookie(oidcStateCookie)
+	c.SetCookie(oidcStateCookie, "", -1, oidcStateCookiePath, "", c.Request.TLS != nil, true)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie), []byte(state)) != 1 {
+		c.Error(apperrors.ErrBadRequest.WithMessage("invalid or expired state"))
+		return
+	}
+
+	user, err := h.service.Complete(c.Request.Context(), state, code)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrInvalidCredentials) || errors.Is(err, apperrors.ErrForbidden) {
+			metrics.LoginAttempts.WithLabelValues(metrics.LoginOIDC, metrics.LoginFailure).Inc()
+		}
+		c.Error(err)
+		return
+	}
+
+	respondWithLogin(c, h.tokenManager, h.sessions, h.mfa, user, metrics.LoginOIDC)
+}
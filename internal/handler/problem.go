@@ -0,0 +1,14 @@
+package handler
+
+// Problem documents the RFC 7807 body middleware.Errors answers failures
+// with. Code repeats apperrors.Error.Code and its Details are added as
+// extension members.
+type Problem struct {
+	Type      string `json:"type" example:"about:blank"`
+	Title     string `json:"title" example:"Not Found"`
+	Status    int    `json:"status" example:"404"`
+	Detail    string `json:"detail" example:"task not found"`
+	Instance  string `json:"instance,omitempty" example:"/tasks/42"`
+	Code      string `json:"code" example:"not_found"`
+	RequestID string `json:"request_id,omitempty" example:"4f1c2b7e9a0d4c3e"`
+}
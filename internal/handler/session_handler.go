@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"net/http"
+	"tasked/internal/auth"
+	"tasked/internal/domain"
+	"tasked/internal/middleware"
+	"tasked/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionHandler struct {
+	service *services.SessionService
+}
+
+func NewSessionHandler(service *services.SessionService) *SessionHandler {
+	return &SessionHandler{service: service}
+}
+
+// ListSessions godoc
+// @Summary Listar sesiones activas
+// @Description Retorna las sesiones activas del usuario autenticado con su dispositivo, IP y última actividad. La sesión de la petición se marca con current.
+// @Tags sessions
+// @Security Bearer
+// @Produce json
+// @Success 200 {array} SessionResponse
+// @Failure 401 {object} Problem
+// @Failure 403 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /sessions [get]
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	sessions, err := h.service.List(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	current := middleware.GetSessionID(c)
+	response := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, SessionResponse{
+			Session: session,
+			Current: session.ID == current,
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeSession godoc
+// @Summary Cerrar una sesión
+// @Description Revoca una sesión del usuario autenticado; sus tokens dejan de aceptarse
+// @Tags sessions
+// @Security Bearer
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} Problem
+// @Failure 403 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /sessions/{id} [delete]
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	err := h.service.Revoke(c.Request.Context(), middleware.GetUserID(c), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "session revoked"})
+}
+
+// RevokeOtherSessions godoc
+// @Summary Cerrar las demás sesiones
+// @Description Revoca todas las sesiones del usuario autenticado excepto la de la petición
+// @Tags sessions
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} RevokeSessionsResponse
+// @Failure 401 {object} Problem
+// @Failure 403 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /sessions [delete]
+func (h *SessionHandler) RevokeOtherSessions(c *gin.Context) {
+	revoked, err := h.service.RevokeOthers(c.Request.Context(), middleware.GetUserID(c), middleware.GetSessionID(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, RevokeSessionsResponse{Revoked: revoked})
+}
+
+// startSession records a sign-in from the requesting device and returns the
+// access token for it.
+func startSession(c *gin.Context, tokenManager *auth.TokenManager, sessions *services.SessionService, userID int64, email, username string) (string, error) {
+	session, err := sessions.Create(c.Request.Context(), userID, c.Request.UserAgent(), c.ClientIP(), tokenManager.TTL())
+	if err != nil {
+		return "", err
+	}
+	return tokenManager.GenerateToken(userID, email, username, session.ID)
+}
+
+type SessionResponse struct {
+	*domain.Session
+	Current bool `json:"current"`
+}
+
+type RevokeSessionsResponse struct {
+	Revoked int `json:"revoked" example:"3"`
+}
@@ -1,9 +1,18 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"tasked/internal/domain"
+	apperrors "tasked/internal/errors"
+	"tasked/internal/middleware"
 	"tasked/internal/services"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -23,25 +32,32 @@ func NewTaskHandler(service *services.TaskService) *TaskHandler {
 // @Security Bearer
 // @Produce json
 // @Param id path int true "Task ID"
+// @Param If-None-Match header string false "ETag conocido por el cliente"
 // @Success 200 {object} domain.Task
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 404 {object} map[string]string
+// @Success 304
+// @Failure 400 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 404 {object} Problem
 // @Router /tasks/{id} [get]
 func (h *TaskHandler) GetTask(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task id"})
+		c.Error(apperrors.ErrBadRequest.WithMessage("invalid task id"))
 		return
 	}
 
 	task, err := h.service.GetTaskById(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		c.Error(err)
 		return
 	}
 
+	if notModified(c, task.Version) {
+		return
+	}
+
+	c.Header("ETag", etag(task.Version))
 	c.JSON(http.StatusOK, task)
 }
 
@@ -53,21 +69,21 @@ func (h *TaskHandler) GetTask(c *gin.Context) {
 // @Produce json
 // @Param user_id path int true "User ID"
 // @Success 200 {array} domain.Task
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /users/{user_id}/tasks [get]
 func (h *TaskHandler) ListTasksByUser(c *gin.Context) {
 	userIdParam := c.Param("id")
 	userId, err := strconv.ParseInt(userIdParam, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		c.Error(apperrors.ErrBadRequest.WithMessage("invalid user id"))
 		return
 	}
 
 	tasks, err := h.service.ListTaskByUser(c.Request.Context(), userId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list tasks"})
+		c.Error(err)
 		return
 	}
 
@@ -82,56 +98,193 @@ func (h *TaskHandler) ListTasksByUser(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Task ID"
+// @Param If-Match header string true "ETag de la versión a modificar, o *"
 // @Param task body UpdateTaskRequest true "Datos a actualizar"
 // @Success 200 {object} domain.Task
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} Problem
+// @Failure 413 {object} Problem
+// @Failure 415 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 412 {object} Problem
+// @Failure 428 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /tasks/{id} [put]
 func (h *TaskHandler) UpdateTask(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task id"})
+		c.Error(apperrors.ErrBadRequest.WithMessage("invalid task id"))
+		return
+	}
+
+	version, ok := ifMatchVersion(c, h.currentVersion(id))
+	if !ok {
 		return
 	}
 
 	var req UpdateTaskRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	task, err := h.service.UpdateTask(c.Request.Context(), id, version, req.Title, req.Description, req.Status, req.Priority, req.DueDate)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", etag(task.Version))
+	c.JSON(http.StatusOK, task)
+}
+
+// PatchTask godoc
+// @Summary Actualizar parcialmente una tarea
+// @Description Aplica un JSON Merge Patch (RFC 7396): los campos ausentes no cambian y null limpia el valor
+// @Tags tasks
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param id path int true "Task ID"
+// @Param If-Match header string true "ETag de la versión a modificar, o *"
+// @Param task body PatchTaskRequest true "Campos a modificar"
+// @Success 200 {object} domain.Task
+// @Failure 400 {object} Problem
+// @Failure 413 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 415 {object} Problem
+// @Failure 412 {object} Problem
+// @Failure 428 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /tasks/{id} [patch]
+func (h *TaskHandler) PatchTask(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		c.Error(apperrors.ErrBadRequest.WithMessage("invalid task id"))
+		return
+	}
+
+	version, ok := ifMatchVersion(c, h.currentVersion(id))
+	if !ok {
+		return
+	}
+
+	contentType := c.ContentType()
+	if contentType != "application/merge-patch+json" && contentType != "application/json" {
+		c.Error(apperrors.ErrUnsupportedMedia.WithMessage("content type must be application/merge-patch+json"))
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		c.Error(middleware.BodyError(err))
+		return
+	}
+
+	patch, err := decodeTaskPatch(body)
+	if err != nil {
+		c.Error(apperrors.ErrBadRequest.WithMessage(err.Error()))
 		return
 	}
 
-	task, err := h.service.UpdateTask(c.Request.Context(), id, req.Title, req.Description, req.Status, req.Priority, req.DueDate)
+	task, err := h.service.PatchTask(c.Request.Context(), id, version, patch)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update task"})
+		c.Error(err)
 		return
 	}
 
+	c.Header("ETag", etag(task.Version))
 	c.JSON(http.StatusOK, task)
 }
 
+// decodeTaskPatch translates a merge patch document into a domain.TaskPatch,
+// keeping the distinction between absent members and explicit nulls.
+func decodeTaskPatch(body []byte) (domain.TaskPatch, error) {
+	var patch domain.TaskPatch
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return patch, errors.New("body must be a JSON object")
+	}
+
+	for key, raw := range doc {
+		isNull := strings.TrimSpace(string(raw)) == "null"
+
+		var value *string
+		if !isNull {
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return patch, fmt.Errorf("%s must be a string", key)
+			}
+			value = &s
+		}
+
+		switch key {
+		case "title":
+			if value == nil || *value == "" {
+				return patch, errors.New("title cannot be empty")
+			}
+			patch.Title = value
+		case "status":
+			if value == nil || *value == "" {
+				return patch, errors.New("status cannot be empty")
+			}
+			patch.Status = value
+		case "description":
+			patch.SetDescription = true
+			patch.Description = value
+		case "priority":
+			patch.SetPriority = true
+			patch.Priority = value
+		case "due_date":
+			if value != nil {
+				if _, err := time.Parse("2006-01-02", *value); err != nil {
+					return patch, errors.New("due_date must use the YYYY-MM-DD format")
+				}
+			}
+			patch.SetDueDate = true
+			patch.DueDate = value
+		default:
+			return patch, fmt.Errorf("unknown field %q", key)
+		}
+	}
+
+	return patch, nil
+}
+
 // DeleteTask godoc
 // @Summary Eliminar tarea
 // @Description Elimina una tarea del sistema
 // @Tags tasks
 // @Security Bearer
 // @Param id path int true "Task ID"
+// @Param If-Match header string true "ETag de la versión a modificar, o *"
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 412 {object} Problem
+// @Failure 428 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /tasks/{id} [delete]
 func (h *TaskHandler) DeleteTask(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task id"})
+		c.Error(apperrors.ErrBadRequest.WithMessage("invalid task id"))
 		return
 	}
 
-	if err := h.service.DeleteTask(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete task"})
+	version, ok := ifMatchVersion(c, h.currentVersion(id))
+	if !ok {
+		return
+	}
+
+	err = h.service.DeleteTask(c.Request.Context(), id, version)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -146,32 +299,43 @@ func (h *TaskHandler) DeleteTask(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Task ID"
+// @Param If-Match header string true "ETag de la versión a modificar, o *"
 // @Param status body UpdateStatusRequest true "Nuevo estado"
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} Problem
+// @Failure 413 {object} Problem
+// @Failure 415 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 412 {object} Problem
+// @Failure 428 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /tasks/{id}/status [patch]
 func (h *TaskHandler) UpdateStatus(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task id"})
+		c.Error(apperrors.ErrBadRequest.WithMessage("invalid task id"))
+		return
+	}
+
+	version, ok := ifMatchVersion(c, h.currentVersion(id))
+	if !ok {
 		return
 	}
 
 	var req UpdateStatusRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	task, err := h.service.UpdateStatus(c.Request.Context(), id, req.Status)
+	task, err := h.service.UpdateStatus(c.Request.Context(), id, version, req.Status)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update status"})
+		c.Error(err)
 		return
 	}
 
+	c.Header("ETag", etag(task.Version))
 	c.JSON(http.StatusOK, task)
 }
 
@@ -184,26 +348,144 @@ func (h *TaskHandler) UpdateStatus(c *gin.Context) {
 // @Produce json
 // @Param task body CreateTaskRequest true "Datos de la tarea"
 // @Success 201 {object} domain.Task
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} Problem
+// @Failure 413 {object} Problem
+// @Failure 415 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /tasks [post]
 func (h *TaskHandler) CreateTask(c *gin.Context) {
 	var req CreateTaskRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	task, err := h.service.CreateTask(c.Request.Context(), req.Title, req.Description, req.Status, req.Priority, req.UserID, req.DueDate)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create task"})
+		c.Error(err)
 		return
 	}
 
+	c.Header("ETag", etag(task.Version))
 	c.JSON(http.StatusCreated, task)
 }
 
+// BatchTasks godoc
+// @Summary Operaciones de tareas en lote
+// @Description Ejecuta hasta 100 operaciones (create, update, status, delete) en una sola transacción. En modo atomic cualquier fallo revierte todo; en modo best_effort cada operación se aplica de forma independiente.
+// @Tags tasks
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param batch body BatchTasksRequest true "Operaciones a ejecutar"
+// @Success 200 {object} BatchTasksResponse
+// @Failure 400 {object} Problem
+// @Failure 413 {object} Problem
+// @Failure 415 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 404 {object} BatchTasksResponse
+// @Failure 412 {object} BatchTasksResponse
+// @Failure 500 {object} Problem
+// @Router /tasks/batch [post]
+func (h *TaskHandler) BatchTasks(c *gin.Context) {
+	var req BatchTasksRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	ops := make([]domain.TaskOperation, 0, len(req.Operations))
+	for _, op := range req.Operations {
+		ops = append(ops, domain.TaskOperation{
+			Op:          op.Op,
+			ID:          op.ID,
+			Version:     op.Version,
+			Title:       op.Title,
+			Description: op.Description,
+			Status:      op.Status,
+			Priority:    op.Priority,
+			UserID:      op.UserID,
+			DueDate:     op.DueDate,
+		})
+	}
+
+	atomic := req.Mode != "best_effort"
+	results, err := h.service.RunBatch(c.Request.Context(), ops, atomic)
+	if err != nil && results == nil {
+		c.Error(err)
+		return
+	}
+
+	resp := BatchTasksResponse{
+		Committed: err == nil,
+		Results:   make([]BatchOperationResult, 0, len(results)),
+	}
+	for _, r := range results {
+		status, message := batchResultStatus(r)
+		if status == http.StatusInternalServerError {
+			c.Error(r.Err)
+		}
+		resp.Results = append(resp.Results, BatchOperationResult{
+			Index:  r.Index,
+			Op:     r.Op,
+			Status: status,
+			Task:   r.Task,
+			Error:  message,
+		})
+	}
+
+	if err != nil {
+		c.JSON(apperrors.From(err).Status, resp)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// batchResultStatus reports the status and message of a single operation,
+// hiding the cause of unexpected failures like the problem responses do.
+func batchResultStatus(r domain.TaskOperationResult) (int, string) {
+	switch {
+	case r.Err == nil && r.Op == "create":
+		return http.StatusCreated, ""
+	case r.Err == nil:
+		return http.StatusOK, ""
+	}
+	appErr := apperrors.From(r.Err)
+	if appErr.Status >= http.StatusInternalServerError {
+		return appErr.Status, "operation failed"
+	}
+	return appErr.Status, appErr.Message
+}
+
+type BatchTasksRequest struct {
+	Mode       string                  `json:"mode" binding:"omitempty,oneof=atomic best_effort" example:"atomic"`
+	Operations []BatchOperationRequest `json:"operations" binding:"required,min=1,max=100,dive"`
+}
+
+type BatchOperationRequest struct {
+	Op          string `json:"op" binding:"required,oneof=create update status delete" example:"status"`
+	ID          int64  `json:"id" example:"1"`
+	Version     int64  `json:"version" example:"3"`
+	Title       string `json:"title" example:"Completar informe"`
+	Description string `json:"description" example:"Terminar el informe mensual"`
+	Status      string `json:"status" example:"completed"`
+	Priority    string `json:"priority" example:"high"`
+	UserID      int64  `json:"user_id" example:"1"`
+	DueDate     string `json:"due_date" example:"2024-12-31"`
+}
+
+type BatchTasksResponse struct {
+	Committed bool                   `json:"committed"`
+	Results   []BatchOperationResult `json:"results"`
+}
+
+type BatchOperationResult struct {
+	Index  int          `json:"index" example:"0"`
+	Op     string       `json:"op" example:"status"`
+	Status int          `json:"status" example:"200"`
+	Task   *domain.Task `json:"task,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
 type UpdateTaskRequest struct {
 	Title       string `json:"title" binding:"required" example:"Completar informe"`
 	Description string `json:"description" example:"Terminar el informe mensual"`
@@ -212,6 +494,14 @@ type UpdateTaskRequest struct {
 	DueDate     string `json:"due_date" example:"2024-12-31"`
 }
 
+type PatchTaskRequest struct {
+	Title       *string `json:"title,omitempty" example:"Completar informe"`
+	Description *string `json:"description,omitempty" example:"Terminar el informe mensual"`
+	Status      *string `json:"status,omitempty" example:"in_progress"`
+	Priority    *string `json:"priority,omitempty" example:"low"`
+	DueDate     *string `json:"due_date,omitempty" example:"2024-12-31"`
+}
+
 type UpdateStatusRequest struct {
 	Status string `json:"status" binding:"required" example:"completed"`
 }
@@ -223,3 +513,14 @@ type CreateTaskRequest struct {
 	UserID      int64  `json:"user_id" binding:"required" example:"1"`
 	DueDate     string `json:"due_date" example:"2024-12-31"`
 }
+
+// currentVersion looks up the version an If-Match list is matched against.
+func (h *TaskHandler) currentVersion(id int64) func(ctx context.Context) (int64, error) {
+	return func(ctx context.Context) (int64, error) {
+		task, err := h.service.GetTaskById(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+		return task.Version, nil
+	}
+}
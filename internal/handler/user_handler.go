@@ -1,9 +1,16 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"tasked/internal/auth"
+	"tasked/internal/domain"
+	apperrors "tasked/internal/errors"
+	"tasked/internal/metrics"
+	"tasked/internal/middleware"
 	"tasked/internal/services"
 	"tasked/internal/utils"
 
@@ -12,12 +19,18 @@ import (
 
 type UserHandler struct {
 	service      *services.UserService
+	accounts     *services.AccountService
+	sessions     *services.SessionService
+	mfa          *services.MFAService
 	tokenManager *auth.TokenManager
 }
 
-func NewUserHandler(service *services.UserService, tokenManager *auth.TokenManager) *UserHandler {
+func NewUserHandler(service *services.UserService, accounts *services.AccountService, sessions *services.SessionService, mfa *services.MFAService, tokenManager *auth.TokenManager) *UserHandler {
 	return &UserHandler{
 		service:      service,
+		accounts:     accounts,
+		sessions:     sessions,
+		mfa:          mfa,
 		tokenManager: tokenManager,
 	}
 }
@@ -29,90 +42,116 @@ func NewUserHandler(service *services.UserService, tokenManager *auth.TokenManag
 // @Accept json
 // @Produce json
 // @Param user body CreateUserRequest true "Datos del usuario"
-// @Success 201 {object} domain.User
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Success 201 {object} UserResponse
+// @Failure 400 {object} Problem
+// @Failure 413 {object} Problem
+// @Failure 415 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /users [post]
 func (h *UserHandler) CreateUser(c *gin.Context) {
 	var req CreateUserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	user, err := h.service.CreateUser(c.Request.Context(), req.Username, req.Email, req.Password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, user)
+	// The account is usable without the email; the user can ask for a new
+	// link later, so a delivery failure must not fail the signup.
+	if err := h.accounts.SendVerification(c.Request.Context(), user); err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to send verification email", "user_id", user.ID, "error", err)
+	}
+
+	c.Header("ETag", etag(user.Version))
+	c.JSON(http.StatusCreated, newUserResponse(user))
 }
 
 // GetUser godoc
 // @Summary Obtener usuario por ID
-// @Description Retorna un usuario específico por su ID
+// @Description Retorna un usuario específico por su ID. Solo el propio usuario recibe UserResponse; los demás reciben PublicUserResponse
 // @Tags users
 // @Security Bearer
 // @Produce json
 // @Param id path int true "User ID"
-// @Success 200 {object} domain.User
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 404 {object} map[string]string
+// @Param If-None-Match header string false "ETag conocido por el cliente"
+// @Success 200 {object} UserResponse
+// @Success 304
+// @Failure 400 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 404 {object} Problem
 // @Router /users/{id} [get]
 func (h *UserHandler) GetUser(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		c.Error(apperrors.ErrBadRequest.WithMessage("invalid user id"))
 		return
 	}
 
 	user, err := h.service.GetUser(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		c.Error(err)
+		return
+	}
+
+	if notModified(c, user.Version) {
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	c.Header("ETag", etag(user.Version))
+	c.JSON(http.StatusOK, userResponseFor(c, user))
 }
 
 // UpdateUser godoc
 // @Summary Actualizar usuario
-// @Description Actualiza los datos de un usuario existente
+// @Description Actualiza los datos de un usuario existente. Solo el propio usuario recibe UserResponse; los demás reciben PublicUserResponse
 // @Tags users
 // @Security Bearer
 // @Accept json
 // @Produce json
 // @Param id path int true "User ID"
+// @Param If-Match header string true "ETag de la versión a modificar, o *"
 // @Param user body UpdateUserRequest true "Datos a actualizar"
-// @Success 200 {object} domain.User
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Success 200 {object} UserResponse
+// @Failure 400 {object} Problem
+// @Failure 413 {object} Problem
+// @Failure 415 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 412 {object} Problem
+// @Failure 428 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /users/{id} [put]
 func (h *UserHandler) UpdateUser(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		c.Error(apperrors.ErrBadRequest.WithMessage("invalid user id"))
+		return
+	}
+
+	version, ok := ifMatchVersion(c, h.currentVersion(id))
+	if !ok {
 		return
 	}
 
 	var req UpdateUserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	user, err := h.service.UpdateUser(c.Request.Context(), id, req.Username, req.Email)
+	user, err := h.service.UpdateUser(c.Request.Context(), id, version, req.Username, req.Email)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update user"})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	c.Header("ETag", etag(user.Version))
+	c.JSON(http.StatusOK, userResponseFor(c, user))
 }
 
 // DeleteUser godoc
@@ -121,63 +160,175 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 // @Tags users
 // @Security Bearer
 // @Param id path int true "User ID"
+// @Param If-Match header string true "ETag de la versión a modificar, o *"
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 412 {object} Problem
+// @Failure 428 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /users/{id} [delete]
 func (h *UserHandler) DeleteUser(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		c.Error(apperrors.ErrBadRequest.WithMessage("invalid user id"))
 		return
 	}
 
-	if err := h.service.DeleteUser(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete user"})
+	version, ok := ifMatchVersion(c, h.currentVersion(id))
+	if !ok {
+		return
+	}
+
+	err = h.service.DeleteUser(c.Request.Context(), id, version)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "user deleted"})
 }
 
+// ChangePassword godoc
+// @Summary Cambiar contraseña
+// @Description Cambia la contraseña del usuario autenticado, cierra sus demás sesiones y retorna un nuevo JWT token
+// @Tags users
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param passwords body ChangePasswordRequest true "Contraseña actual y nueva"
+// @Success 200 {object} ChangePasswordResponse
+// @Failure 400 {object} Problem
+// @Failure 413 {object} Problem
+// @Failure 415 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 403 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 429 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /users/{id}/password [put]
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		c.Error(apperrors.ErrBadRequest.WithMessage("invalid user id"))
+		return
+	}
+
+	if id != middleware.GetUserID(c) {
+		c.Error(apperrors.ErrForbidden.WithMessage("cannot change another user's password"))
+		return
+	}
+
+	var req ChangePasswordRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	err = h.service.ChangePassword(c.Request.Context(), id, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		// The caller is authenticated; a wrong current password is a
+		// refusal, not a reason to log in again.
+		if errors.Is(err, apperrors.ErrInvalidCredentials) {
+			err = apperrors.From(err).WithStatus(http.StatusForbidden)
+		}
+		c.Error(err)
+		return
+	}
+
+	if _, err := h.sessions.RevokeOthers(c.Request.Context(), id, ""); err != nil {
+		c.Error(err)
+		return
+	}
+
+	token, err := startSession(c, h.tokenManager, h.sessions, id, middleware.GetUserEmail(c), middleware.GetUsername(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ChangePasswordResponse{
+		Message: "password updated",
+		Token:   token,
+	})
+}
+
 // Login godoc
 // @Summary Autenticar usuario
-// @Description Autentica un usuario y retorna un JWT token
+// @Description Autentica un usuario y retorna un JWT token. Si el usuario tiene 2FA activo retorna en su lugar un MFAChallengeResponse que se canjea en /auth/mfa.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param credentials body LoginRequest true "Email y password"
 // @Success 200 {object} LoginResponse
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} Problem
+// @Failure 413 {object} Problem
+// @Failure 415 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 403 {object} Problem
+// @Failure 429 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /login [post]
 func (h *UserHandler) Login(c *gin.Context) {
 	var req LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	user, err := h.service.GetUserByEmail(c.Request.Context(), req.Email)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		metrics.LoginAttempts.WithLabelValues(metrics.LoginPassword, metrics.LoginFailure).Inc()
+		c.Error(apperrors.ErrInvalidCredentials)
 		return
 	}
 
 	if !utils.VerifyPassword(user.Password, req.Password) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		metrics.LoginAttempts.WithLabelValues(metrics.LoginPassword, metrics.LoginFailure).Inc()
+		c.Error(apperrors.ErrInvalidCredentials)
 		return
 	}
 
-	token, err := h.tokenManager.GenerateToken(user.ID, user.Email, user.Username)
+	if !h.accounts.LoginAllowed(user) {
+		metrics.LoginAttempts.WithLabelValues(metrics.LoginPassword, metrics.LoginUnverified).Inc()
+		c.Error(apperrors.ErrForbidden.WithMessage("email not verified"))
+		return
+	}
+
+	respondWithLogin(c, h.tokenManager, h.sessions, h.mfa, user, metrics.LoginPassword)
+}
+
+// respondWithLogin finishes a successful first factor: users with two-factor
+// authentication get a challenge token, everyone else an access token.
+func respondWithLogin(c *gin.Context, tokenManager *auth.TokenManager, sessions *services.SessionService, mfa *services.MFAService, user *domain.User, method string) {
+	if user.MFAEnabled {
+		challengeID, err := mfa.StartChallenge(c.Request.Context(), user.ID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		challenge, err := tokenManager.GenerateMFAChallenge(user.ID, user.Email, user.Username, challengeID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		metrics.LoginAttempts.WithLabelValues(method, metrics.LoginMFARequired).Inc()
+		c.JSON(http.StatusOK, MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    challenge,
+		})
+		return
+	}
+
+	token, err := startSession(c, tokenManager, sessions, user.ID, user.Email, user.Username)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		c.Error(err)
 		return
 	}
 
+	metrics.LoginAttempts.WithLabelValues(method, metrics.LoginSuccess).Inc()
 	c.JSON(http.StatusOK, LoginResponse{
 		Token: token,
 		User: LoginUser{
@@ -191,7 +342,7 @@ func (h *UserHandler) Login(c *gin.Context) {
 type CreateUserRequest struct {
 	Username string `json:"username" binding:"required" example:"john_doe"`
 	Email    string `json:"email" binding:"required,email" example:"john@example.com"`
-	Password string `json:"password" binding:"required,min=6" example:"password123"`
+	Password string `json:"password" binding:"required" example:"S3cure-passw0rd"`
 }
 
 type UpdateUserRequest struct {
@@ -199,6 +350,16 @@ type UpdateUserRequest struct {
 	Email    string `json:"email" binding:"required,email" example:"john@example.com"`
 }
 
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required" example:"S3cure-passw0rd"`
+	NewPassword     string `json:"new_password" binding:"required" example:"N3w-passw0rd"`
+}
+
+type ChangePasswordResponse struct {
+	Message string `json:"message" example:"password updated"`
+	Token   string `json:"token" example:"eyJhbGciOiJIUzI1NiIs..."`
+}
+
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email" example:"john@example.com"`
 	Password string `json:"password" binding:"required" example:"password123"`
@@ -214,3 +375,14 @@ type LoginUser struct {
 	Username string `json:"username" example:"john_doe"`
 	Email    string `json:"email" example:"john@example.com"`
 }
+
+// currentVersion looks up the version an If-Match list is matched against.
+func (h *UserHandler) currentVersion(id int64) func(ctx context.Context) (int64, error) {
+	return func(ctx context.Context) (int64, error) {
+		user, err := h.service.GetUser(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+		return user.Version, nil
+	}
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"tasked/internal/domain"
+	"tasked/internal/middleware"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserResponse is a user as the account owner sees it. Handlers answer with
+// it or PublicUserResponse, never domain.User, so credentials stored with
+// the user cannot reach a response by adding a field.
+type UserResponse struct {
+	ID       int64  `json:"id" example:"1"`
+	Username string `json:"username" example:"john_doe"`
+	Email    string `json:"email" example:"john@example.com"`
+	// Null until the email is verified.
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt"`
+	MFAEnabled      bool       `json:"mfaEnabled" example:"false"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+	Version         int64      `json:"version" example:"1"`
+}
+
+// PublicUserResponse is a user as anyone else sees it, without contact
+// details or account security state.
+type PublicUserResponse struct {
+	ID        int64     `json:"id" example:"2"`
+	Username  string    `json:"username" example:"jane_doe"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func newUserResponse(user *domain.User) UserResponse {
+	r := UserResponse{
+		ID:         user.ID,
+		Username:   user.Username,
+		Email:      user.Email,
+		MFAEnabled: user.MFAEnabled,
+		CreatedAt:  user.CreatedAt,
+		UpdatedAt:  user.UpdatedAt,
+		Version:    user.Version,
+	}
+	if !user.EmailVerifiedAt.IsZero() {
+		verifiedAt := user.EmailVerifiedAt
+		r.EmailVerifiedAt = &verifiedAt
+	}
+	return r
+}
+
+func newPublicUserResponse(user *domain.User) PublicUserResponse {
+	return PublicUserResponse{
+		ID:        user.ID,
+		Username:  user.Username,
+		CreatedAt: user.CreatedAt,
+	}
+}
+
+// userResponseFor shows user to the caller: in full when it is the caller's
+// own account, as PublicUserResponse otherwise.
+func userResponseFor(c *gin.Context, user *domain.User) any {
+	if user.ID == middleware.GetUserID(c) {
+		return newUserResponse(user)
+	}
+	return newPublicUserResponse(user)
+}
@@ -0,0 +1,35 @@
+// Package health holds the dependency checks behind the readiness probe.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"tasked/internal/migrate"
+)
+
+// Check names a dependency and reports whether it is usable by returning nil.
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Database pings db.
+func Database(db *sql.DB) Check {
+	return Check{Name: "database", Check: db.PingContext}
+}
+
+// Migrations fails while migrations are pending, so an instance whose
+// schema is behind receives no traffic.
+func Migrations(migrator *migrate.Migrator) Check {
+	return Check{Name: "migrations", Check: func(ctx context.Context) error {
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migrations, first %03d_%s", len(pending), pending[0].Version, pending[0].Name)
+		}
+		return nil
+	}}
+}
@@ -0,0 +1,146 @@
+// Package metrics defines the Prometheus metrics exposed on /metrics.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"tasked/internal/domain"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace         = "tasked"
+	taskStatsDeadline = 5 * time.Second
+)
+
+// Label values of LoginAttempts. Throttled attempts never reach the
+// handlers; they show up as 429s in HTTPRequests.
+const (
+	LoginPassword = "password"
+	LoginMFA      = "mfa"
+	LoginOIDC     = "oidc"
+
+	LoginSuccess     = "success"
+	LoginFailure     = "failure"
+	LoginMFARequired = "mfa_required"
+	LoginUnverified  = "unverified"
+)
+
+// Label values of BcryptOperations and BcryptDuration.
+const (
+	BcryptHash    = "hash"
+	BcryptCompare = "compare"
+
+	BcryptOK       = "ok"
+	BcryptMismatch = "mismatch"
+	BcryptError    = "error"
+)
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	LoginAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_attempts_total",
+		Help:      "Login attempts by method and outcome.",
+	}, []string{"method", "outcome"})
+
+	BcryptOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bcrypt_operations_total",
+		Help:      "bcrypt hashes and comparisons by result.",
+	}, []string{"op", "result"})
+
+	BcryptDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "bcrypt_duration_seconds",
+		Help:      "Time spent in bcrypt, which dominates login latency.",
+		Buckets:   []float64{.01, .025, .05, .1, .25, .5, 1},
+	}, []string{"op"})
+)
+
+// TaskStatsSource provides the business gauges; it is queried on every
+// scrape.
+type TaskStatsSource interface {
+	TaskStats(ctx context.Context) (*domain.TaskStats, error)
+}
+
+// NewRegistry returns a registry with the process and Go runtime metrics,
+// the metrics above, the connection pool stats of db, unless it is nil, and
+// the task gauges.
+func NewRegistry(db *sql.DB, tasks TaskStatsSource) *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	if db != nil {
+		registry.MustRegister(collectors.NewDBStatsCollector(db, "tasked"))
+	}
+	registry.MustRegister(
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewGoCollector(),
+		HTTPRequests,
+		HTTPDuration,
+		LoginAttempts,
+		BcryptOperations,
+		BcryptDuration,
+		&taskCollector{source: tasks},
+	)
+	return registry
+}
+
+func Handler(registry *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+var (
+	tasksDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "tasks"),
+		"Tasks by status.",
+		[]string{"status"}, nil,
+	)
+	overdueTasksDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "tasks_overdue"),
+		"Tasks past their due date that are not completed.",
+		nil, nil,
+	)
+)
+
+type taskCollector struct {
+	source TaskStatsSource
+}
+
+func (c *taskCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- tasksDesc
+	ch <- overdueTasksDesc
+}
+
+func (c *taskCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), taskStatsDeadline)
+	defer cancel()
+
+	stats, err := c.source.TaskStats(ctx)
+	if err != nil {
+		slog.Error("failed to collect task metrics", "error", err)
+		ch <- prometheus.NewInvalidMetric(tasksDesc, err)
+		return
+	}
+	for status, count := range stats.ByStatus {
+		ch <- prometheus.MustNewConstMetric(tasksDesc, prometheus.GaugeValue, float64(count), status)
+	}
+	ch <- prometheus.MustNewConstMetric(overdueTasksDesc, prometheus.GaugeValue, float64(stats.Overdue))
+}
@@ -1,42 +1,90 @@
 package middleware
 
 import (
-	"net/http"
+	"context"
+	"errors"
 	"strings"
 	"tasked/internal/auth"
+	"tasked/internal/domain"
+	apperrors "tasked/internal/errors"
+	"tasked/internal/logging"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthRequired(tokenManager *auth.TokenManager) gin.HandlerFunc {
+// SessionValidator checks that the session behind a JWT has not been
+// revoked, for example by the user or by a password change.
+type SessionValidator interface {
+	Validate(ctx context.Context, id string, userID int64) error
+}
+
+// AccessTokenAuthenticator resolves a personal access token to its owner.
+type AccessTokenAuthenticator interface {
+	Authenticate(ctx context.Context, token string) (*domain.PersonalAccessToken, *domain.User, error)
+}
+
+// AuthRequired accepts either a JWT from /login or a personal access token.
+// Requests authenticated with an access token carry its scopes, see
+// RequireScope.
+func AuthRequired(tokenManager *auth.TokenManager, sessions SessionValidator, accessTokens AccessTokenAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing authorization header"})
-			c.Abort()
+			abortWithError(c, apperrors.ErrUnauthorized.WithMessage("missing authorization header"))
 			return
 		}
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization format"})
-			c.Abort()
+			abortWithError(c, apperrors.ErrUnauthorized.WithMessage("invalid authorization format"))
 			return
 		}
 
 		tokenString := parts[1]
 
+		if strings.HasPrefix(tokenString, domain.AccessTokenPrefix) {
+			pat, user, err := accessTokens.Authenticate(c.Request.Context(), tokenString)
+			if errors.Is(err, apperrors.ErrInvalidToken) {
+				abortWithError(c, apperrors.ErrUnauthorized.WithMessage("invalid token"))
+				return
+			}
+			if err != nil {
+				abortWithError(c, err)
+				return
+			}
+
+			c.Set("user_id", user.ID)
+			c.Set("email", user.Email)
+			c.Set("username", user.Username)
+			c.Set("scopes", pat.Scopes)
+			c.Request = c.Request.WithContext(logging.WithUserID(c.Request.Context(), user.ID))
+
+			c.Next()
+			return
+		}
+
 		claims, err := tokenManager.ValidateToken(tokenString)
+		if err != nil || claims.ID == "" {
+			abortWithError(c, apperrors.ErrUnauthorized.WithMessage("invalid token"))
+			return
+		}
+
+		err = sessions.Validate(c.Request.Context(), claims.ID, claims.UserID)
+		if errors.Is(err, apperrors.ErrInvalidToken) {
+			abortWithError(c, apperrors.ErrUnauthorized.WithMessage("token has been revoked"))
+			return
+		}
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
-			c.Abort()
+			abortWithError(c, err)
 			return
 		}
 
 		c.Set("user_id", claims.UserID)
 		c.Set("email", claims.Email)
 		c.Set("username", claims.Username)
+		c.Set("session_id", claims.ID)
+		c.Request = c.Request.WithContext(logging.WithUserID(c.Request.Context(), claims.UserID))
 
 		c.Next()
 	}
@@ -57,3 +105,21 @@ func GetUserEmail(c *gin.Context) string {
 	}
 	return email.(string)
 }
+
+func GetUsername(c *gin.Context) string {
+	username, exists := c.Get("username")
+	if !exists {
+		return ""
+	}
+	return username.(string)
+}
+
+// GetSessionID returns the session of the request, or "" when it was
+// authenticated with a personal access token.
+func GetSessionID(c *gin.Context) string {
+	sessionID, exists := c.Get("session_id")
+	if !exists {
+		return ""
+	}
+	return sessionID.(string)
+}
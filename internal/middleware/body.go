@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	apperrors "tasked/internal/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLimit refuses request bodies larger than limit bytes: at once when
+// Content-Length says so, and otherwise when a reader of the body goes past
+// the limit, which then gets an error for which BodyError reports 413.
+func BodyLimit(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			abortWithError(c, apperrors.ErrPayloadTooLarge.WithMessage(fmt.Sprintf("request body must be at most %d bytes", limit)))
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
+
+// BodyError turns an error reading the request body into the error to
+// report: 413 when it passed the BodyLimit, 400 otherwise.
+func BodyError(err error) error {
+	var maxBytes *http.MaxBytesError
+	if errors.As(err, &maxBytes) {
+		return apperrors.ErrPayloadTooLarge.WithMessage(fmt.Sprintf("request body must be at most %d bytes", maxBytes.Limit))
+	}
+	return apperrors.ErrBadRequest.WithMessage(err.Error())
+}
+
+// RequireJSON answers 415 to POST, PUT, PATCH and DELETE requests that send
+// a body in anything but JSON, including JSON-based types such as
+// application/merge-patch+json. Requests without a body pass, since the
+// handler decides whether one is needed.
+func RequireJSON() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength == 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		if err != nil || !isJSON(mediaType) {
+			abortWithError(c, apperrors.ErrUnsupportedMedia.WithMessage("content type must be application/json"))
+			return
+		}
+		c.Next()
+	}
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" ||
+		strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	apperrors "tasked/internal/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+const problemContentType = "application/problem+json"
+
+// Errors answers requests that failed with an RFC 7807 problem document.
+// Handlers and middleware report a failure with c.Error and return without
+// writing; the last error decides the response, see apperrors.Error.
+// Middleware that inspects the response after c.Next calls WriteProblem
+// first so the answer is final.
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		WriteProblem(c)
+	}
+}
+
+// WriteProblem writes the problem document for the last error of c unless a
+// response has already been written.
+func WriteProblem(c *gin.Context) {
+	if len(c.Errors) == 0 || c.Writer.Written() {
+		return
+	}
+
+	appErr := apperrors.From(c.Errors.Last().Err)
+	problem := gin.H{
+		"type":   "about:blank",
+		"title":  http.StatusText(appErr.Status),
+		"status": appErr.Status,
+		"detail": appErr.Message,
+		"code":   appErr.Code,
+	}
+	if c.Request.URL != nil {
+		problem["instance"] = c.Request.URL.Path
+	}
+	if requestID := GetRequestID(c); requestID != "" {
+		problem["request_id"] = requestID
+	}
+	for key, value := range appErr.Details {
+		if _, reserved := problem[key]; !reserved {
+			problem[key] = value
+		}
+	}
+
+	c.Header("Content-Type", problemContentType)
+	c.AbortWithStatusJSON(appErr.Status, problem)
+}
+
+// abortWithError reports err and stops the chain; Errors writes the
+// response.
+func abortWithError(c *gin.Context, err error) {
+	c.Error(err)
+	c.Abort()
+}
@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"tasked/internal/clock"
+	"tasked/internal/domain"
+	apperrors "tasked/internal/errors"
+	"tasked/internal/repository"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+	// How long a duplicate waits for the original request before giving up.
+	idempotencyInFlightWait = 5 * time.Second
+	idempotencyPollInterval = 100 * time.Millisecond
+	// An in-progress key older than this belongs to a request that died
+	// without finishing, so it can be claimed again.
+	idempotencyAbandonedAfter = time.Minute
+)
+
+// Headers replayed together with the stored body.
+var idempotentHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// Idempotency makes POST handlers safe to retry. The first request carrying
+// an Idempotency-Key runs normally and its response is stored for ttl; later
+// requests with the same key and body get that response replayed, while a
+// different body under the same key is rejected with 409. Keys are scoped to
+// the authenticated user and route, and server errors are not stored so the
+// client can retry them.
+func Idempotency(repo repository.IdempotencyRepository, ttl time.Duration, clock clock.Clock) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			abortWithError(c, apperrors.BadRequest("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			abortWithError(c, BodyError(err))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		scope := fmt.Sprintf("%d:%s %s", GetUserID(c), c.Request.Method, c.FullPath())
+		fingerprint := requestFingerprint(c.Request.Method, c.Request.URL.Path, body)
+		// The wait is measured in real time: it bounds how long this
+		// request sleeps, whatever the injected clock says.
+		waitStart := time.Now()
+
+		for {
+			now := clock.Now()
+			claimed, err := repo.ClaimKey(ctx, scope, key, fingerprint, now, now.Add(ttl))
+			if err != nil {
+				abortWithError(c, err)
+				return
+			}
+			if claimed {
+				runIdempotent(c, repo, scope, key)
+				return
+			}
+
+			record, err := repo.GetKey(ctx, scope, key)
+			if errors.Is(err, apperrors.ErrNotFound) {
+				// Released between our claim and the lookup; try again.
+				continue
+			}
+			if err != nil {
+				abortWithError(c, err)
+				return
+			}
+
+			now = clock.Now()
+			abandoned := record.Status == domain.IdempotencyInProgress && record.CreatedAt.Before(now.Add(-idempotencyAbandonedAfter))
+			if record.ExpiresAt.Before(now) || abandoned {
+				if _, err := repo.DeleteStaleKey(ctx, scope, key, now, now.Add(-idempotencyAbandonedAfter)); err != nil {
+					abortWithError(c, err)
+					return
+				}
+				continue
+			}
+
+			if record.Fingerprint != fingerprint {
+				abortWithError(c, apperrors.ErrConflict.WithMessage("Idempotency-Key was already used with a different request"))
+				return
+			}
+
+			if record.Status == domain.IdempotencyCompleted {
+				for name, value := range record.ResponseHeaders {
+					c.Header(name, value)
+				}
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(record.ResponseStatus, record.ResponseHeaders["Content-Type"], record.ResponseBody)
+				c.Abort()
+				return
+			}
+
+			if time.Since(waitStart) > idempotencyInFlightWait {
+				c.Header("Retry-After", "1")
+				abortWithError(c, apperrors.ErrConflict.WithMessage("a request with this Idempotency-Key is still in progress"))
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				c.Abort()
+				return
+			case <-time.After(idempotencyPollInterval):
+			}
+		}
+	}
+}
+
+func runIdempotent(c *gin.Context, repo repository.IdempotencyRepository, scope, key string) {
+	recorder := &responseRecorder{ResponseWriter: c.Writer}
+	c.Writer = recorder
+	c.Next()
+	WriteProblem(c)
+
+	// The outcome must be recorded even if the client has gone away.
+	ctx := context.WithoutCancel(c.Request.Context())
+	status := recorder.Status()
+	if status >= http.StatusInternalServerError {
+		releaseKey(ctx, repo, scope, key)
+		return
+	}
+
+	headers := map[string]string{}
+	for _, name := range idempotentHeaders {
+		if value := recorder.Header().Get(name); value != "" {
+			headers[name] = value
+		}
+	}
+	if err := repo.CompleteKey(ctx, scope, key, status, headers, recorder.body.Bytes()); err != nil {
+		slog.ErrorContext(ctx, "failed to store idempotent response", "error", err)
+		releaseKey(ctx, repo, scope, key)
+	}
+}
+
+// releaseKey frees a key whose response was not stored so the client can
+// retry. If that fails too, the key is reclaimed once it is abandoned.
+func releaseKey(ctx context.Context, repo repository.IdempotencyRepository, scope, key string) {
+	if err := repo.DeleteKey(ctx, scope, key); err != nil {
+		slog.ErrorContext(ctx, "failed to release idempotency key", "error", err)
+	}
+}
+
+func requestFingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"tasked/internal/auth"
+	apperrors "tasked/internal/errors"
+	"tasked/internal/ratelimit"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoginRateLimit throttles login attempts, and other requests an attacker
+// would repeat, per client IP and per account and feeds the outcome of each
+// attempt back into the lockout counters: an invalid credentials error
+// counts as a failure, whatever status it is answered with, and a 200
+// clears them. accountOf names the account a request is for, or returns ""
+// when it cannot tell.
+//
+// The limiter fails closed: when its store cannot be read the attempt is
+// refused with a 500. Outcomes are recorded once the response is decided,
+// so errors recording them can only be logged.
+func LoginRateLimit(limiter *ratelimit.Limiter, accountOf func(*gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		account := accountOf(c)
+
+		decision, err := limiter.Check(ctx, c.ClientIP(), account)
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+
+		setRateLimitHeaders(c, decision.Result)
+		if decision.Locked {
+			c.Header("Retry-After", ceilSeconds(decision.RetryAfter))
+			abortWithError(c, apperrors.ErrTooManyRequests.WithMessage("account temporarily locked"))
+			return
+		}
+		if !decision.Allowed {
+			c.Header("Retry-After", ceilSeconds(decision.RetryAfter))
+			abortWithError(c, apperrors.ErrTooManyRequests)
+			return
+		}
+
+		c.Next()
+		WriteProblem(c)
+
+		switch {
+		case invalidCredentials(c):
+			if _, err := limiter.Failure(ctx, account); err != nil {
+				slog.ErrorContext(ctx, "failed to record login failure", "error", err)
+			}
+		case c.Writer.Status() == http.StatusOK:
+			if err := limiter.Success(ctx, account); err != nil {
+				slog.ErrorContext(ctx, "failed to reset login failures", "error", err)
+			}
+		}
+	}
+}
+
+func invalidCredentials(c *gin.Context) bool {
+	last := c.Errors.Last()
+	return last != nil && errors.Is(last.Err, apperrors.ErrInvalidCredentials)
+}
+
+// LoginEmail keys password logins on the email in the body.
+func LoginEmail(c *gin.Context) string {
+	var req struct {
+		Email string `json:"email"`
+	}
+	peekJSON(c, &req)
+	return req.Email
+}
+
+// MFAChallengeUser keys second-factor attempts on the user the challenge
+// token was issued to, so guessing codes counts against that account.
+func MFAChallengeUser(tokenManager *auth.TokenManager) func(*gin.Context) string {
+	return func(c *gin.Context) string {
+		var req struct {
+			MFAToken string `json:"mfa_token"`
+		}
+		peekJSON(c, &req)
+		claims, err := tokenManager.ValidateMFAChallenge(req.MFAToken)
+		if err != nil {
+			return ""
+		}
+		return "user:" + strconv.FormatInt(claims.UserID, 10)
+	}
+}
+
+// AuthenticatedUser keys attempts on the user making the request, for
+// routes that ask an authenticated user to confirm a secret again.
+func AuthenticatedUser(c *gin.Context) string {
+	return "user:" + strconv.FormatInt(GetUserID(c), 10)
+}
+
+// peekJSON decodes the request body into v without consuming it.
+func peekJSON(c *gin.Context, v any) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	_ = json.Unmarshal(body, v)
+}
+
+func setRateLimitHeaders(c *gin.Context, result ratelimit.Result) {
+	c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("RateLimit-Reset", ceilSeconds(result.Reset))
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
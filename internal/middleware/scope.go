@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"tasked/internal/domain"
+	apperrors "tasked/internal/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireScope refuses requests authenticated with a personal access token
+// that was not granted scope. Sessions from /login are not scoped.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scopes, limited := c.Get("scopes")
+		if limited && !domain.HasScope(scopes.([]string), scope) {
+			abortWithError(c, apperrors.ErrForbidden.WithMessage("token is missing the "+scope+" scope").WithDetail("scope", scope))
+			return
+		}
+		c.Next()
+	}
+}
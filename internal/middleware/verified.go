@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"context"
+	apperrors "tasked/internal/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EmailVerifier interface {
+	IsEmailVerified(ctx context.Context, userID int64) (bool, error)
+}
+
+// RequireVerifiedEmail rejects requests from users who have not confirmed
+// their email yet. It must run after AuthRequired.
+func RequireVerifiedEmail(verifier EmailVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		verified, err := verifier.IsEmailVerified(c.Request.Context(), GetUserID(c))
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+		if !verified {
+			abortWithError(c, apperrors.ErrForbidden.WithMessage("email not verified"))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,144 @@
+// Package pgtest gives integration tests a throwaway Postgres database.
+//
+// Tests use the server in TEST_DATABASE_URL when it is set, which needs a
+// role allowed to create databases. Otherwise the first test to ask starts
+// a temporary cluster with initdb and pg_ctl, found on the PATH or in the
+// Debian /usr/lib/postgresql layout, and tests skip when neither exists.
+// Packages calling New must run their tests through Main so the cluster is
+// stopped afterwards:
+//
+//	func TestMain(m *testing.M) { os.Exit(pgtest.Main(m)) }
+package pgtest
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	_ "github.com/lib/pq"
+)
+
+var server struct {
+	once sync.Once
+	url  string
+	stop func()
+	err  error
+}
+
+// Main runs the tests and stops the cluster started for them, if any,
+// returning the exit code for os.Exit.
+func Main(m *testing.M) int {
+	code := m.Run()
+	if server.stop != nil {
+		server.stop()
+	}
+	return code
+}
+
+// New creates an empty database, dropped when the test ends, and returns
+// its postgres:// URL.
+func New(t testing.TB) string {
+	t.Helper()
+	server.once.Do(func() {
+		server.url = os.Getenv("TEST_DATABASE_URL")
+		if server.url == "" {
+			server.url, server.stop, server.err = start()
+		}
+	})
+	if server.err != nil {
+		t.Fatalf("start postgres: %v", server.err)
+	}
+	if server.url == "" {
+		t.Skip("no postgres: set TEST_DATABASE_URL or install initdb and pg_ctl")
+	}
+
+	admin, err := sql.Open("postgres", server.url)
+	if err != nil {
+		t.Fatalf("connect to %s: %v", server.url, err)
+	}
+	name := "tasked_test_" + strings.ToLower(rand.Text()[:12])
+	if _, err := admin.Exec("CREATE DATABASE " + name); err != nil {
+		admin.Close()
+		t.Fatalf("create database: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP DATABASE " + name + " WITH (FORCE)"); err != nil {
+			t.Errorf("drop database %s: %v", name, err)
+		}
+		admin.Close()
+	})
+
+	u, err := url.Parse(server.url)
+	if err != nil {
+		t.Fatalf("parse TEST_DATABASE_URL: %v", err)
+	}
+	u.Path = "/" + name
+	return u.String()
+}
+
+// start runs a cluster in a temporary directory, trusting local connections
+// and skipping fsync since its data is thrown away. It returns an empty URL
+// when Postgres is not installed.
+func start() (string, func(), error) {
+	initdb, pgctl := findBinary("initdb"), findBinary("pg_ctl")
+	if initdb == "" || pgctl == "" {
+		return "", nil, nil
+	}
+
+	dir, err := os.MkdirTemp("", "tasked-pgtest")
+	if err != nil {
+		return "", nil, err
+	}
+	data := filepath.Join(dir, "data")
+	if out, err := exec.Command(initdb, "-D", data, "-U", "postgres", "-A", "trust", "--no-sync").CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("initdb: %v\n%s", err, out)
+	}
+
+	port, err := freePort()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, err
+	}
+	options := fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1 -c fsync=off", port, dir)
+	cmd := exec.Command(pgctl, "-D", data, "-l", filepath.Join(dir, "postgres.log"), "-o", options, "-w", "start")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("pg_ctl start: %v\n%s", err, out)
+	}
+
+	stop := func() {
+		exec.Command(pgctl, "-D", data, "-m", "immediate", "stop").Run()
+		os.RemoveAll(dir)
+	}
+	return fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres?sslmode=disable", port), stop, nil
+}
+
+func findBinary(name string) string {
+	if path, err := exec.LookPath(name); err == nil {
+		return path
+	}
+	matches, _ := filepath.Glob(filepath.Join("/usr/lib/postgresql/*/bin", name))
+	if len(matches) == 0 {
+		return ""
+	}
+	// Major versions since 10 sort correctly as strings; take the newest.
+	return matches[len(matches)-1]
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
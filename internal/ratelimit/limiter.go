@@ -0,0 +1,198 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strings"
+	"tasked/internal/clock"
+	"time"
+)
+
+// Limit describes a token bucket holding Requests tokens that refills
+// completely over Window.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Window.Seconds()
+}
+
+// Lockout describes progressive lockout: once an account collects Threshold
+// failures, each further failure locks it for Base, doubling up to Max.
+// Failures are forgotten after Window without new ones.
+type Lockout struct {
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+	Window    time.Duration
+}
+
+// Result is the state of a bucket after a Take.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+// Store keeps buckets and failure counters. Implementations must be safe for
+// concurrent use and apply each call atomically.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+	RecordFailure(ctx context.Context, key string, lockout Lockout) (time.Time, error)
+	ResetFailures(ctx context.Context, key string) error
+	LockedUntil(ctx context.Context, key string) (time.Time, error)
+	Cleanup(ctx context.Context, idleSince time.Time) error
+}
+
+// Prefix returns a view of store whose keys are kept apart from those of
+// other limiters sharing it. Cleanup still covers the whole store.
+func Prefix(store Store, prefix string) Store {
+	return prefixedStore{store: store, prefix: prefix}
+}
+
+type prefixedStore struct {
+	store  Store
+	prefix string
+}
+
+func (s prefixedStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	return s.store.Take(ctx, s.prefix+key, limit)
+}
+
+func (s prefixedStore) RecordFailure(ctx context.Context, key string, lockout Lockout) (time.Time, error) {
+	return s.store.RecordFailure(ctx, s.prefix+key, lockout)
+}
+
+func (s prefixedStore) ResetFailures(ctx context.Context, key string) error {
+	return s.store.ResetFailures(ctx, s.prefix+key)
+}
+
+func (s prefixedStore) LockedUntil(ctx context.Context, key string) (time.Time, error) {
+	return s.store.LockedUntil(ctx, s.prefix+key)
+}
+
+func (s prefixedStore) Cleanup(ctx context.Context, idleSince time.Time) error {
+	return s.store.Cleanup(ctx, idleSince)
+}
+
+type Limiter struct {
+	store      Store
+	perIP      Limit
+	perAccount Limit
+	lockout    Lockout
+	clock      clock.Clock
+}
+
+func NewLimiter(store Store, perIP, perAccount Limit, lockout Lockout, clock clock.Clock) *Limiter {
+	return &Limiter{
+		store:      store,
+		perIP:      perIP,
+		perAccount: perAccount,
+		lockout:    lockout,
+		clock:      clock,
+	}
+}
+
+// Decision is the outcome of Check. Result is the most restrictive of the
+// buckets consulted and is what should be reported to the client.
+type Decision struct {
+	Result
+	Locked bool
+}
+
+// Check takes a token from the IP bucket and, when account is known, from
+// the account bucket, refusing outright while the account is locked out.
+func (l *Limiter) Check(ctx context.Context, ip, account string) (Decision, error) {
+	if account != "" {
+		until, err := l.store.LockedUntil(ctx, accountKey(account))
+		if err != nil {
+			return Decision{}, err
+		}
+		if wait := until.Sub(l.clock.Now()); wait > 0 {
+			return Decision{
+				Result: Result{Limit: l.perAccount.Requests, RetryAfter: wait, Reset: wait},
+				Locked: true,
+			}, nil
+		}
+	}
+
+	result, err := l.store.Take(ctx, "ip:"+ip, l.perIP)
+	if err != nil || !result.Allowed || account == "" {
+		return Decision{Result: result}, err
+	}
+
+	accountResult, err := l.store.Take(ctx, accountKey(account), l.perAccount)
+	if err != nil {
+		return Decision{}, err
+	}
+	if !accountResult.Allowed || accountResult.Remaining < result.Remaining {
+		result = accountResult
+	}
+	return Decision{Result: result}, nil
+}
+
+// Failure records a failed login for account and returns the time until
+// which the account is locked, zero when it is not.
+func (l *Limiter) Failure(ctx context.Context, account string) (time.Time, error) {
+	if account == "" {
+		return time.Time{}, nil
+	}
+	return l.store.RecordFailure(ctx, accountKey(account), l.lockout)
+}
+
+func (l *Limiter) Success(ctx context.Context, account string) error {
+	if account == "" {
+		return nil
+	}
+	return l.store.ResetFailures(ctx, accountKey(account))
+}
+
+// Cleanup drops state that has been idle long enough to no longer matter.
+func (l *Limiter) Cleanup(ctx context.Context) error {
+	idle := max(l.perIP.Window, l.perAccount.Window, l.lockout.Window, l.lockout.Max)
+	return l.store.Cleanup(ctx, l.clock.Now().Add(-idle))
+}
+
+func accountKey(account string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(account))
+}
+
+// take refills a bucket holding tokens that was last touched elapsed ago and
+// tries to remove one token from it. It returns the new token count.
+func take(tokens float64, elapsed time.Duration, limit Limit) (float64, Result) {
+	rate := limit.rate()
+	capacity := float64(limit.Requests)
+	tokens = math.Min(capacity, tokens+elapsed.Seconds()*rate)
+
+	result := Result{Limit: limit.Requests}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	result.Remaining = int(math.Floor(tokens))
+	result.Reset = seconds((capacity - tokens) / rate)
+	return tokens, result
+}
+
+// nextLock returns how long an account with the given number of failures
+// stays locked.
+func (l Lockout) nextLock(failures int) time.Duration {
+	if l.Threshold <= 0 || failures < l.Threshold {
+		return 0
+	}
+	lock := l.Base
+	for i := l.Threshold; i < failures && lock < l.Max; i++ {
+		lock *= 2
+	}
+	return min(lock, l.Max)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
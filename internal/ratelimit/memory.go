@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"tasked/internal/clock"
+	"time"
+)
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+type failureState struct {
+	failures      int
+	lastFailureAt time.Time
+	lockedUntil   time.Time
+}
+
+// MemoryStore keeps all state in process. It is only correct when a single
+// instance serves the traffic.
+type MemoryStore struct {
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	failures map[string]*failureState
+	clock    clock.Clock
+}
+
+func NewMemoryStore(clock clock.Clock) *MemoryStore {
+	return &MemoryStore{
+		buckets:  make(map[string]*bucket),
+		failures: make(map[string]*failureState),
+		clock:    clock,
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Requests), updatedAt: now}
+		s.buckets[key] = b
+	}
+
+	tokens, result := take(b.tokens, now.Sub(b.updatedAt), limit)
+	b.tokens = tokens
+	b.updatedAt = now
+	return result, nil
+}
+
+func (s *MemoryStore) RecordFailure(ctx context.Context, key string, lockout Lockout) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	f, ok := s.failures[key]
+	if !ok || now.Sub(f.lastFailureAt) > lockout.Window {
+		f = &failureState{}
+		s.failures[key] = f
+	}
+
+	f.failures++
+	f.lastFailureAt = now
+	if lock := lockout.nextLock(f.failures); lock > 0 {
+		f.lockedUntil = now.Add(lock)
+	}
+	return f.lockedUntil, nil
+}
+
+func (s *MemoryStore) ResetFailures(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.failures, key)
+	return nil
+}
+
+func (s *MemoryStore) LockedUntil(ctx context.Context, key string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f, ok := s.failures[key]; ok {
+		return f.lockedUntil, nil
+	}
+	return time.Time{}, nil
+}
+
+func (s *MemoryStore) Cleanup(ctx context.Context, idleSince time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, b := range s.buckets {
+		if b.updatedAt.Before(idleSince) {
+			delete(s.buckets, key)
+		}
+	}
+	now := s.clock.Now()
+	for key, f := range s.failures {
+		if f.lastFailureAt.Before(idleSince) && f.lockedUntil.Before(now) {
+			delete(s.failures, key)
+		}
+	}
+	return nil
+}
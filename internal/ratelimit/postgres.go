@@ -0,0 +1,131 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"tasked/internal/clock"
+	"tasked/internal/database"
+	"time"
+)
+
+// PostgresStore shares buckets and lockouts between instances. Every call
+// locks its row for the duration of a short transaction and uses the
+// database clock, so instances with skewed clocks still agree. Lock times
+// are returned relative to clock.
+type PostgresStore struct {
+	db      *sql.DB
+	queries *database.Queries
+	clock   clock.Clock
+}
+
+func NewPostgresStore(db *sql.DB, clock clock.Clock) *PostgresStore {
+	return &PostgresStore{
+		db:      db,
+		queries: database.New(db),
+		clock:   clock,
+	}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	var result Result
+	err := s.inTx(ctx, func(q *database.Queries) error {
+		err := q.EnsureRateLimitBucket(ctx, database.EnsureRateLimitBucketParams{
+			Key:    key,
+			Tokens: float64(limit.Requests),
+		})
+		if err != nil {
+			return err
+		}
+
+		row, err := q.GetRateLimitBucketForUpdate(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		var tokens float64
+		tokens, result = take(row.Tokens, row.Now.Sub(row.UpdatedAt), limit)
+		return q.UpdateRateLimitBucket(ctx, database.UpdateRateLimitBucketParams{
+			Key:       key,
+			Tokens:    tokens,
+			UpdatedAt: row.Now,
+		})
+	})
+	return result, err
+}
+
+func (s *PostgresStore) RecordFailure(ctx context.Context, key string, lockout Lockout) (time.Time, error) {
+	var lockedUntil time.Time
+	err := s.inTx(ctx, func(q *database.Queries) error {
+		if err := q.EnsureLoginFailure(ctx, key); err != nil {
+			return err
+		}
+
+		row, err := q.GetLoginFailureForUpdate(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		failures := int(row.Failures)
+		if row.Now.Sub(row.LastFailureAt) > lockout.Window {
+			failures = 0
+			row.LockedUntil = sql.NullTime{}
+		}
+		failures++
+
+		if lock := lockout.nextLock(failures); lock > 0 {
+			row.LockedUntil = sql.NullTime{Time: row.Now.Add(lock), Valid: true}
+		}
+		if row.LockedUntil.Valid {
+			lockedUntil = s.clock.Now().Add(row.LockedUntil.Time.Sub(row.Now))
+		}
+
+		return q.UpdateLoginFailure(ctx, database.UpdateLoginFailureParams{
+			Key:           key,
+			Failures:      int32(failures),
+			LastFailureAt: row.Now,
+			LockedUntil:   row.LockedUntil,
+		})
+	})
+	return lockedUntil, err
+}
+
+func (s *PostgresStore) ResetFailures(ctx context.Context, key string) error {
+	return s.queries.DeleteLoginFailure(ctx, key)
+}
+
+// LockedUntil translates the database deadline onto the store clock so the
+// caller can compare it with its Now.
+func (s *PostgresStore) LockedUntil(ctx context.Context, key string) (time.Time, error) {
+	row, err := s.queries.GetLoginLock(ctx, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !row.LockedUntil.Valid {
+		return time.Time{}, nil
+	}
+	return s.clock.Now().Add(row.LockedUntil.Time.Sub(row.Now)), nil
+}
+
+func (s *PostgresStore) Cleanup(ctx context.Context, idleSince time.Time) error {
+	if _, err := s.queries.DeleteIdleRateLimitBuckets(ctx, idleSince); err != nil {
+		return err
+	}
+	_, err := s.queries.DeleteIdleLoginFailures(ctx, idleSince)
+	return err
+}
+
+func (s *PostgresStore) inTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(s.queries.WithTx(tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"tasked/internal/database"
+	"tasked/internal/domain"
+	"time"
+)
+
+type AccessTokenRepository interface {
+	CreateToken(ctx context.Context, userID int64, name string, tokenHash string, scopes []string, expiresAt time.Time) (*domain.PersonalAccessToken, error)
+	ListTokens(ctx context.Context, userID int64) ([]*domain.PersonalAccessToken, error)
+	GetTokenByHash(ctx context.Context, tokenHash string) (*domain.PersonalAccessToken, error)
+	TouchToken(ctx context.Context, id int64) error
+	DeleteToken(ctx context.Context, id int64, userID int64) error
+}
+
+type accessTokenRepository struct {
+	queries *database.Queries
+}
+
+func NewAccessTokenRepository(db *sql.DB) AccessTokenRepository {
+	return &accessTokenRepository{
+		queries: database.New(db),
+	}
+}
+
+func (r *accessTokenRepository) CreateToken(ctx context.Context, userID int64, name string, tokenHash string, scopes []string, expiresAt time.Time) (*domain.PersonalAccessToken, error) {
+	dbToken, err := r.queries.CreatePersonalAccessToken(ctx, database.CreatePersonalAccessTokenParams{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: tokenHash,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, dbError(err, "access token")
+	}
+	return toDomainAccessToken(dbToken), nil
+}
+
+func (r *accessTokenRepository) ListTokens(ctx context.Context, userID int64) ([]*domain.PersonalAccessToken, error) {
+	dbTokens, err := r.queries.ListPersonalAccessTokens(ctx, userID)
+	if err != nil {
+		return nil, dbError(err, "access token")
+	}
+	tokens := make([]*domain.PersonalAccessToken, 0, len(dbTokens))
+	for _, dbToken := range dbTokens {
+		tokens = append(tokens, toDomainAccessToken(dbToken))
+	}
+	return tokens, nil
+}
+
+// GetTokenByHash returns ErrNotFound for unknown and expired tokens alike.
+func (r *accessTokenRepository) GetTokenByHash(ctx context.Context, tokenHash string) (*domain.PersonalAccessToken, error) {
+	dbToken, err := r.queries.GetPersonalAccessTokenByHash(ctx, tokenHash)
+	if err != nil {
+		return nil, dbError(err, "access token")
+	}
+	return toDomainAccessToken(dbToken), nil
+}
+
+func (r *accessTokenRepository) TouchToken(ctx context.Context, id int64) error {
+	return r.queries.TouchPersonalAccessToken(ctx, id)
+}
+
+func (r *accessTokenRepository) DeleteToken(ctx context.Context, id int64, userID int64) error {
+	rows, err := r.queries.DeletePersonalAccessToken(ctx, database.DeletePersonalAccessTokenParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		return dbError(err, "access token")
+	}
+	if rows == 0 {
+		return notFound("access token")
+	}
+	return nil
+}
+
+func toDomainAccessToken(dbToken database.PersonalAccessToken) *domain.PersonalAccessToken {
+	return &domain.PersonalAccessToken{
+		ID:         dbToken.ID,
+		UserID:     dbToken.UserID,
+		Name:       dbToken.Name,
+		Scopes:     dbToken.Scopes,
+		ExpiresAt:  dbToken.ExpiresAt,
+		LastUsedAt: dbToken.LastUsedAt.Time,
+		CreatedAt:  dbToken.CreatedAt,
+	}
+}
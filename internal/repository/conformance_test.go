@@ -0,0 +1,55 @@
+package repository_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"tasked/internal/clock"
+	"tasked/internal/pgtest"
+	"tasked/internal/repository"
+	"tasked/internal/repository/repotest"
+	"tasked/internal/storage"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+func TestMemoryRepositories(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) *repository.Repositories {
+		return repository.NewMemoryRepositories(clock.System)
+	})
+}
+
+func TestSQLiteRepositories(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) *repository.Repositories {
+		return openDatabase(t, "sqlite://"+filepath.Join(t.TempDir(), "tasked.db"))
+	})
+}
+
+func TestPostgresRepositories(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) *repository.Repositories {
+		return openDatabase(t, pgtest.New(t))
+	})
+}
+
+// openDatabase opens url and brings its schema up to date.
+func openDatabase(t *testing.T, url string) *repository.Repositories {
+	t.Helper()
+	ctx := context.Background()
+	db, err := storage.Open(ctx, url)
+	if err != nil {
+		t.Fatalf("open %s: %v", url, err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := db.Migrator()
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db.Repositories()
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	apperrors "tasked/internal/errors"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// keyColumns extracts the columns from the DETAIL of a constraint violation,
+// such as `Key (email)=(john@example.com) already exists.`
+var keyColumns = regexp.MustCompile(`^Key \(([^)]+)\)=`)
+
+// sqliteColumns extracts the columns from a SQLite constraint message, such
+// as `UNIQUE constraint failed: users.email`.
+var sqliteColumns = regexp.MustCompile(`UNIQUE constraint failed: ([\w.]+(?:, [\w.]+)*)`)
+
+// dbError translates database errors into application errors: a missing row
+// becomes ErrNotFound, a unique violation ErrConflict and a foreign key
+// pointing nowhere ErrBadRequest, naming resource and the column involved.
+// Other errors, and errors that are already application errors, are
+// returned unchanged.
+func dbError(err error, resource string) error {
+	if err == nil {
+		return nil
+	}
+	var appErr *apperrors.Error
+	if errors.As(err, &appErr) {
+		return err
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound(resource).Wrap(err)
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteError(sqliteErr, resource)
+	}
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	var column string
+	if match := keyColumns.FindStringSubmatch(pqErr.Detail); match != nil {
+		column = match[1]
+	}
+
+	switch pqErr.Code.Name() {
+	case "unique_violation":
+		return duplicate(resource, column).Wrap(err)
+	case "foreign_key_violation":
+		// Deleting a row other rows still point at.
+		if strings.Contains(pqErr.Detail, "is still referenced") {
+			return apperrors.ErrConflict.WithMessage(resource + " is still referenced").Wrap(err)
+		}
+		return missingReference(resource, column).Wrap(err)
+	}
+	return err
+}
+
+// sqliteError is dbError for the SQLite driver, whose foreign key errors do
+// not name the column.
+func sqliteError(err *sqlite.Error, resource string) error {
+	switch err.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		var columns []string
+		if match := sqliteColumns.FindStringSubmatch(err.Error()); match != nil {
+			for _, column := range strings.Split(match[1], ", ") {
+				_, name, _ := strings.Cut(column, ".")
+				columns = append(columns, name)
+			}
+		}
+		return duplicate(resource, strings.Join(columns, ", ")).Wrap(err)
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		return missingReference(resource, "").Wrap(err)
+	}
+	return err
+}
+
+func notFound(resource string) *apperrors.Error {
+	return apperrors.ErrNotFound.WithMessage(resource + " not found")
+}
+
+// duplicate is the error for a row that would repeat the unique column of an
+// existing one.
+func duplicate(resource, column string) *apperrors.Error {
+	if column == "" {
+		return apperrors.ErrConflict.WithMessage(resource + " already exists")
+	}
+	return apperrors.ErrConflict.
+		WithMessage(resource+" with this "+column+" already exists").
+		WithDetail("field", column)
+}
+
+// missingReference is the error for a row whose foreign key column points at
+// a row that does not exist.
+func missingReference(resource, column string) *apperrors.Error {
+	if column == "" {
+		return apperrors.ErrBadRequest.WithMessage(resource + " references a missing record")
+	}
+	return apperrors.ErrBadRequest.
+		WithMessage(column+" does not reference an existing record").
+		WithDetail("field", column)
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"tasked/internal/database"
+	"tasked/internal/domain"
+	"time"
+)
+
+type IdempotencyRepository interface {
+	ClaimKey(ctx context.Context, scope string, key string, fingerprint string, now time.Time, expiresAt time.Time) (bool, error)
+	GetKey(ctx context.Context, scope string, key string) (*domain.IdempotencyRecord, error)
+	CompleteKey(ctx context.Context, scope string, key string, status int, headers map[string]string, body []byte) error
+	DeleteKey(ctx context.Context, scope string, key string) error
+	DeleteStaleKey(ctx context.Context, scope string, key string, now time.Time, abandonedBefore time.Time) (bool, error)
+	DeleteExpiredKeys(ctx context.Context, now time.Time) (int64, error)
+}
+
+type idempotencyRepository struct {
+	queries *database.Queries
+}
+
+func NewIdempotencyRepository(db *sql.DB) IdempotencyRepository {
+	return &idempotencyRepository{
+		queries: database.New(db),
+	}
+}
+
+// ClaimKey reserves the key for the caller as of now. It reports false when
+// another request already holds it, whatever state that request is in.
+func (r *idempotencyRepository) ClaimKey(ctx context.Context, scope string, key string, fingerprint string, now time.Time, expiresAt time.Time) (bool, error) {
+	rows, err := r.queries.ClaimIdempotencyKey(ctx, database.ClaimIdempotencyKeyParams{
+		Scope:       scope,
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		ExpiresAt:   expiresAt,
+	})
+	if err != nil {
+		return false, dbError(err, "idempotency key")
+	}
+	return rows == 1, nil
+}
+
+func (r *idempotencyRepository) GetKey(ctx context.Context, scope string, key string) (*domain.IdempotencyRecord, error) {
+	dbKey, err := r.queries.GetIdempotencyKey(ctx, database.GetIdempotencyKeyParams{
+		Scope: scope,
+		Key:   key,
+	})
+	if err != nil {
+		return nil, dbError(err, "idempotency key")
+	}
+
+	headers := map[string]string{}
+	if err := json.Unmarshal(dbKey.ResponseHeaders, &headers); err != nil {
+		return nil, dbError(err, "idempotency key")
+	}
+
+	return &domain.IdempotencyRecord{
+		Scope:           dbKey.Scope,
+		Key:             dbKey.Key,
+		Fingerprint:     dbKey.Fingerprint,
+		Status:          dbKey.Status,
+		ResponseStatus:  int(dbKey.ResponseStatus.Int32),
+		ResponseHeaders: headers,
+		ResponseBody:    dbKey.ResponseBody,
+		CreatedAt:       dbKey.CreatedAt,
+		ExpiresAt:       dbKey.ExpiresAt,
+	}, nil
+}
+
+func (r *idempotencyRepository) CompleteKey(ctx context.Context, scope string, key string, status int, headers map[string]string, body []byte) error {
+	encodedHeaders, err := json.Marshal(headers)
+	if err != nil {
+		return dbError(err, "idempotency key")
+	}
+
+	return r.queries.CompleteIdempotencyKey(ctx, database.CompleteIdempotencyKeyParams{
+		Scope: scope,
+		Key:   key,
+		ResponseStatus: sql.NullInt32{
+			Int32: int32(status),
+			Valid: true,
+		},
+		ResponseHeaders: encodedHeaders,
+		ResponseBody:    body,
+	})
+}
+
+func (r *idempotencyRepository) DeleteKey(ctx context.Context, scope string, key string) error {
+	return r.queries.DeleteIdempotencyKey(ctx, database.DeleteIdempotencyKeyParams{
+		Scope: scope,
+		Key:   key,
+	})
+}
+
+// DeleteStaleKey removes the key only if it has expired by now or its request
+// has been in progress since before abandonedBefore.
+func (r *idempotencyRepository) DeleteStaleKey(ctx context.Context, scope string, key string, now time.Time, abandonedBefore time.Time) (bool, error) {
+	rows, err := r.queries.DeleteStaleIdempotencyKey(ctx, database.DeleteStaleIdempotencyKeyParams{
+		Scope:           scope,
+		Key:             key,
+		Now:             now,
+		AbandonedBefore: abandonedBefore,
+	})
+	if err != nil {
+		return false, dbError(err, "idempotency key")
+	}
+	return rows > 0, nil
+}
+
+func (r *idempotencyRepository) DeleteExpiredKeys(ctx context.Context, now time.Time) (int64, error) {
+	return r.queries.DeleteExpiredIdempotencyKeys(ctx, now)
+}
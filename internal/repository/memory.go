@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"sync"
+	"tasked/internal/clock"
+	"tasked/internal/domain"
+	"time"
+)
+
+// MemoryStore keeps the data of the in-memory repositories in process, for
+// tests and demo servers; nothing survives a restart. Repositories built on
+// the same store see each other's rows, so the unique columns, foreign keys
+// and cascading deletes of the Postgres schema apply across them.
+type MemoryStore struct {
+	mu sync.Mutex
+
+	users         map[int64]memoryUser
+	tasks         map[int64]memoryTask
+	userTokens    map[string]memoryUserToken
+	sessions      map[string]memorySession
+	recoveryCodes map[int64][]memoryRecoveryCode
+	identities    map[memoryIdentityKey]memoryIdentity
+	loginStates   map[string]memoryLoginState
+	accessTokens  map[int64]memoryAccessToken
+	idempotency   map[memoryIdempotencyKey]domain.IdempotencyRecord
+
+	lastUserID        int64
+	lastTaskID        int64
+	lastAccessTokenID int64
+
+	clock clock.Clock
+}
+
+func NewMemoryStore(clock clock.Clock) *MemoryStore {
+	return &MemoryStore{
+		clock:         clock,
+		users:         make(map[int64]memoryUser),
+		tasks:         make(map[int64]memoryTask),
+		userTokens:    make(map[string]memoryUserToken),
+		sessions:      make(map[string]memorySession),
+		recoveryCodes: make(map[int64][]memoryRecoveryCode),
+		identities:    make(map[memoryIdentityKey]memoryIdentity),
+		loginStates:   make(map[string]memoryLoginState),
+		accessTokens:  make(map[int64]memoryAccessToken),
+		idempotency:   make(map[memoryIdempotencyKey]domain.IdempotencyRecord),
+	}
+}
+
+// NewMemoryRepositories returns every repository backed by a new, empty
+// MemoryStore that takes the time from clock.
+func NewMemoryRepositories(clock clock.Clock) *Repositories {
+	store := NewMemoryStore(clock)
+	return &Repositories{
+		Users:        NewMemoryUserRepository(store),
+		UserTokens:   NewMemoryUserTokenRepository(store),
+		Sessions:     NewMemorySessionRepository(store),
+		MFA:          NewMemoryMFARepository(store),
+		OIDC:         NewMemoryOIDCRepository(store),
+		AccessTokens: NewMemoryAccessTokenRepository(store),
+		Tasks:        NewMemoryTaskRepository(store),
+		Idempotency:  NewMemoryIdempotencyRepository(store),
+	}
+}
+
+// now returns the current time with the microsecond precision Postgres
+// stores.
+func (s *MemoryStore) now() time.Time {
+	return s.clock.Now().Truncate(time.Microsecond)
+}
+
+// deleteUser removes the user and, like ON DELETE CASCADE, every row that
+// belongs to it. The caller holds s.mu.
+func (s *MemoryStore) deleteUser(id int64) {
+	delete(s.users, id)
+	delete(s.recoveryCodes, id)
+	for taskID, task := range s.tasks {
+		if task.Userid == id {
+			delete(s.tasks, taskID)
+		}
+	}
+	for hash, token := range s.userTokens {
+		if token.userID == id {
+			delete(s.userTokens, hash)
+		}
+	}
+	for sessionID, session := range s.sessions {
+		if session.UserID == id {
+			delete(s.sessions, sessionID)
+		}
+	}
+	for key, identity := range s.identities {
+		if identity.userID == id {
+			delete(s.identities, key)
+		}
+	}
+	for tokenID, token := range s.accessTokens {
+		if token.UserID == id {
+			delete(s.accessTokens, tokenID)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"tasked/internal/domain"
+	"time"
+)
+
+type memoryAccessToken struct {
+	domain.PersonalAccessToken
+	tokenHash string
+}
+
+func (t memoryAccessToken) toDomain() *domain.PersonalAccessToken {
+	token := t.PersonalAccessToken
+	token.Scopes = slices.Clone(t.Scopes)
+	return &token
+}
+
+type memoryAccessTokenRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryAccessTokenRepository(store *MemoryStore) AccessTokenRepository {
+	return &memoryAccessTokenRepository{store: store}
+}
+
+func (r *memoryAccessTokenRepository) CreateToken(ctx context.Context, userID int64, name string, tokenHash string, scopes []string, expiresAt time.Time) (*domain.PersonalAccessToken, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[userID]; !ok {
+		return nil, missingReference("access token", "user_id")
+	}
+	for _, token := range r.store.accessTokens {
+		if token.tokenHash == tokenHash {
+			return nil, duplicate("access token", "token_hash")
+		}
+	}
+
+	r.store.lastAccessTokenID++
+	token := memoryAccessToken{
+		PersonalAccessToken: domain.PersonalAccessToken{
+			ID:        r.store.lastAccessTokenID,
+			UserID:    userID,
+			Name:      name,
+			Scopes:    slices.Clone(scopes),
+			ExpiresAt: expiresAt,
+			CreatedAt: r.store.now(),
+		},
+		tokenHash: tokenHash,
+	}
+	r.store.accessTokens[token.ID] = token
+	return token.toDomain(), nil
+}
+
+func (r *memoryAccessTokenRepository) ListTokens(ctx context.Context, userID int64) ([]*domain.PersonalAccessToken, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	tokens := make([]*domain.PersonalAccessToken, 0)
+	for _, token := range r.store.accessTokens {
+		if token.UserID == userID {
+			tokens = append(tokens, token.toDomain())
+		}
+	}
+	slices.SortFunc(tokens, func(a, b *domain.PersonalAccessToken) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+	return tokens, nil
+}
+
+// GetTokenByHash returns ErrNotFound for unknown and expired tokens alike.
+func (r *memoryAccessTokenRepository) GetTokenByHash(ctx context.Context, tokenHash string) (*domain.PersonalAccessToken, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := r.store.now()
+	for _, token := range r.store.accessTokens {
+		if token.tokenHash == tokenHash && token.ExpiresAt.After(now) {
+			return token.toDomain(), nil
+		}
+	}
+	return nil, notFound("access token")
+}
+
+func (r *memoryAccessTokenRepository) TouchToken(ctx context.Context, id int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	token, ok := r.store.accessTokens[id]
+	if !ok {
+		return nil
+	}
+	token.LastUsedAt = r.store.now()
+	r.store.accessTokens[id] = token
+	return nil
+}
+
+func (r *memoryAccessTokenRepository) DeleteToken(ctx context.Context, id int64, userID int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	token, ok := r.store.accessTokens[id]
+	if !ok || token.UserID != userID {
+		return notFound("access token")
+	}
+	delete(r.store.accessTokens, id)
+	return nil
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"tasked/internal/domain"
+	"time"
+)
+
+type memoryIdempotencyKey struct {
+	scope string
+	key   string
+}
+
+type memoryIdempotencyRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryIdempotencyRepository(store *MemoryStore) IdempotencyRepository {
+	return &memoryIdempotencyRepository{store: store}
+}
+
+// ClaimKey reserves the key for the caller as of now. It reports false when
+// another request already holds it, whatever state that request is in.
+func (r *memoryIdempotencyRepository) ClaimKey(ctx context.Context, scope string, key string, fingerprint string, now time.Time, expiresAt time.Time) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	id := memoryIdempotencyKey{scope: scope, key: key}
+	if _, ok := r.store.idempotency[id]; ok {
+		return false, nil
+	}
+	r.store.idempotency[id] = domain.IdempotencyRecord{
+		Scope:           scope,
+		Key:             key,
+		Fingerprint:     fingerprint,
+		Status:          domain.IdempotencyInProgress,
+		ResponseHeaders: map[string]string{},
+		CreatedAt:       now.Truncate(time.Microsecond),
+		ExpiresAt:       expiresAt,
+	}
+	return true, nil
+}
+
+func (r *memoryIdempotencyRepository) GetKey(ctx context.Context, scope string, key string) (*domain.IdempotencyRecord, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	record, ok := r.store.idempotency[memoryIdempotencyKey{scope: scope, key: key}]
+	if !ok {
+		return nil, notFound("idempotency key")
+	}
+	record.ResponseHeaders = maps.Clone(record.ResponseHeaders)
+	record.ResponseBody = slices.Clone(record.ResponseBody)
+	return &record, nil
+}
+
+func (r *memoryIdempotencyRepository) CompleteKey(ctx context.Context, scope string, key string, status int, headers map[string]string, body []byte) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	id := memoryIdempotencyKey{scope: scope, key: key}
+	record, ok := r.store.idempotency[id]
+	if !ok {
+		return nil
+	}
+	record.Status = domain.IdempotencyCompleted
+	record.ResponseStatus = status
+	record.ResponseHeaders = maps.Clone(headers)
+	if record.ResponseHeaders == nil {
+		record.ResponseHeaders = map[string]string{}
+	}
+	record.ResponseBody = slices.Clone(body)
+	r.store.idempotency[id] = record
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) DeleteKey(ctx context.Context, scope string, key string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.idempotency, memoryIdempotencyKey{scope: scope, key: key})
+	return nil
+}
+
+// DeleteStaleKey removes the key only if it has expired by now or its request
+// has been in progress since before abandonedBefore.
+func (r *memoryIdempotencyRepository) DeleteStaleKey(ctx context.Context, scope string, key string, now time.Time, abandonedBefore time.Time) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	id := memoryIdempotencyKey{scope: scope, key: key}
+	record, ok := r.store.idempotency[id]
+	if !ok {
+		return false, nil
+	}
+	abandoned := record.Status == domain.IdempotencyInProgress && record.CreatedAt.Before(abandonedBefore)
+	if !record.ExpiresAt.Before(now) && !abandoned {
+		return false, nil
+	}
+	delete(r.store.idempotency, id)
+	return true, nil
+}
+
+func (r *memoryIdempotencyRepository) DeleteExpiredKeys(ctx context.Context, now time.Time) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var deleted int64
+	for id, record := range r.store.idempotency {
+		if record.ExpiresAt.Before(now) {
+			delete(r.store.idempotency, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
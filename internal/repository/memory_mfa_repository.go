@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"tasked/internal/domain"
+)
+
+type memoryRecoveryCode struct {
+	hash string
+	used bool
+}
+
+type memoryMFARepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryMFARepository(store *MemoryStore) MFARepository {
+	return &memoryMFARepository{store: store}
+}
+
+func (r *memoryMFARepository) GetMFA(ctx context.Context, userID int64) (*domain.UserMFA, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[userID]
+	if !ok {
+		return nil, notFound("user")
+	}
+	mfa := user.mfa
+	return &mfa, nil
+}
+
+// SetPendingSecret starts a new enrollment, replacing any previous one.
+func (r *memoryMFARepository) SetPendingSecret(ctx context.Context, userID int64, secret string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[userID]
+	if !ok {
+		return nil
+	}
+	user.mfa = domain.UserMFA{Secret: secret}
+	user.usedStep = false
+	user.UpdatedAt = r.store.now()
+	r.store.users[userID] = user
+	return nil
+}
+
+// EnableMFA confirms the pending enrollment and replaces the recovery codes.
+func (r *memoryMFARepository) EnableMFA(ctx context.Context, userID int64, recoveryCodeHashes []string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[userID]
+	if !ok {
+		return nil
+	}
+	now := r.store.now()
+	user.mfa.EnabledAt = now
+	user.UpdatedAt = now
+	user.Version++
+	r.store.users[userID] = user
+
+	codes := make([]memoryRecoveryCode, 0, len(recoveryCodeHashes))
+	for _, hash := range recoveryCodeHashes {
+		codes = append(codes, memoryRecoveryCode{hash: hash})
+	}
+	r.store.recoveryCodes[userID] = codes
+	return nil
+}
+
+func (r *memoryMFARepository) DisableMFA(ctx context.Context, userID int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.recoveryCodes, userID)
+	user, ok := r.store.users[userID]
+	if !ok {
+		return nil
+	}
+	user.mfa = domain.UserMFA{}
+	user.usedStep = false
+	user.UpdatedAt = r.store.now()
+	user.Version++
+	r.store.users[userID] = user
+	return nil
+}
+
+// UseTOTPStep records step as used. It reports false when that step, or a
+// later one, was already used, which means the code is being replayed.
+func (r *memoryMFARepository) UseTOTPStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[userID]
+	if !ok || (user.usedStep && user.mfa.LastStep >= step) {
+		return false, nil
+	}
+	user.mfa.LastStep = step
+	user.usedStep = true
+	r.store.users[userID] = user
+	return true, nil
+}
+
+func (r *memoryMFARepository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	used := false
+	codes := r.store.recoveryCodes[userID]
+	for i := range codes {
+		if codes[i].hash == codeHash && !codes[i].used {
+			codes[i].used = true
+			used = true
+		}
+	}
+	return used, nil
+}
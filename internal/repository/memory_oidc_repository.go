@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"tasked/internal/domain"
+	"time"
+)
+
+type memoryIdentityKey struct {
+	issuer  string
+	subject string
+}
+
+type memoryIdentity struct {
+	userID int64
+	email  string
+}
+
+type memoryLoginState struct {
+	codeVerifier string
+	nonce        string
+	expiresAt    time.Time
+}
+
+type memoryOIDCRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryOIDCRepository(store *MemoryStore) OIDCRepository {
+	return &memoryOIDCRepository{store: store}
+}
+
+func (r *memoryOIDCRepository) GetUserByIdentity(ctx context.Context, issuer string, subject string) (*domain.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	identity, ok := r.store.identities[memoryIdentityKey{issuer: issuer, subject: subject}]
+	if !ok {
+		return nil, notFound("identity")
+	}
+	user, ok := r.store.users[identity.userID]
+	if !ok {
+		return nil, notFound("identity")
+	}
+	return user.toDomain(), nil
+}
+
+func (r *memoryOIDCRepository) LinkIdentity(ctx context.Context, userID int64, issuer string, subject string, email string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.linkIdentity(userID, issuer, subject, email, "identity")
+}
+
+// CreateUserWithIdentity creates a user whose email the provider has already
+// verified, linked to the identity that created it.
+func (r *memoryOIDCRepository) CreateUserWithIdentity(ctx context.Context, username string, email string, password string, issuer string, subject string) (*domain.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	// Checked up front so a failure leaves no user behind, as the
+	// transaction does in Postgres.
+	if _, ok := r.store.identities[memoryIdentityKey{issuer: issuer, subject: subject}]; ok {
+		return nil, duplicate("user", "issuer, subject")
+	}
+	user, err := r.store.createUser(username, email, password)
+	if err != nil {
+		return nil, err
+	}
+	r.store.markEmailVerified(user.ID)
+	if err := r.store.linkIdentity(user.ID, issuer, subject, email, "user"); err != nil {
+		return nil, err
+	}
+	return r.store.users[user.ID].toDomain(), nil
+}
+
+func (r *memoryOIDCRepository) UsernameExists(ctx context.Context, username string) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, user := range r.store.users {
+		if user.Username == username {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryOIDCRepository) SaveLoginState(ctx context.Context, stateHash string, codeVerifier string, nonce string, expiresAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.loginStates[stateHash]; ok {
+		return duplicate("login state", "state_hash")
+	}
+	r.store.loginStates[stateHash] = memoryLoginState{
+		codeVerifier: codeVerifier,
+		nonce:        nonce,
+		expiresAt:    expiresAt,
+	}
+	return nil
+}
+
+// ConsumeLoginState deletes a pending login and returns its PKCE verifier and
+// nonce. It returns ErrNotFound when the state is unknown, expired or was
+// already used.
+func (r *memoryOIDCRepository) ConsumeLoginState(ctx context.Context, stateHash string) (string, string, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	state, ok := r.store.loginStates[stateHash]
+	if !ok || !state.expiresAt.After(r.store.now()) {
+		return "", "", notFound("login state")
+	}
+	delete(r.store.loginStates, stateHash)
+	return state.codeVerifier, state.nonce, nil
+}
+
+func (r *memoryOIDCRepository) DeleteExpiredLoginStates(ctx context.Context) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := r.store.now()
+	var deleted int64
+	for hash, state := range r.store.loginStates {
+		if state.expiresAt.Before(now) {
+			delete(r.store.loginStates, hash)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// linkIdentity names resource in its errors. The caller holds s.mu.
+func (s *MemoryStore) linkIdentity(userID int64, issuer, subject, email, resource string) error {
+	if _, ok := s.users[userID]; !ok {
+		return missingReference(resource, "user_id")
+	}
+	key := memoryIdentityKey{issuer: issuer, subject: subject}
+	if _, ok := s.identities[key]; ok {
+		return duplicate(resource, "issuer, subject")
+	}
+	s.identities[key] = memoryIdentity{userID: userID, email: email}
+	return nil
+}
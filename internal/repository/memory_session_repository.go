@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"tasked/internal/domain"
+	"time"
+)
+
+type memorySession struct {
+	domain.Session
+	revokedAt time.Time
+}
+
+type memorySessionRepository struct {
+	store *MemoryStore
+}
+
+func NewMemorySessionRepository(store *MemoryStore) SessionRepository {
+	return &memorySessionRepository{store: store}
+}
+
+func (r *memorySessionRepository) CreateSession(ctx context.Context, id string, userID int64, userAgent string, ip string, expiresAt time.Time) (*domain.Session, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[userID]; !ok {
+		return nil, missingReference("session", "user_id")
+	}
+	if _, ok := r.store.sessions[id]; ok {
+		return nil, duplicate("session", "id")
+	}
+
+	now := r.store.now()
+	session := memorySession{Session: domain.Session{
+		ID:         id,
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  expiresAt,
+	}}
+	r.store.sessions[id] = session
+	return &session.Session, nil
+}
+
+// TouchActiveSession records activity on a session and returns it. It
+// returns ErrNotFound when the session is unknown, expired or revoked.
+func (r *memorySessionRepository) TouchActiveSession(ctx context.Context, id string) (*domain.Session, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := r.store.now()
+	session, ok := r.store.sessions[id]
+	if !ok || !r.store.sessionActive(session, now) {
+		return nil, notFound("session")
+	}
+	session.LastSeenAt = now
+	r.store.sessions[id] = session
+	return &session.Session, nil
+}
+
+func (r *memorySessionRepository) ListActiveSessions(ctx context.Context, userID int64) ([]*domain.Session, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := r.store.now()
+	sessions := make([]*domain.Session, 0)
+	for _, session := range r.store.sessions {
+		if session.UserID == userID && r.store.sessionActive(session, now) {
+			sessions = append(sessions, &session.Session)
+		}
+	}
+	slices.SortFunc(sessions, func(a, b *domain.Session) int {
+		return b.LastSeenAt.Compare(a.LastSeenAt)
+	})
+	return sessions, nil
+}
+
+func (r *memorySessionRepository) RevokeSession(ctx context.Context, id string, userID int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	session, ok := r.store.sessions[id]
+	if !ok || session.UserID != userID || !session.revokedAt.IsZero() {
+		return notFound("session")
+	}
+	session.revokedAt = r.store.now()
+	r.store.sessions[id] = session
+	return nil
+}
+
+// RevokeOtherSessions revokes every session of the user except exceptID and
+// returns the IDs it revoked.
+func (r *memorySessionRepository) RevokeOtherSessions(ctx context.Context, userID int64, exceptID string) ([]string, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := r.store.now()
+	var revoked []string
+	for id, session := range r.store.sessions {
+		if session.UserID != userID || id == exceptID || !session.revokedAt.IsZero() {
+			continue
+		}
+		session.revokedAt = now
+		r.store.sessions[id] = session
+		revoked = append(revoked, id)
+	}
+	return revoked, nil
+}
+
+// DeleteStaleSessions removes expired sessions and those revoked before
+// revokedBefore.
+func (r *memorySessionRepository) DeleteStaleSessions(ctx context.Context, revokedBefore time.Time) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := r.store.now()
+	var deleted int64
+	for id, session := range r.store.sessions {
+		revoked := !session.revokedAt.IsZero() && session.revokedAt.Before(revokedBefore)
+		if session.ExpiresAt.Before(now) || revoked {
+			delete(r.store.sessions, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// sessionActive reports whether the session is neither revoked nor expired.
+// Sessions created before the user's last password change count as revoked.
+// The caller holds s.mu.
+func (s *MemoryStore) sessionActive(session memorySession, now time.Time) bool {
+	if !session.revokedAt.IsZero() || !session.ExpiresAt.After(now) {
+		return false
+	}
+	user, ok := s.users[session.UserID]
+	return ok && !session.CreatedAt.Before(user.sessionsRevokedAt)
+}
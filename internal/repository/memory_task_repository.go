@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"tasked/internal/domain"
+	"time"
+)
+
+type memoryTask struct {
+	domain.Task
+	createdAt time.Time
+}
+
+type memoryTaskRepository struct {
+	store *MemoryStore
+	// inTx marks the repository WithTx hands to fn, whose calls run with
+	// store.mu already held.
+	inTx bool
+}
+
+func NewMemoryTaskRepository(store *MemoryStore) TaskRepository {
+	return &memoryTaskRepository{store: store}
+}
+
+func (r *memoryTaskRepository) lock() (unlock func()) {
+	if r.inTx {
+		return func() {}
+	}
+	r.store.mu.Lock()
+	return r.store.mu.Unlock
+}
+
+// WithTx runs fn holding the store lock and restores the tasks when fn
+// fails. fn must only use tx: other repositories of the same store would
+// wait for the lock forever.
+func (r *memoryTaskRepository) WithTx(ctx context.Context, fn func(tx TaskTx) error) error {
+	if r.inTx {
+		return fn(r)
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	snapshot := maps.Clone(r.store.tasks)
+	if err := fn(&memoryTaskRepository{store: r.store, inTx: true}); err != nil {
+		r.store.tasks = snapshot
+		return err
+	}
+	return nil
+}
+
+func (r *memoryTaskRepository) Savepoint(ctx context.Context, fn func() error) error {
+	if !r.inTx {
+		return fn()
+	}
+
+	snapshot := maps.Clone(r.store.tasks)
+	if err := fn(); err != nil {
+		r.store.tasks = snapshot
+		return err
+	}
+	return nil
+}
+
+func (r *memoryTaskRepository) GetTaskById(ctx context.Context, id int64) (*domain.Task, error) {
+	defer r.lock()()
+
+	task, ok := r.store.tasks[id]
+	if !ok {
+		return nil, notFound("task")
+	}
+	return &task.Task, nil
+}
+
+func (r *memoryTaskRepository) ListTaskByUser(ctx context.Context, id int64) ([]domain.Task, error) {
+	defer r.lock()()
+
+	var owned []memoryTask
+	for _, task := range r.store.tasks {
+		if task.Userid == id {
+			owned = append(owned, task)
+		}
+	}
+	// Newest first, like ORDER BY created_at DESC, id DESC.
+	slices.SortFunc(owned, func(a, b memoryTask) int {
+		if c := b.createdAt.Compare(a.createdAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.Id, a.Id)
+	})
+
+	tasks := make([]domain.Task, 0, len(owned))
+	for _, task := range owned {
+		tasks = append(tasks, task.Task)
+	}
+	return tasks, nil
+}
+
+func (r *memoryTaskRepository) UpdateTask(ctx context.Context, id int64, version int64, title string, description string, status string, priority string, dueDate string) (*domain.Task, error) {
+	due, err := parseDueDate(dueDate)
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.lock()()
+
+	task, ok := r.store.tasks[id]
+	if !ok || version != 0 && task.Version != version {
+		return nil, notFound("task")
+	}
+	task.Title = title
+	task.Description = description
+	task.Status = status
+	task.Priority = priority
+	task.Duedate = due
+	return r.save(task), nil
+}
+
+func (r *memoryTaskRepository) PatchTask(ctx context.Context, id int64, version int64, patch domain.TaskPatch) (*domain.Task, error) {
+	var due time.Time
+	if patch.DueDate != nil {
+		parsed, err := time.Parse("2006-01-02", *patch.DueDate)
+		if err != nil {
+			return nil, invalidDueDate(err)
+		}
+		due = parsed
+	}
+
+	defer r.lock()()
+
+	task, ok := r.store.tasks[id]
+	if !ok || version != 0 && task.Version != version {
+		return nil, notFound("task")
+	}
+	if patch.Title != nil {
+		task.Title = *patch.Title
+	}
+	if patch.Status != nil {
+		task.Status = *patch.Status
+	}
+	if patch.SetDescription {
+		task.Description = valueOrEmpty(patch.Description)
+	}
+	if patch.SetPriority {
+		task.Priority = valueOrEmpty(patch.Priority)
+	}
+	if patch.SetDueDate {
+		task.Duedate = due
+	}
+	return r.save(task), nil
+}
+
+func (r *memoryTaskRepository) DeleteTask(ctx context.Context, id int64, version int64) error {
+	defer r.lock()()
+
+	task, ok := r.store.tasks[id]
+	if !ok || version != 0 && task.Version != version {
+		return notFound("task")
+	}
+	delete(r.store.tasks, id)
+	return nil
+}
+
+func (r *memoryTaskRepository) UpdateStatus(ctx context.Context, id int64, version int64, status string) (*domain.Task, error) {
+	defer r.lock()()
+
+	task, ok := r.store.tasks[id]
+	if !ok || version != 0 && task.Version != version {
+		return nil, notFound("task")
+	}
+	task.Status = status
+	return r.save(task), nil
+}
+
+func (r *memoryTaskRepository) CreateTask(ctx context.Context, title string, description string, status string, priority string, userId int64, dueDate string) (*domain.Task, error) {
+	due, err := parseDueDate(dueDate)
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.lock()()
+
+	if _, ok := r.store.users[userId]; !ok {
+		return nil, missingReference("task", "user_id")
+	}
+
+	now := r.store.now()
+	r.store.lastTaskID++
+	task := memoryTask{
+		Task: domain.Task{
+			Id:          r.store.lastTaskID,
+			Title:       title,
+			Description: description,
+			Status:      status,
+			Priority:    priority,
+			Userid:      userId,
+			Duedate:     due,
+			UpdatedAt:   now,
+			Version:     1,
+		},
+		createdAt: now,
+	}
+	r.store.tasks[task.Id] = task
+	return &task.Task, nil
+}
+
+func (r *memoryTaskRepository) GetTaskStats(ctx context.Context) (*domain.TaskStats, error) {
+	defer r.lock()()
+
+	now := r.store.now()
+	stats := &domain.TaskStats{ByStatus: make(map[string]int64)}
+	for _, task := range r.store.tasks {
+		stats.ByStatus[task.Status]++
+		if !task.Duedate.IsZero() && task.Duedate.Before(now) && task.Status != "completed" {
+			stats.Overdue++
+		}
+	}
+	return stats, nil
+}
+
+// save stores an updated task with a new version. The caller holds the
+// store lock.
+func (r *memoryTaskRepository) save(task memoryTask) *domain.Task {
+	task.UpdatedAt = r.store.now()
+	task.Version++
+	r.store.tasks[task.Id] = task
+	return &task.Task
+}
+
+// parseDueDate reads a YYYY-MM-DD date; the empty string means no due date.
+func parseDueDate(dueDate string) (time.Time, error) {
+	if dueDate == "" {
+		return time.Time{}, nil
+	}
+	due, err := time.Parse("2006-01-02", dueDate)
+	if err != nil {
+		return time.Time{}, invalidDueDate(err)
+	}
+	return due, nil
+}
+
+func valueOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
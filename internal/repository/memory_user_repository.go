@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"tasked/internal/domain"
+	"time"
+)
+
+type memoryUser struct {
+	domain.User
+	sessionsRevokedAt time.Time
+	mfa               domain.UserMFA
+	// usedStep tells a LastStep of 0 apart from no step used yet.
+	usedStep bool
+}
+
+func (u memoryUser) toDomain() *domain.User {
+	user := u.User
+	user.MFAEnabled = !u.mfa.EnabledAt.IsZero()
+	return &user
+}
+
+type memoryUserRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryUserRepository(store *MemoryStore) UserRepository {
+	return &memoryUserRepository{store: store}
+}
+
+func (r *memoryUserRepository) GetUserById(ctx context.Context, id int64) (*domain.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[id]
+	if !ok {
+		return nil, notFound("user")
+	}
+	return user.toDomain(), nil
+}
+
+func (r *memoryUserRepository) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, user := range r.store.users {
+		if user.Email == email {
+			return user.toDomain(), nil
+		}
+	}
+	return nil, notFound("user")
+}
+
+func (r *memoryUserRepository) CreateUser(ctx context.Context, username string, email string, password string) (*domain.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, err := r.store.createUser(username, email, password)
+	if err != nil {
+		return nil, err
+	}
+	return user.toDomain(), nil
+}
+
+func (r *memoryUserRepository) UpdateUser(ctx context.Context, id int64, version int64, username string, email string) (*domain.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[id]
+	if !ok || version != 0 && user.Version != version {
+		return nil, notFound("user")
+	}
+	if err := r.store.checkUserUnique(id, username, email); err != nil {
+		return nil, err
+	}
+
+	if user.Email != email {
+		user.EmailVerifiedAt = time.Time{}
+	}
+	user.Username = username
+	user.Email = email
+	user.UpdatedAt = r.store.now()
+	user.Version++
+	r.store.users[id] = user
+	return user.toDomain(), nil
+}
+
+func (r *memoryUserRepository) DeleteUser(ctx context.Context, id int64, version int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[id]
+	if !ok || version != 0 && user.Version != version {
+		return notFound("user")
+	}
+	r.store.deleteUser(id)
+	return nil
+}
+
+func (r *memoryUserRepository) UpdatePassword(ctx context.Context, id int64, password string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[id]
+	if !ok {
+		return nil
+	}
+	now := r.store.now()
+	user.Password = password
+	user.sessionsRevokedAt = now
+	user.UpdatedAt = now
+	user.Version++
+	r.store.users[id] = user
+	return nil
+}
+
+func (r *memoryUserRepository) MarkEmailVerified(ctx context.Context, id int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.markEmailVerified(id)
+	return nil
+}
+
+// createUser inserts a user after the checks the users table constraints
+// make. The caller holds s.mu.
+func (s *MemoryStore) createUser(username, email, password string) (memoryUser, error) {
+	if err := s.checkUserUnique(0, username, email); err != nil {
+		return memoryUser{}, err
+	}
+
+	now := s.now()
+	s.lastUserID++
+	user := memoryUser{User: domain.User{
+		ID:        s.lastUserID,
+		Username:  username,
+		Email:     email,
+		Password:  password,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Version:   1,
+	}}
+	s.users[user.ID] = user
+	return user, nil
+}
+
+// checkUserUnique reports a conflict when a user other than id already has
+// the username or email. The caller holds s.mu.
+func (s *MemoryStore) checkUserUnique(id int64, username, email string) error {
+	for _, user := range s.users {
+		if user.ID != id && user.Username == username {
+			return duplicate("user", "username")
+		}
+	}
+	for _, user := range s.users {
+		if user.ID != id && user.Email == email {
+			return duplicate("user", "email")
+		}
+	}
+	return nil
+}
+
+// markEmailVerified keeps the first verification time. The caller holds
+// s.mu.
+func (s *MemoryStore) markEmailVerified(id int64) {
+	user, ok := s.users[id]
+	if !ok {
+		return
+	}
+	now := s.now()
+	if user.EmailVerifiedAt.IsZero() {
+		user.EmailVerifiedAt = now
+	}
+	user.UpdatedAt = now
+	user.Version++
+	s.users[id] = user
+}
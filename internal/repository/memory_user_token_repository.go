@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+type memoryUserToken struct {
+	userID    int64
+	purpose   string
+	expiresAt time.Time
+	used      bool
+}
+
+type memoryUserTokenRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryUserTokenRepository(store *MemoryStore) UserTokenRepository {
+	return &memoryUserTokenRepository{store: store}
+}
+
+func (r *memoryUserTokenRepository) CreateToken(ctx context.Context, userID int64, purpose string, tokenHash string, expiresAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[userID]; !ok {
+		return missingReference("token", "user_id")
+	}
+	if _, ok := r.store.userTokens[tokenHash]; ok {
+		return duplicate("token", "token_hash")
+	}
+	r.store.userTokens[tokenHash] = memoryUserToken{
+		userID:    userID,
+		purpose:   purpose,
+		expiresAt: expiresAt,
+	}
+	return nil
+}
+
+// FindToken returns the owner of a valid, unused token without spending it.
+// It returns ErrNotFound when the token is unknown, expired or spent.
+func (r *memoryUserTokenRepository) FindToken(ctx context.Context, purpose string, tokenHash string) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	token, ok := r.store.userTokens[tokenHash]
+	if !ok || token.purpose != purpose || token.used || !token.expiresAt.After(r.store.now()) {
+		return 0, notFound("token")
+	}
+	return token.userID, nil
+}
+
+// ConsumeToken marks a valid, unused token as used and returns its owner.
+// It returns ErrNotFound when the token is unknown, expired or spent.
+func (r *memoryUserTokenRepository) ConsumeToken(ctx context.Context, purpose string, tokenHash string) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	token, ok := r.store.userTokens[tokenHash]
+	if !ok || token.purpose != purpose || token.used || !token.expiresAt.After(r.store.now()) {
+		return 0, notFound("token")
+	}
+	token.used = true
+	r.store.userTokens[tokenHash] = token
+	return token.userID, nil
+}
+
+func (r *memoryUserTokenRepository) DeleteTokens(ctx context.Context, userID int64, purpose string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for hash, token := range r.store.userTokens {
+		if token.userID == userID && token.purpose == purpose {
+			delete(r.store.userTokens, hash)
+		}
+	}
+	return nil
+}
+
+func (r *memoryUserTokenRepository) DeleteExpiredTokens(ctx context.Context) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := r.store.now()
+	var deleted int64
+	for hash, token := range r.store.userTokens {
+		if token.expiresAt.Before(now) {
+			delete(r.store.userTokens, hash)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"tasked/internal/database"
+	"tasked/internal/domain"
+)
+
+type MFARepository interface {
+	GetMFA(ctx context.Context, userID int64) (*domain.UserMFA, error)
+	SetPendingSecret(ctx context.Context, userID int64, secret string) error
+	EnableMFA(ctx context.Context, userID int64, recoveryCodeHashes []string) error
+	DisableMFA(ctx context.Context, userID int64) error
+	UseTOTPStep(ctx context.Context, userID int64, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error)
+}
+
+type mfaRepository struct {
+	db      *sql.DB
+	queries *database.Queries
+}
+
+func NewMFARepository(db *sql.DB) MFARepository {
+	return &mfaRepository{
+		db:      db,
+		queries: database.New(db),
+	}
+}
+
+func (r *mfaRepository) GetMFA(ctx context.Context, userID int64) (*domain.UserMFA, error) {
+	row, err := r.queries.GetUserMFA(ctx, userID)
+	if err != nil {
+		return nil, dbError(err, "user")
+	}
+	return &domain.UserMFA{
+		Secret:    row.TotpSecret.String,
+		EnabledAt: row.TotpEnabledAt.Time,
+		LastStep:  row.TotpLastStep.Int64,
+	}, nil
+}
+
+// SetPendingSecret starts a new enrollment, replacing any previous one.
+func (r *mfaRepository) SetPendingSecret(ctx context.Context, userID int64, secret string) error {
+	return r.queries.SetUserTOTPSecret(ctx, database.SetUserTOTPSecretParams{
+		ID: userID,
+		TotpSecret: sql.NullString{
+			String: secret,
+			Valid:  true,
+		},
+	})
+}
+
+// EnableMFA confirms the pending enrollment and replaces the recovery codes.
+func (r *mfaRepository) EnableMFA(ctx context.Context, userID int64, recoveryCodeHashes []string) error {
+	return r.inTx(ctx, func(q *database.Queries) error {
+		if err := q.EnableUserTOTP(ctx, userID); err != nil {
+			return err
+		}
+		if err := q.DeleteRecoveryCodes(ctx, userID); err != nil {
+			return err
+		}
+		for _, hash := range recoveryCodeHashes {
+			err := q.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+				UserID:   userID,
+				CodeHash: hash,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *mfaRepository) DisableMFA(ctx context.Context, userID int64) error {
+	return r.inTx(ctx, func(q *database.Queries) error {
+		if err := q.DisableUserTOTP(ctx, userID); err != nil {
+			return err
+		}
+		return q.DeleteRecoveryCodes(ctx, userID)
+	})
+}
+
+// UseTOTPStep records step as used. It reports false when that step, or a
+// later one, was already used, which means the code is being replayed.
+func (r *mfaRepository) UseTOTPStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	rows, err := r.queries.UseUserTOTPStep(ctx, database.UseUserTOTPStepParams{
+		ID:   userID,
+		Step: step,
+	})
+	if err != nil {
+		return false, dbError(err, "user")
+	}
+	return rows == 1, nil
+}
+
+func (r *mfaRepository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	rows, err := r.queries.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+		UserID:   userID,
+		CodeHash: codeHash,
+	})
+	if err != nil {
+		return false, dbError(err, "user")
+	}
+	return rows > 0, nil
+}
+
+func (r *mfaRepository) inTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(r.queries.WithTx(tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"tasked/internal/database"
+	"tasked/internal/domain"
+	"time"
+)
+
+type OIDCRepository interface {
+	GetUserByIdentity(ctx context.Context, issuer string, subject string) (*domain.User, error)
+	LinkIdentity(ctx context.Context, userID int64, issuer string, subject string, email string) error
+	CreateUserWithIdentity(ctx context.Context, username string, email string, password string, issuer string, subject string) (*domain.User, error)
+	UsernameExists(ctx context.Context, username string) (bool, error)
+	SaveLoginState(ctx context.Context, stateHash string, codeVerifier string, nonce string, expiresAt time.Time) error
+	ConsumeLoginState(ctx context.Context, stateHash string) (codeVerifier string, nonce string, err error)
+	DeleteExpiredLoginStates(ctx context.Context) (int64, error)
+}
+
+type oidcRepository struct {
+	db      *sql.DB
+	queries *database.Queries
+}
+
+func NewOIDCRepository(db *sql.DB) OIDCRepository {
+	return &oidcRepository{
+		db:      db,
+		queries: database.New(db),
+	}
+}
+
+func (r *oidcRepository) GetUserByIdentity(ctx context.Context, issuer string, subject string) (*domain.User, error) {
+	dbUser, err := r.queries.GetUserByIdentity(ctx, database.GetUserByIdentityParams{
+		Issuer:  issuer,
+		Subject: subject,
+	})
+	if err != nil {
+		return nil, dbError(err, "identity")
+	}
+	return toDomainUser(dbUser), nil
+}
+
+func (r *oidcRepository) LinkIdentity(ctx context.Context, userID int64, issuer string, subject string, email string) error {
+	err := r.queries.CreateUserIdentity(ctx, database.CreateUserIdentityParams{
+		UserID:  userID,
+		Issuer:  issuer,
+		Subject: subject,
+		Email:   email,
+	})
+	return dbError(err, "identity")
+}
+
+// CreateUserWithIdentity creates a user whose email the provider has already
+// verified, linked to the identity that created it.
+func (r *oidcRepository) CreateUserWithIdentity(ctx context.Context, username string, email string, password string, issuer string, subject string) (*domain.User, error) {
+	var user *domain.User
+	err := r.inTx(ctx, func(q *database.Queries) error {
+		dbUser, err := q.CreateUser(ctx, database.CreateUserParams{
+			Username: username,
+			Email:    email,
+			Password: password,
+		})
+		if err != nil {
+			return err
+		}
+		if err := q.MarkUserEmailVerified(ctx, dbUser.ID); err != nil {
+			return err
+		}
+		err = q.CreateUserIdentity(ctx, database.CreateUserIdentityParams{
+			UserID:  dbUser.ID,
+			Issuer:  issuer,
+			Subject: subject,
+			Email:   email,
+		})
+		if err != nil {
+			return err
+		}
+		dbUser, err = q.GetUserByID(ctx, dbUser.ID)
+		if err != nil {
+			return err
+		}
+		user = toDomainUser(dbUser)
+		return nil
+	})
+	return user, dbError(err, "user")
+}
+
+func (r *oidcRepository) UsernameExists(ctx context.Context, username string) (bool, error) {
+	return r.queries.UsernameExists(ctx, username)
+}
+
+func (r *oidcRepository) SaveLoginState(ctx context.Context, stateHash string, codeVerifier string, nonce string, expiresAt time.Time) error {
+	return r.queries.CreateOIDCLoginState(ctx, database.CreateOIDCLoginStateParams{
+		StateHash:    stateHash,
+		CodeVerifier: codeVerifier,
+		Nonce:        nonce,
+		ExpiresAt:    expiresAt,
+	})
+}
+
+// ConsumeLoginState deletes a pending login and returns its PKCE verifier and
+// nonce. It returns ErrNotFound when the state is unknown, expired or was
+// already used.
+func (r *oidcRepository) ConsumeLoginState(ctx context.Context, stateHash string) (string, string, error) {
+	row, err := r.queries.ConsumeOIDCLoginState(ctx, stateHash)
+	if err != nil {
+		return "", "", dbError(err, "login state")
+	}
+	return row.CodeVerifier, row.Nonce, nil
+}
+
+func (r *oidcRepository) DeleteExpiredLoginStates(ctx context.Context) (int64, error) {
+	return r.queries.DeleteExpiredOIDCLoginStates(ctx)
+}
+
+func (r *oidcRepository) inTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(r.queries.WithTx(tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,29 @@
+package repository
+
+import "database/sql"
+
+// Repositories holds one implementation of every repository, all backed by
+// the same storage.
+type Repositories struct {
+	Users        UserRepository
+	UserTokens   UserTokenRepository
+	Sessions     SessionRepository
+	MFA          MFARepository
+	OIDC         OIDCRepository
+	AccessTokens AccessTokenRepository
+	Tasks        TaskRepository
+	Idempotency  IdempotencyRepository
+}
+
+func NewPostgresRepositories(db *sql.DB) *Repositories {
+	return &Repositories{
+		Users:        NewUserRepository(db),
+		UserTokens:   NewUserTokenRepository(db),
+		Sessions:     NewSessionRepository(db),
+		MFA:          NewMFARepository(db),
+		OIDC:         NewOIDCRepository(db),
+		AccessTokens: NewAccessTokenRepository(db),
+		Tasks:        NewTaskRepository(db),
+		Idempotency:  NewIdempotencyRepository(db),
+	}
+}
@@ -0,0 +1,472 @@
+// Package repotest is the conformance suite for the repository interfaces.
+// Every storage backend runs it, so they all keep the contract the services
+// rely on: the same results, versions and application errors.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"tasked/internal/domain"
+	apperrors "tasked/internal/errors"
+	"tasked/internal/repository"
+	"testing"
+	"time"
+)
+
+// Factory returns repositories backed by fresh, empty storage.
+type Factory func(t *testing.T) *repository.Repositories
+
+// Run runs the whole suite against the repositories newRepos returns.
+func Run(t *testing.T, newRepos Factory) {
+	t.Run("UserRepository", func(t *testing.T) { TestUserRepository(t, newRepos) })
+	t.Run("TaskRepository", func(t *testing.T) { TestTaskRepository(t, newRepos) })
+}
+
+func TestUserRepository(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		users := newRepos(t).Users
+		created := mustCreateUser(t, users, "ana")
+		if created.ID <= 0 || created.Version != 1 || created.CreatedAt.IsZero() {
+			t.Fatalf("CreateUser = %+v, want an ID, version 1 and a creation time", created)
+		}
+		if created.Username != "ana" || created.Email != "ana@example.com" || created.Password != "hash-ana" {
+			t.Fatalf("CreateUser = %+v, want the given username, email and password", created)
+		}
+
+		byID, err := users.GetUserById(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("GetUserById: %v", err)
+		}
+		byEmail, err := users.GetUserByEmail(ctx, "ana@example.com")
+		if err != nil {
+			t.Fatalf("GetUserByEmail: %v", err)
+		}
+		for _, got := range []*domain.User{byID, byEmail} {
+			if got.ID != created.ID || got.Username != created.Username || got.Version != created.Version {
+				t.Fatalf("got %+v, want %+v", got, created)
+			}
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		users := newRepos(t).Users
+		_, err := users.GetUserById(ctx, 999)
+		wantError(t, err, apperrors.ErrNotFound)
+		_, err = users.GetUserByEmail(ctx, "nobody@example.com")
+		wantError(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("DuplicateUsernameOrEmail", func(t *testing.T) {
+		users := newRepos(t).Users
+		mustCreateUser(t, users, "ana")
+		_, err := users.CreateUser(ctx, "other", "ana@example.com", "hash-other")
+		wantError(t, err, apperrors.ErrConflict)
+		wantField(t, err, "email")
+		_, err = users.CreateUser(ctx, "ana", "other@example.com", "hash-other")
+		wantError(t, err, apperrors.ErrConflict)
+		wantField(t, err, "username")
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		users := newRepos(t).Users
+		user := mustCreateUser(t, users, "ana")
+		if err := users.MarkEmailVerified(ctx, user.ID); err != nil {
+			t.Fatalf("MarkEmailVerified: %v", err)
+		}
+		user = mustGetUser(t, users, user.ID)
+		if user.EmailVerifiedAt.IsZero() || user.Version != 2 {
+			t.Fatalf("after MarkEmailVerified got %+v, want a verified email and version 2", user)
+		}
+
+		updated, err := users.UpdateUser(ctx, user.ID, user.Version, "ana2", "ana2@example.com")
+		if err != nil {
+			t.Fatalf("UpdateUser: %v", err)
+		}
+		if updated.Username != "ana2" || updated.Email != "ana2@example.com" || updated.Version != 3 {
+			t.Fatalf("UpdateUser = %+v, want the new username and email at version 3", updated)
+		}
+		if !updated.EmailVerifiedAt.IsZero() {
+			t.Fatalf("UpdateUser kept the verification of the old email")
+		}
+
+		_, err = users.UpdateUser(ctx, user.ID, user.Version, "ana3", "ana3@example.com")
+		wantError(t, err, apperrors.ErrNotFound)
+
+		mustCreateUser(t, users, "bea")
+		_, err = users.UpdateUser(ctx, user.ID, updated.Version, "ana2", "bea@example.com")
+		wantError(t, err, apperrors.ErrConflict)
+
+		anyVersion, err := users.UpdateUser(ctx, user.ID, 0, "ana4", "ana4@example.com")
+		if err != nil || anyVersion.Version != updated.Version+1 {
+			t.Fatalf("UpdateUser at version 0 = %+v, %v, want version %d", anyVersion, err, updated.Version+1)
+		}
+	})
+
+	t.Run("UpdatePassword", func(t *testing.T) {
+		users := newRepos(t).Users
+		user := mustCreateUser(t, users, "ana")
+		if err := users.UpdatePassword(ctx, user.ID, "hash-new"); err != nil {
+			t.Fatalf("UpdatePassword: %v", err)
+		}
+		got := mustGetUser(t, users, user.ID)
+		if got.Password != "hash-new" || got.Version != user.Version+1 {
+			t.Fatalf("after UpdatePassword got %+v, want the new password and a new version", got)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		users := newRepos(t).Users
+		user := mustCreateUser(t, users, "ana")
+		wantError(t, users.DeleteUser(ctx, user.ID, user.Version+1), apperrors.ErrNotFound)
+		if err := users.DeleteUser(ctx, user.ID, user.Version); err != nil {
+			t.Fatalf("DeleteUser: %v", err)
+		}
+		_, err := users.GetUserById(ctx, user.ID)
+		wantError(t, err, apperrors.ErrNotFound)
+		wantError(t, users.DeleteUser(ctx, user.ID, user.Version), apperrors.ErrNotFound)
+		wantError(t, users.DeleteUser(ctx, user.ID, 0), apperrors.ErrNotFound)
+
+		other := mustCreateUser(t, users, "bea")
+		if err := users.DeleteUser(ctx, other.ID, 0); err != nil {
+			t.Fatalf("DeleteUser at version 0: %v", err)
+		}
+	})
+}
+
+func TestTaskRepository(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		repos := newRepos(t)
+		user := mustCreateUser(t, repos.Users, "ana")
+		created, err := repos.Tasks.CreateTask(ctx, "Write docs", "For the API", "pending", "high", user.ID, "2030-01-02")
+		if err != nil {
+			t.Fatalf("CreateTask: %v", err)
+		}
+		want := domain.Task{
+			Title:       "Write docs",
+			Description: "For the API",
+			Status:      "pending",
+			Priority:    "high",
+			Userid:      user.ID,
+			Duedate:     time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC),
+			Version:     1,
+		}
+		if created.Id <= 0 || created.UpdatedAt.IsZero() {
+			t.Fatalf("CreateTask = %+v, want an ID and an update time", created)
+		}
+		wantTask(t, created, want)
+
+		got, err := repos.Tasks.GetTaskById(ctx, created.Id)
+		if err != nil {
+			t.Fatalf("GetTaskById: %v", err)
+		}
+		wantTask(t, got, want)
+	})
+
+	t.Run("CreateErrors", func(t *testing.T) {
+		repos := newRepos(t)
+		user := mustCreateUser(t, repos.Users, "ana")
+		_, err := repos.Tasks.CreateTask(ctx, "Bad date", "", "", "", user.ID, "02/01/2030")
+		wantError(t, err, apperrors.ErrBadRequest)
+		_, err = repos.Tasks.CreateTask(ctx, "No owner", "", "", "", user.ID+100, "")
+		wantError(t, err, apperrors.ErrBadRequest)
+		_, err = repos.Tasks.GetTaskById(ctx, 999)
+		wantError(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("ListNewestFirst", func(t *testing.T) {
+		repos := newRepos(t)
+		ana := mustCreateUser(t, repos.Users, "ana")
+		bea := mustCreateUser(t, repos.Users, "bea")
+		first := mustCreateTask(t, repos.Tasks, ana.ID, "first")
+		mustCreateTask(t, repos.Tasks, bea.ID, "other")
+		second := mustCreateTask(t, repos.Tasks, ana.ID, "second")
+
+		tasks, err := repos.Tasks.ListTaskByUser(ctx, ana.ID)
+		if err != nil {
+			t.Fatalf("ListTaskByUser: %v", err)
+		}
+		if len(tasks) != 2 || tasks[0].Id != second.Id || tasks[1].Id != first.Id {
+			t.Fatalf("ListTaskByUser = %+v, want tasks %d and %d", tasks, second.Id, first.Id)
+		}
+
+		none, err := repos.Tasks.ListTaskByUser(ctx, ana.ID+bea.ID)
+		if err != nil || none == nil || len(none) != 0 {
+			t.Fatalf("ListTaskByUser for a user without tasks = %v, %v, want an empty list", none, err)
+		}
+	})
+
+	t.Run("UpdateChecksVersion", func(t *testing.T) {
+		repos := newRepos(t)
+		user := mustCreateUser(t, repos.Users, "ana")
+		task := mustCreateTask(t, repos.Tasks, user.ID, "draft")
+
+		updated, err := repos.Tasks.UpdateTask(ctx, task.Id, task.Version, "final", "done soon", "in_progress", "low", "")
+		if err != nil {
+			t.Fatalf("UpdateTask: %v", err)
+		}
+		wantTask(t, updated, domain.Task{
+			Title:       "final",
+			Description: "done soon",
+			Status:      "in_progress",
+			Priority:    "low",
+			Userid:      user.ID,
+			Version:     2,
+		})
+
+		_, err = repos.Tasks.UpdateTask(ctx, task.Id, task.Version, "stale", "", "", "", "")
+		wantError(t, err, apperrors.ErrNotFound)
+
+		status, err := repos.Tasks.UpdateStatus(ctx, task.Id, updated.Version, "completed")
+		if err != nil {
+			t.Fatalf("UpdateStatus: %v", err)
+		}
+		if status.Status != "completed" || status.Title != "final" || status.Version != 3 {
+			t.Fatalf("UpdateStatus = %+v, want status completed at version 3", status)
+		}
+		_, err = repos.Tasks.UpdateStatus(ctx, task.Id, updated.Version, "pending")
+		wantError(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("Patch", func(t *testing.T) {
+		repos := newRepos(t)
+		user := mustCreateUser(t, repos.Users, "ana")
+		task, err := repos.Tasks.CreateTask(ctx, "title", "description", "pending", "high", user.ID, "2030-01-02")
+		if err != nil {
+			t.Fatalf("CreateTask: %v", err)
+		}
+
+		title := "renamed"
+		patched, err := repos.Tasks.PatchTask(ctx, task.Id, task.Version, domain.TaskPatch{Title: &title})
+		if err != nil {
+			t.Fatalf("PatchTask: %v", err)
+		}
+		wantTask(t, patched, domain.Task{
+			Title:       "renamed",
+			Description: "description",
+			Status:      "pending",
+			Priority:    "high",
+			Userid:      user.ID,
+			Duedate:     time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC),
+			Version:     2,
+		})
+
+		cleared, err := repos.Tasks.PatchTask(ctx, task.Id, patched.Version, domain.TaskPatch{
+			SetDescription: true,
+			SetPriority:    true,
+			SetDueDate:     true,
+		})
+		if err != nil {
+			t.Fatalf("PatchTask: %v", err)
+		}
+		wantTask(t, cleared, domain.Task{
+			Title:   "renamed",
+			Status:  "pending",
+			Userid:  user.ID,
+			Version: 3,
+		})
+
+		badDate := "tomorrow"
+		_, err = repos.Tasks.PatchTask(ctx, task.Id, cleared.Version, domain.TaskPatch{SetDueDate: true, DueDate: &badDate})
+		wantError(t, err, apperrors.ErrBadRequest)
+		_, err = repos.Tasks.PatchTask(ctx, task.Id, patched.Version, domain.TaskPatch{Title: &title})
+		wantError(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("DeleteChecksVersion", func(t *testing.T) {
+		repos := newRepos(t)
+		user := mustCreateUser(t, repos.Users, "ana")
+		task := mustCreateTask(t, repos.Tasks, user.ID, "temporary")
+
+		wantError(t, repos.Tasks.DeleteTask(ctx, task.Id, task.Version+1), apperrors.ErrNotFound)
+		if err := repos.Tasks.DeleteTask(ctx, task.Id, task.Version); err != nil {
+			t.Fatalf("DeleteTask: %v", err)
+		}
+		_, err := repos.Tasks.GetTaskById(ctx, task.Id)
+		wantError(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("VersionZeroMatchesAny", func(t *testing.T) {
+		repos := newRepos(t)
+		user := mustCreateUser(t, repos.Users, "ana")
+		task := mustCreateTask(t, repos.Tasks, user.ID, "unversioned")
+
+		if _, err := repos.Tasks.UpdateTask(ctx, task.Id, 0, "first", "", "", "", ""); err != nil {
+			t.Fatalf("UpdateTask at version 0: %v", err)
+		}
+		title := "second"
+		if _, err := repos.Tasks.PatchTask(ctx, task.Id, 0, domain.TaskPatch{Title: &title}); err != nil {
+			t.Fatalf("PatchTask at version 0: %v", err)
+		}
+		status, err := repos.Tasks.UpdateStatus(ctx, task.Id, 0, "completed")
+		if err != nil {
+			t.Fatalf("UpdateStatus at version 0: %v", err)
+		}
+		if status.Title != "second" || status.Version != task.Version+3 {
+			t.Fatalf("after three writes at version 0 got %s", describeTask(*status))
+		}
+		if err := repos.Tasks.DeleteTask(ctx, task.Id, 0); err != nil {
+			t.Fatalf("DeleteTask at version 0: %v", err)
+		}
+		wantError(t, repos.Tasks.DeleteTask(ctx, task.Id, 0), apperrors.ErrNotFound)
+	})
+
+	t.Run("DeletingUserDeletesTasks", func(t *testing.T) {
+		repos := newRepos(t)
+		user := mustCreateUser(t, repos.Users, "ana")
+		task := mustCreateTask(t, repos.Tasks, user.ID, "owned")
+		if err := repos.Users.DeleteUser(ctx, user.ID, user.Version); err != nil {
+			t.Fatalf("DeleteUser: %v", err)
+		}
+		_, err := repos.Tasks.GetTaskById(ctx, task.Id)
+		wantError(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		repos := newRepos(t)
+		user := mustCreateUser(t, repos.Users, "ana")
+		for _, task := range []struct{ status, dueDate string }{
+			{"pending", "2000-01-01"},
+			{"pending", "2999-01-01"},
+			{"completed", "2000-01-01"},
+			{"", ""},
+		} {
+			_, err := repos.Tasks.CreateTask(ctx, "task", "", task.status, "", user.ID, task.dueDate)
+			if err != nil {
+				t.Fatalf("CreateTask: %v", err)
+			}
+		}
+
+		stats, err := repos.Tasks.GetTaskStats(ctx)
+		if err != nil {
+			t.Fatalf("GetTaskStats: %v", err)
+		}
+		if stats.Overdue != 1 || stats.ByStatus["pending"] != 2 || stats.ByStatus["completed"] != 1 || stats.ByStatus[""] != 1 {
+			t.Fatalf("GetTaskStats = %+v, want 1 overdue, 2 pending, 1 completed and 1 without status", stats)
+		}
+	})
+
+	t.Run("WithTxRollsBack", func(t *testing.T) {
+		repos := newRepos(t)
+		user := mustCreateUser(t, repos.Users, "ana")
+		kept := mustCreateTask(t, repos.Tasks, user.ID, "kept")
+
+		errAbort := errors.New("abort")
+		var created *domain.Task
+		err := repos.Tasks.WithTx(ctx, func(tx repository.TaskTx) error {
+			var err error
+			created, err = tx.CreateTask(ctx, "rolled back", "", "", "", user.ID, "")
+			if err != nil {
+				return err
+			}
+			if err := tx.DeleteTask(ctx, kept.Id, kept.Version); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Fatalf("WithTx = %v, want the error fn returned", err)
+		}
+		_, err = repos.Tasks.GetTaskById(ctx, created.Id)
+		wantError(t, err, apperrors.ErrNotFound)
+		if _, err := repos.Tasks.GetTaskById(ctx, kept.Id); err != nil {
+			t.Fatalf("task deleted in a rolled back transaction: %v", err)
+		}
+	})
+
+	t.Run("SavepointRollsBackOneStep", func(t *testing.T) {
+		repos := newRepos(t)
+		user := mustCreateUser(t, repos.Users, "ana")
+
+		var kept, undone *domain.Task
+		err := repos.Tasks.WithTx(ctx, func(tx repository.TaskTx) error {
+			var err error
+			kept, err = tx.CreateTask(ctx, "kept", "", "", "", user.ID, "")
+			if err != nil {
+				return err
+			}
+			err = tx.Savepoint(ctx, func() error {
+				undone, err = tx.CreateTask(ctx, "undone", "", "", "", user.ID, "")
+				if err != nil {
+					return err
+				}
+				return errors.New("undo")
+			})
+			if err == nil {
+				return errors.New("Savepoint returned nil, want the error fn returned")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WithTx: %v", err)
+		}
+		if _, err := repos.Tasks.GetTaskById(ctx, kept.Id); err != nil {
+			t.Fatalf("task created outside the savepoint is gone: %v", err)
+		}
+		_, err = repos.Tasks.GetTaskById(ctx, undone.Id)
+		wantError(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func mustCreateUser(t *testing.T, users repository.UserRepository, username string) *domain.User {
+	t.Helper()
+	user, err := users.CreateUser(context.Background(), username, username+"@example.com", "hash-"+username)
+	if err != nil {
+		t.Fatalf("CreateUser(%q): %v", username, err)
+	}
+	return user
+}
+
+func mustGetUser(t *testing.T, users repository.UserRepository, id int64) *domain.User {
+	t.Helper()
+	user, err := users.GetUserById(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetUserById(%d): %v", id, err)
+	}
+	return user
+}
+
+func mustCreateTask(t *testing.T, tasks repository.TaskRepository, userID int64, title string) *domain.Task {
+	t.Helper()
+	task, err := tasks.CreateTask(context.Background(), title, "", "pending", "medium", userID, "")
+	if err != nil {
+		t.Fatalf("CreateTask(%q): %v", title, err)
+	}
+	return task
+}
+
+// wantTask compares the stored fields of got with want, ignoring the ID and
+// timestamps the backend assigns.
+func wantTask(t *testing.T, got *domain.Task, want domain.Task) {
+	t.Helper()
+	if got.Title != want.Title || got.Description != want.Description || got.Status != want.Status ||
+		got.Priority != want.Priority || got.Userid != want.Userid || !got.Duedate.Equal(want.Duedate) ||
+		got.Version != want.Version {
+		t.Fatalf("got task %s, want %s", describeTask(*got), describeTask(want))
+	}
+}
+
+func describeTask(task domain.Task) string {
+	return fmt.Sprintf("{title=%q description=%q status=%q priority=%q user=%d due=%s version=%d}",
+		task.Title, task.Description, task.Status, task.Priority, task.Userid, task.Duedate.Format(time.DateOnly), task.Version)
+}
+
+func wantError(t *testing.T, err error, want error) {
+	t.Helper()
+	if !errors.Is(err, want) {
+		t.Fatalf("got error %v, want %v", err, want)
+	}
+}
+
+// wantField checks that err names the offending column, which clients
+// receive as the field of the problem response.
+func wantField(t *testing.T, err error, field string) {
+	t.Helper()
+	if got := apperrors.From(err).Details["field"]; got != field {
+		t.Fatalf("got field %v in error %v, want %q", got, err, field)
+	}
+}
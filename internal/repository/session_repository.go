@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"tasked/internal/database"
+	"tasked/internal/domain"
+	"time"
+)
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, id string, userID int64, userAgent string, ip string, expiresAt time.Time) (*domain.Session, error)
+	TouchActiveSession(ctx context.Context, id string) (*domain.Session, error)
+	ListActiveSessions(ctx context.Context, userID int64) ([]*domain.Session, error)
+	RevokeSession(ctx context.Context, id string, userID int64) error
+	RevokeOtherSessions(ctx context.Context, userID int64, exceptID string) ([]string, error)
+	DeleteStaleSessions(ctx context.Context, revokedBefore time.Time) (int64, error)
+}
+
+type sessionRepository struct {
+	queries *database.Queries
+}
+
+func NewSessionRepository(db *sql.DB) SessionRepository {
+	return &sessionRepository{
+		queries: database.New(db),
+	}
+}
+
+func (r *sessionRepository) CreateSession(ctx context.Context, id string, userID int64, userAgent string, ip string, expiresAt time.Time) (*domain.Session, error) {
+	dbSession, err := r.queries.CreateSession(ctx, database.CreateSessionParams{
+		ID:        id,
+		UserID:    userID,
+		UserAgent: userAgent,
+		Ip:        ip,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, dbError(err, "session")
+	}
+	return toDomainSession(dbSession), nil
+}
+
+// TouchActiveSession records activity on a session and returns it. It
+// returns ErrNotFound when the session is unknown, expired or revoked.
+func (r *sessionRepository) TouchActiveSession(ctx context.Context, id string) (*domain.Session, error) {
+	dbSession, err := r.queries.TouchActiveSession(ctx, id)
+	if err != nil {
+		return nil, dbError(err, "session")
+	}
+	return toDomainSession(dbSession), nil
+}
+
+func (r *sessionRepository) ListActiveSessions(ctx context.Context, userID int64) ([]*domain.Session, error) {
+	dbSessions, err := r.queries.ListActiveSessions(ctx, userID)
+	if err != nil {
+		return nil, dbError(err, "session")
+	}
+	sessions := make([]*domain.Session, 0, len(dbSessions))
+	for _, dbSession := range dbSessions {
+		sessions = append(sessions, toDomainSession(dbSession))
+	}
+	return sessions, nil
+}
+
+func (r *sessionRepository) RevokeSession(ctx context.Context, id string, userID int64) error {
+	rows, err := r.queries.RevokeSession(ctx, database.RevokeSessionParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		return dbError(err, "session")
+	}
+	if rows == 0 {
+		return notFound("session")
+	}
+	return nil
+}
+
+// RevokeOtherSessions revokes every session of the user except exceptID and
+// returns the IDs it revoked.
+func (r *sessionRepository) RevokeOtherSessions(ctx context.Context, userID int64, exceptID string) ([]string, error) {
+	return r.queries.RevokeOtherSessions(ctx, database.RevokeOtherSessionsParams{
+		UserID: userID,
+		ID:     exceptID,
+	})
+}
+
+// DeleteStaleSessions removes expired sessions and those revoked before
+// revokedBefore.
+func (r *sessionRepository) DeleteStaleSessions(ctx context.Context, revokedBefore time.Time) (int64, error) {
+	return r.queries.DeleteStaleSessions(ctx, sql.NullTime{Time: revokedBefore, Valid: true})
+}
+
+func toDomainSession(dbSession database.Session) *domain.Session {
+	return &domain.Session{
+		ID:         dbSession.ID,
+		UserID:     dbSession.UserID,
+		UserAgent:  dbSession.UserAgent,
+		IP:         dbSession.Ip,
+		CreatedAt:  dbSession.CreatedAt,
+		LastSeenAt: dbSession.LastSeenAt,
+		ExpiresAt:  dbSession.ExpiresAt,
+	}
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"tasked/internal/database/sqlite"
+	"time"
+)
+
+func NewSQLiteRepositories(db *sql.DB) *Repositories {
+	return &Repositories{
+		Users:        NewSQLiteUserRepository(db),
+		UserTokens:   NewSQLiteUserTokenRepository(db),
+		Sessions:     NewSQLiteSessionRepository(db),
+		MFA:          NewSQLiteMFARepository(db),
+		OIDC:         NewSQLiteOIDCRepository(db),
+		AccessTokens: NewSQLiteAccessTokenRepository(db),
+		Tasks:        NewSQLiteTaskRepository(db),
+		Idempotency:  NewSQLiteIdempotencyRepository(db),
+	}
+}
+
+// sqliteNow is the current time as the SQLite repositories store it. SQLite
+// has no timestamp type, so the queries take the time from here instead of
+// calling a database function, and the times compare as text. It is cut to
+// microseconds like a Postgres timestamptz.
+func sqliteNow() time.Time {
+	return sqliteTime(time.Now())
+}
+
+// sqliteTime normalises t to UTC so stored times compare as text in order.
+func sqliteTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+func sqliteInTx(ctx context.Context, db *sql.DB, queries *sqlite.Queries, fn func(q *sqlite.Queries) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(queries.WithTx(tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
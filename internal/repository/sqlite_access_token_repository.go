@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"tasked/internal/database/sqlite"
+	"tasked/internal/domain"
+	"time"
+)
+
+type sqliteAccessTokenRepository struct {
+	queries *sqlite.Queries
+}
+
+func NewSQLiteAccessTokenRepository(db *sql.DB) AccessTokenRepository {
+	return &sqliteAccessTokenRepository{
+		queries: sqlite.New(db),
+	}
+}
+
+func (r *sqliteAccessTokenRepository) CreateToken(ctx context.Context, userID int64, name string, tokenHash string, scopes []string, expiresAt time.Time) (*domain.PersonalAccessToken, error) {
+	encodedScopes, err := json.Marshal(scopes)
+	if err != nil {
+		return nil, dbError(err, "access token")
+	}
+	dbToken, err := r.queries.CreatePersonalAccessToken(ctx, sqlite.CreatePersonalAccessTokenParams{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: tokenHash,
+		Scopes:    string(encodedScopes),
+		ExpiresAt: expiresAt,
+		CreatedAt: sqliteNow(),
+	})
+	if err != nil {
+		return nil, dbError(err, "access token")
+	}
+	return sqliteToDomainAccessToken(dbToken)
+}
+
+func (r *sqliteAccessTokenRepository) ListTokens(ctx context.Context, userID int64) ([]*domain.PersonalAccessToken, error) {
+	dbTokens, err := r.queries.ListPersonalAccessTokens(ctx, userID)
+	if err != nil {
+		return nil, dbError(err, "access token")
+	}
+	tokens := make([]*domain.PersonalAccessToken, 0, len(dbTokens))
+	for _, dbToken := range dbTokens {
+		token, err := sqliteToDomainAccessToken(dbToken)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// GetTokenByHash returns ErrNotFound for unknown and expired tokens alike.
+func (r *sqliteAccessTokenRepository) GetTokenByHash(ctx context.Context, tokenHash string) (*domain.PersonalAccessToken, error) {
+	dbToken, err := r.queries.GetPersonalAccessTokenByHash(ctx, sqlite.GetPersonalAccessTokenByHashParams{
+		TokenHash: tokenHash,
+		Now:       sqliteNow(),
+	})
+	if err != nil {
+		return nil, dbError(err, "access token")
+	}
+	return sqliteToDomainAccessToken(dbToken)
+}
+
+func (r *sqliteAccessTokenRepository) TouchToken(ctx context.Context, id int64) error {
+	return r.queries.TouchPersonalAccessToken(ctx, sqlite.TouchPersonalAccessTokenParams{
+		ID:  id,
+		Now: sql.NullTime{Time: sqliteNow(), Valid: true},
+	})
+}
+
+func (r *sqliteAccessTokenRepository) DeleteToken(ctx context.Context, id int64, userID int64) error {
+	rows, err := r.queries.DeletePersonalAccessToken(ctx, sqlite.DeletePersonalAccessTokenParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		return dbError(err, "access token")
+	}
+	if rows == 0 {
+		return notFound("access token")
+	}
+	return nil
+}
+
+// sqliteToDomainAccessToken decodes the scopes, which SQLite stores as a
+// JSON array.
+func sqliteToDomainAccessToken(dbToken sqlite.PersonalAccessToken) (*domain.PersonalAccessToken, error) {
+	var scopes []string
+	if err := json.Unmarshal([]byte(dbToken.Scopes), &scopes); err != nil {
+		return nil, dbError(err, "access token")
+	}
+	return &domain.PersonalAccessToken{
+		ID:         dbToken.ID,
+		UserID:     dbToken.UserID,
+		Name:       dbToken.Name,
+		Scopes:     scopes,
+		ExpiresAt:  dbToken.ExpiresAt,
+		LastUsedAt: dbToken.LastUsedAt.Time,
+		CreatedAt:  dbToken.CreatedAt,
+	}, nil
+}
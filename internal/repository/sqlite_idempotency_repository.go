@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"tasked/internal/database/sqlite"
+	"tasked/internal/domain"
+	"time"
+)
+
+type sqliteIdempotencyRepository struct {
+	queries *sqlite.Queries
+}
+
+func NewSQLiteIdempotencyRepository(db *sql.DB) IdempotencyRepository {
+	return &sqliteIdempotencyRepository{
+		queries: sqlite.New(db),
+	}
+}
+
+// ClaimKey reserves the key for the caller as of now. It reports false when
+// another request already holds it, whatever state that request is in.
+func (r *sqliteIdempotencyRepository) ClaimKey(ctx context.Context, scope string, key string, fingerprint string, now time.Time, expiresAt time.Time) (bool, error) {
+	rows, err := r.queries.ClaimIdempotencyKey(ctx, sqlite.ClaimIdempotencyKeyParams{
+		Scope:       scope,
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   sqliteTime(now),
+		ExpiresAt:   sqliteTime(expiresAt),
+	})
+	if err != nil {
+		return false, dbError(err, "idempotency key")
+	}
+	return rows == 1, nil
+}
+
+func (r *sqliteIdempotencyRepository) GetKey(ctx context.Context, scope string, key string) (*domain.IdempotencyRecord, error) {
+	dbKey, err := r.queries.GetIdempotencyKey(ctx, sqlite.GetIdempotencyKeyParams{
+		Scope: scope,
+		Key:   key,
+	})
+	if err != nil {
+		return nil, dbError(err, "idempotency key")
+	}
+	headers := map[string]string{}
+	if err := json.Unmarshal([]byte(dbKey.ResponseHeaders), &headers); err != nil {
+		return nil, dbError(err, "idempotency key")
+	}
+	return &domain.IdempotencyRecord{
+		Scope:           dbKey.Scope,
+		Key:             dbKey.Key,
+		Fingerprint:     dbKey.Fingerprint,
+		Status:          dbKey.Status,
+		ResponseStatus:  int(dbKey.ResponseStatus.Int64),
+		ResponseHeaders: headers,
+		ResponseBody:    dbKey.ResponseBody,
+		CreatedAt:       dbKey.CreatedAt,
+		ExpiresAt:       dbKey.ExpiresAt,
+	}, nil
+}
+
+func (r *sqliteIdempotencyRepository) CompleteKey(ctx context.Context, scope string, key string, status int, headers map[string]string, body []byte) error {
+	encodedHeaders, err := json.Marshal(headers)
+	if err != nil {
+		return dbError(err, "idempotency key")
+	}
+	return r.queries.CompleteIdempotencyKey(ctx, sqlite.CompleteIdempotencyKeyParams{
+		Scope: scope,
+		Key:   key,
+		ResponseStatus: sql.NullInt64{
+			Int64: int64(status),
+			Valid: true,
+		},
+		ResponseHeaders: string(encodedHeaders),
+		ResponseBody:    body,
+	})
+}
+
+func (r *sqliteIdempotencyRepository) DeleteKey(ctx context.Context, scope string, key string) error {
+	return r.queries.DeleteIdempotencyKey(ctx, sqlite.DeleteIdempotencyKeyParams{
+		Scope: scope,
+		Key:   key,
+	})
+}
+
+// DeleteStaleKey removes the key only if it has expired by now or its request
+// has been in progress since before abandonedBefore.
+func (r *sqliteIdempotencyRepository) DeleteStaleKey(ctx context.Context, scope string, key string, now time.Time, abandonedBefore time.Time) (bool, error) {
+	rows, err := r.queries.DeleteStaleIdempotencyKey(ctx, sqlite.DeleteStaleIdempotencyKeyParams{
+		Scope:           scope,
+		Key:             key,
+		Now:             sqliteTime(now),
+		AbandonedBefore: sqliteTime(abandonedBefore),
+	})
+	if err != nil {
+		return false, dbError(err, "idempotency key")
+	}
+	return rows > 0, nil
+}
+
+func (r *sqliteIdempotencyRepository) DeleteExpiredKeys(ctx context.Context, now time.Time) (int64, error) {
+	return r.queries.DeleteExpiredIdempotencyKeys(ctx, sqliteTime(now))
+}
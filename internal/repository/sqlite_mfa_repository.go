@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"tasked/internal/database/sqlite"
+	"tasked/internal/domain"
+)
+
+type sqliteMFARepository struct {
+	db      *sql.DB
+	queries *sqlite.Queries
+}
+
+func NewSQLiteMFARepository(db *sql.DB) MFARepository {
+	return &sqliteMFARepository{
+		db:      db,
+		queries: sqlite.New(db),
+	}
+}
+
+func (r *sqliteMFARepository) GetMFA(ctx context.Context, userID int64) (*domain.UserMFA, error) {
+	row, err := r.queries.GetUserMFA(ctx, userID)
+	if err != nil {
+		return nil, dbError(err, "user")
+	}
+	return &domain.UserMFA{
+		Secret:    row.TotpSecret.String,
+		EnabledAt: row.TotpEnabledAt.Time,
+		LastStep:  row.TotpLastStep.Int64,
+	}, nil
+}
+
+// SetPendingSecret starts a new enrollment, replacing any previous one.
+func (r *sqliteMFARepository) SetPendingSecret(ctx context.Context, userID int64, secret string) error {
+	return r.queries.SetUserTOTPSecret(ctx, sqlite.SetUserTOTPSecretParams{
+		ID: userID,
+		TotpSecret: sql.NullString{
+			String: secret,
+			Valid:  true,
+		},
+		Now: sqliteNow(),
+	})
+}
+
+// EnableMFA confirms the pending enrollment and replaces the recovery codes.
+func (r *sqliteMFARepository) EnableMFA(ctx context.Context, userID int64, recoveryCodeHashes []string) error {
+	return sqliteInTx(ctx, r.db, r.queries, func(q *sqlite.Queries) error {
+		now := sqliteNow()
+		err := q.EnableUserTOTP(ctx, sqlite.EnableUserTOTPParams{
+			ID:  userID,
+			Now: sql.NullTime{Time: now, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		if err := q.DeleteRecoveryCodes(ctx, userID); err != nil {
+			return err
+		}
+		for _, hash := range recoveryCodeHashes {
+			err := q.CreateRecoveryCode(ctx, sqlite.CreateRecoveryCodeParams{
+				UserID:    userID,
+				CodeHash:  hash,
+				CreatedAt: now,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *sqliteMFARepository) DisableMFA(ctx context.Context, userID int64) error {
+	return sqliteInTx(ctx, r.db, r.queries, func(q *sqlite.Queries) error {
+		err := q.DisableUserTOTP(ctx, sqlite.DisableUserTOTPParams{
+			ID:  userID,
+			Now: sqliteNow(),
+		})
+		if err != nil {
+			return err
+		}
+		return q.DeleteRecoveryCodes(ctx, userID)
+	})
+}
+
+// UseTOTPStep records step as used. It reports false when that step, or a
+// later one, was already used, which means the code is being replayed.
+func (r *sqliteMFARepository) UseTOTPStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	rows, err := r.queries.UseUserTOTPStep(ctx, sqlite.UseUserTOTPStepParams{
+		ID:   userID,
+		Step: sql.NullInt64{Int64: step, Valid: true},
+	})
+	if err != nil {
+		return false, dbError(err, "user")
+	}
+	return rows == 1, nil
+}
+
+func (r *sqliteMFARepository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	rows, err := r.queries.UseRecoveryCode(ctx, sqlite.UseRecoveryCodeParams{
+		UserID:   userID,
+		CodeHash: codeHash,
+		Now:      sql.NullTime{Time: sqliteNow(), Valid: true},
+	})
+	if err != nil {
+		return false, dbError(err, "user")
+	}
+	return rows > 0, nil
+}
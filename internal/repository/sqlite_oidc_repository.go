@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"tasked/internal/database/sqlite"
+	"tasked/internal/domain"
+	"time"
+)
+
+type sqliteOIDCRepository struct {
+	db      *sql.DB
+	queries *sqlite.Queries
+}
+
+func NewSQLiteOIDCRepository(db *sql.DB) OIDCRepository {
+	return &sqliteOIDCRepository{
+		db:      db,
+		queries: sqlite.New(db),
+	}
+}
+
+func (r *sqliteOIDCRepository) GetUserByIdentity(ctx context.Context, issuer string, subject string) (*domain.User, error) {
+	dbUser, err := r.queries.GetUserByIdentity(ctx, sqlite.GetUserByIdentityParams{
+		Issuer:  issuer,
+		Subject: subject,
+	})
+	if err != nil {
+		return nil, dbError(err, "identity")
+	}
+	return sqliteToDomainUser(dbUser), nil
+}
+
+func (r *sqliteOIDCRepository) LinkIdentity(ctx context.Context, userID int64, issuer string, subject string, email string) error {
+	err := r.queries.CreateUserIdentity(ctx, sqlite.CreateUserIdentityParams{
+		UserID:    userID,
+		Issuer:    issuer,
+		Subject:   subject,
+		Email:     email,
+		CreatedAt: sqliteNow(),
+	})
+	return dbError(err, "identity")
+}
+
+// CreateUserWithIdentity creates a user whose email the provider has already
+// verified, linked to the identity that created it.
+func (r *sqliteOIDCRepository) CreateUserWithIdentity(ctx context.Context, username string, email string, password string, issuer string, subject string) (*domain.User, error) {
+	var user *domain.User
+	err := sqliteInTx(ctx, r.db, r.queries, func(q *sqlite.Queries) error {
+		now := sqliteNow()
+		dbUser, err := q.CreateUser(ctx, sqlite.CreateUserParams{
+			Username: username,
+			Email:    email,
+			Password: password,
+			Now:      now,
+		})
+		if err != nil {
+			return err
+		}
+		err = q.MarkUserEmailVerified(ctx, sqlite.MarkUserEmailVerifiedParams{
+			ID:  dbUser.ID,
+			Now: now,
+		})
+		if err != nil {
+			return err
+		}
+		err = q.CreateUserIdentity(ctx, sqlite.CreateUserIdentityParams{
+			UserID:    dbUser.ID,
+			Issuer:    issuer,
+			Subject:   subject,
+			Email:     email,
+			CreatedAt: now,
+		})
+		if err != nil {
+			return err
+		}
+		dbUser, err = q.GetUserByID(ctx, dbUser.ID)
+		if err != nil {
+			return err
+		}
+		user = sqliteToDomainUser(dbUser)
+		return nil
+	})
+	return user, dbError(err, "user")
+}
+
+func (r *sqliteOIDCRepository) UsernameExists(ctx context.Context, username string) (bool, error) {
+	exists, err := r.queries.UsernameExists(ctx, username)
+	return exists != 0, err
+}
+
+func (r *sqliteOIDCRepository) SaveLoginState(ctx context.Context, stateHash string, codeVerifier string, nonce string, expiresAt time.Time) error {
+	return r.queries.CreateOIDCLoginState(ctx, sqlite.CreateOIDCLoginStateParams{
+		StateHash:    stateHash,
+		CodeVerifier: codeVerifier,
+		Nonce:        nonce,
+		ExpiresAt:    expiresAt,
+	})
+}
+
+// ConsumeLoginState deletes a pending login and returns its PKCE verifier and
+// nonce. It returns ErrNotFound when the state is unknown, expired or was
+// already used.
+func (r *sqliteOIDCRepository) ConsumeLoginState(ctx context.Context, stateHash string) (string, string, error) {
+	row, err := r.queries.ConsumeOIDCLoginState(ctx, sqlite.ConsumeOIDCLoginStateParams{
+		StateHash: stateHash,
+		Now:       sqliteNow(),
+	})
+	if err != nil {
+		return "", "", dbError(err, "login state")
+	}
+	return row.CodeVerifier, row.Nonce, nil
+}
+
+func (r *sqliteOIDCRepository) DeleteExpiredLoginStates(ctx context.Context) (int64, error) {
+	return r.queries.DeleteExpiredOIDCLoginStates(ctx, sqliteNow())
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"tasked/internal/database/sqlite"
+	"tasked/internal/domain"
+	"time"
+)
+
+type sqliteSessionRepository struct {
+	queries *sqlite.Queries
+}
+
+func NewSQLiteSessionRepository(db *sql.DB) SessionRepository {
+	return &sqliteSessionRepository{
+		queries: sqlite.New(db),
+	}
+}
+
+func (r *sqliteSessionRepository) CreateSession(ctx context.Context, id string, userID int64, userAgent string, ip string, expiresAt time.Time) (*domain.Session, error) {
+	dbSession, err := r.queries.CreateSession(ctx, sqlite.CreateSessionParams{
+		ID:        id,
+		UserID:    userID,
+		UserAgent: userAgent,
+		Ip:        ip,
+		Now:       sqliteNow(),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, dbError(err, "session")
+	}
+	return sqliteToDomainSession(dbSession), nil
+}
+
+// TouchActiveSession records activity on a session and returns it. It
+// returns ErrNotFound when the session is unknown, expired or revoked.
+func (r *sqliteSessionRepository) TouchActiveSession(ctx context.Context, id string) (*domain.Session, error) {
+	dbSession, err := r.queries.TouchActiveSession(ctx, sqlite.TouchActiveSessionParams{
+		ID:  id,
+		Now: sqliteNow(),
+	})
+	if err != nil {
+		return nil, dbError(err, "session")
+	}
+	return sqliteToDomainSession(dbSession), nil
+}
+
+func (r *sqliteSessionRepository) ListActiveSessions(ctx context.Context, userID int64) ([]*domain.Session, error) {
+	dbSessions, err := r.queries.ListActiveSessions(ctx, sqlite.ListActiveSessionsParams{
+		UserID: userID,
+		Now:    sqliteNow(),
+	})
+	if err != nil {
+		return nil, dbError(err, "session")
+	}
+	sessions := make([]*domain.Session, 0, len(dbSessions))
+	for _, dbSession := range dbSessions {
+		sessions = append(sessions, sqliteToDomainSession(dbSession))
+	}
+	return sessions, nil
+}
+
+func (r *sqliteSessionRepository) RevokeSession(ctx context.Context, id string, userID int64) error {
+	rows, err := r.queries.RevokeSession(ctx, sqlite.RevokeSessionParams{
+		ID:     id,
+		UserID: userID,
+		Now:    sql.NullTime{Time: sqliteNow(), Valid: true},
+	})
+	if err != nil {
+		return dbError(err, "session")
+	}
+	if rows == 0 {
+		return notFound("session")
+	}
+	return nil
+}
+
+// RevokeOtherSessions revokes every session of the user except exceptID and
+// returns the IDs it revoked.
+func (r *sqliteSessionRepository) RevokeOtherSessions(ctx context.Context, userID int64, exceptID string) ([]string, error) {
+	return r.queries.RevokeOtherSessions(ctx, sqlite.RevokeOtherSessionsParams{
+		UserID:   userID,
+		ExceptID: exceptID,
+		Now:      sql.NullTime{Time: sqliteNow(), Valid: true},
+	})
+}
+
+// DeleteStaleSessions removes expired sessions and those revoked before
+// revokedBefore.
+func (r *sqliteSessionRepository) DeleteStaleSessions(ctx context.Context, revokedBefore time.Time) (int64, error) {
+	return r.queries.DeleteStaleSessions(ctx, sqlite.DeleteStaleSessionsParams{
+		Now:           sqliteNow(),
+		RevokedBefore: sql.NullTime{Time: revokedBefore, Valid: true},
+	})
+}
+
+func sqliteToDomainSession(dbSession sqlite.Session) *domain.Session {
+	return &domain.Session{
+		ID:         dbSession.ID,
+		UserID:     dbSession.UserID,
+		UserAgent:  dbSession.UserAgent,
+		IP:         dbSession.Ip,
+		CreatedAt:  dbSession.CreatedAt,
+		LastSeenAt: dbSession.LastSeenAt,
+		ExpiresAt:  dbSession.ExpiresAt,
+	}
+}
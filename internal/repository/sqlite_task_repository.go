@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"tasked/internal/database/sqlite"
+	"tasked/internal/domain"
+	"time"
+)
+
+type sqliteTaskRepository struct {
+	db         *sql.DB
+	tx         *sql.Tx
+	queries    *sqlite.Queries
+	savepoints int
+}
+
+func NewSQLiteTaskRepository(db *sql.DB) TaskRepository {
+	return &sqliteTaskRepository{
+		db:      db,
+		queries: sqlite.New(db),
+	}
+}
+
+// WithTx runs fn in a transaction that is committed when fn returns nil and
+// rolled back otherwise. Calling it on a repository that is already bound to
+// a transaction reuses that transaction.
+func (r *sqliteTaskRepository) WithTx(ctx context.Context, fn func(tx TaskTx) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	txRepo := &sqliteTaskRepository{
+		db:      r.db,
+		tx:      tx,
+		queries: r.queries.WithTx(tx),
+	}
+	if err := fn(txRepo); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *sqliteTaskRepository) Savepoint(ctx context.Context, fn func() error) error {
+	if r.tx == nil {
+		return fn()
+	}
+
+	r.savepoints++
+	name := fmt.Sprintf("sp_%d", r.savepoints)
+	if _, err := r.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		if _, rbErr := r.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return rbErr
+		}
+		return err
+	}
+	_, err := r.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+func (r *sqliteTaskRepository) GetTaskById(ctx context.Context, id int64) (*domain.Task, error) {
+	dbTask, err := r.queries.GetTaskByID(ctx, id)
+	if err != nil {
+		return nil, dbError(err, "task")
+	}
+	return sqliteToDomainTask(dbTask), nil
+}
+
+func (r *sqliteTaskRepository) ListTaskByUser(ctx context.Context, id int64) ([]domain.Task, error) {
+	dbTasks, err := r.queries.ListTasksByUser(ctx, id)
+	if err != nil {
+		return nil, dbError(err, "task")
+	}
+	tasks := make([]domain.Task, 0, len(dbTasks))
+	for _, dbTask := range dbTasks {
+		tasks = append(tasks, *sqliteToDomainTask(dbTask))
+	}
+	return tasks, nil
+}
+
+func (r *sqliteTaskRepository) UpdateTask(ctx context.Context, id int64, version int64, title string, description string, status string, priority string, dueDate string) (*domain.Task, error) {
+	nullDueDate, err := sqliteDueDate(dueDate)
+	if err != nil {
+		return nil, err
+	}
+
+	dbTask, err := r.queries.UpdateTask(ctx, sqlite.UpdateTaskParams{
+		ID:          id,
+		Title:       title,
+		Description: nullString(description),
+		Status:      nullString(status),
+		Priority:    nullString(priority),
+		DueDate:     nullDueDate,
+		Now:         sqliteNow(),
+		Version:     version,
+	})
+	if err != nil {
+		return nil, dbError(err, "task")
+	}
+	return sqliteToDomainTask(dbTask), nil
+}
+
+func (r *sqliteTaskRepository) PatchTask(ctx context.Context, id int64, version int64, patch domain.TaskPatch) (*domain.Task, error) {
+	params := sqlite.PatchTaskParams{
+		ID:             id,
+		Version:        version,
+		SetDescription: patch.SetDescription,
+		SetPriority:    patch.SetPriority,
+		SetDueDate:     patch.SetDueDate,
+		Now:            sqliteNow(),
+	}
+	if patch.Title != nil {
+		params.Title = sql.NullString{String: *patch.Title, Valid: true}
+	}
+	if patch.Status != nil {
+		params.Status = sql.NullString{String: *patch.Status, Valid: true}
+	}
+	if patch.Description != nil {
+		params.Description = sql.NullString{String: *patch.Description, Valid: true}
+	}
+	if patch.Priority != nil {
+		params.Priority = sql.NullString{String: *patch.Priority, Valid: true}
+	}
+	if patch.DueDate != nil {
+		parsedDate, err := time.Parse("2006-01-02", *patch.DueDate)
+		if err != nil {
+			return nil, invalidDueDate(err)
+		}
+		params.DueDate = sql.NullTime{Time: parsedDate, Valid: true}
+	}
+
+	dbTask, err := r.queries.PatchTask(ctx, params)
+	if err != nil {
+		return nil, dbError(err, "task")
+	}
+	return sqliteToDomainTask(dbTask), nil
+}
+
+func (r *sqliteTaskRepository) DeleteTask(ctx context.Context, id int64, version int64) error {
+	rows, err := r.queries.DeleteTask(ctx, sqlite.DeleteTaskParams{
+		ID:      id,
+		Version: version,
+	})
+	if err != nil {
+		return dbError(err, "task")
+	}
+	if rows == 0 {
+		return notFound("task")
+	}
+	return nil
+}
+
+func (r *sqliteTaskRepository) UpdateStatus(ctx context.Context, id int64, version int64, status string) (*domain.Task, error) {
+	dbTask, err := r.queries.UpdateTaskStatus(ctx, sqlite.UpdateTaskStatusParams{
+		ID:      id,
+		Status:  nullString(status),
+		Now:     sqliteNow(),
+		Version: version,
+	})
+	if err != nil {
+		return nil, dbError(err, "task")
+	}
+	return sqliteToDomainTask(dbTask), nil
+}
+
+func (r *sqliteTaskRepository) CreateTask(ctx context.Context, title string, description string, status string, priority string, userId int64, dueDate string) (*domain.Task, error) {
+	nullDueDate, err := sqliteDueDate(dueDate)
+	if err != nil {
+		return nil, err
+	}
+
+	dbTask, err := r.queries.CreateTask(ctx, sqlite.CreateTaskParams{
+		Title:       title,
+		Description: nullString(description),
+		Status:      nullString(status),
+		Priority:    nullString(priority),
+		UserID:      userId,
+		DueDate:     nullDueDate,
+		Now:         sqliteNow(),
+	})
+	if err != nil {
+		return nil, dbError(err, "task")
+	}
+	return sqliteToDomainTask(dbTask), nil
+}
+
+func (r *sqliteTaskRepository) GetTaskStats(ctx context.Context) (*domain.TaskStats, error) {
+	rows, err := r.queries.CountTasksByStatus(ctx)
+	if err != nil {
+		return nil, dbError(err, "task")
+	}
+	overdue, err := r.queries.CountOverdueTasks(ctx, sql.NullTime{Time: sqliteNow(), Valid: true})
+	if err != nil {
+		return nil, dbError(err, "task")
+	}
+
+	stats := &domain.TaskStats{
+		ByStatus: make(map[string]int64, len(rows)),
+		Overdue:  overdue,
+	}
+	for _, row := range rows {
+		stats.ByStatus[row.Status] += row.Count
+	}
+	return stats, nil
+}
+
+func sqliteToDomainTask(dbTask sqlite.Task) *domain.Task {
+	return &domain.Task{
+		Id:          dbTask.ID,
+		Title:       dbTask.Title,
+		Description: dbTask.Description.String,
+		Status:      dbTask.Status.String,
+		Priority:    dbTask.Priority.String,
+		Userid:      dbTask.UserID,
+		Duedate:     dbTask.DueDate.Time,
+		CompletedAt: dbTask.CompletedAt.Time,
+		UpdatedAt:   dbTask.UpdatedAt,
+		Version:     dbTask.Version,
+	}
+}
+
+// sqliteDueDate is parseDueDate as a nullable column value.
+func sqliteDueDate(dueDate string) (sql.NullTime, error) {
+	due, err := parseDueDate(dueDate)
+	return sql.NullTime{Time: due, Valid: !due.IsZero()}, err
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"tasked/internal/database/sqlite"
+	"tasked/internal/domain"
+)
+
+type sqliteUserRepository struct {
+	queries *sqlite.Queries
+}
+
+func NewSQLiteUserRepository(db *sql.DB) UserRepository {
+	return &sqliteUserRepository{
+		queries: sqlite.New(db),
+	}
+}
+
+func (r *sqliteUserRepository) GetUserById(ctx context.Context, id int64) (*domain.User, error) {
+	dbUser, err := r.queries.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, dbError(err, "user")
+	}
+	return sqliteToDomainUser(dbUser), nil
+}
+
+func (r *sqliteUserRepository) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	dbUser, err := r.queries.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, dbError(err, "user")
+	}
+	return sqliteToDomainUser(dbUser), nil
+}
+
+func (r *sqliteUserRepository) CreateUser(ctx context.Context, username string, email string, password string) (*domain.User, error) {
+	dbUser, err := r.queries.CreateUser(ctx, sqlite.CreateUserParams{
+		Username: username,
+		Email:    email,
+		Password: password,
+		Now:      sqliteNow(),
+	})
+	if err != nil {
+		return nil, dbError(err, "user")
+	}
+	return sqliteToDomainUser(dbUser), nil
+}
+
+func (r *sqliteUserRepository) UpdateUser(ctx context.Context, id int64, version int64, username string, email string) (*domain.User, error) {
+	dbUser, err := r.queries.UpdateUser(ctx, sqlite.UpdateUserParams{
+		ID:       id,
+		Username: username,
+		Email:    email,
+		Now:      sqliteNow(),
+		Version:  version,
+	})
+	if err != nil {
+		return nil, dbError(err, "user")
+	}
+	return sqliteToDomainUser(dbUser), nil
+}
+
+func (r *sqliteUserRepository) DeleteUser(ctx context.Context, id int64, version int64) error {
+	rows, err := r.queries.DeleteUser(ctx, sqlite.DeleteUserParams{
+		ID:      id,
+		Version: version,
+	})
+	if err != nil {
+		return dbError(err, "user")
+	}
+	if rows == 0 {
+		return notFound("user")
+	}
+	return nil
+}
+
+func (r *sqliteUserRepository) UpdatePassword(ctx context.Context, id int64, password string) error {
+	return r.queries.UpdateUserPassword(ctx, sqlite.UpdateUserPasswordParams{
+		ID:       id,
+		Password: password,
+		Now:      sql.NullTime{Time: sqliteNow(), Valid: true},
+	})
+}
+
+func (r *sqliteUserRepository) MarkEmailVerified(ctx context.Context, id int64) error {
+	return r.queries.MarkUserEmailVerified(ctx, sqlite.MarkUserEmailVerifiedParams{
+		ID:  id,
+		Now: sqliteNow(),
+	})
+}
+
+func sqliteToDomainUser(dbUser sqlite.User) *domain.User {
+	return &domain.User{
+		ID:              dbUser.ID,
+		Username:        dbUser.Username,
+		Email:           dbUser.Email,
+		Password:        dbUser.Password,
+		CreatedAt:       dbUser.CreatedAt,
+		UpdatedAt:       dbUser.UpdatedAt,
+		Version:         dbUser.Version,
+		EmailVerifiedAt: dbUser.EmailVerifiedAt.Time,
+		MFAEnabled:      dbUser.TotpEnabledAt.Valid,
+	}
+}
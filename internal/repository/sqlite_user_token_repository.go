@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"tasked/internal/database/sqlite"
+	"time"
+)
+
+type sqliteUserTokenRepository struct {
+	queries *sqlite.Queries
+}
+
+func NewSQLiteUserTokenRepository(db *sql.DB) UserTokenRepository {
+	return &sqliteUserTokenRepository{
+		queries: sqlite.New(db),
+	}
+}
+
+func (r *sqliteUserTokenRepository) CreateToken(ctx context.Context, userID int64, purpose string, tokenHash string, expiresAt time.Time) error {
+	return r.queries.CreateUserToken(ctx, sqlite.CreateUserTokenParams{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+		CreatedAt: sqliteNow(),
+	})
+}
+
+// FindToken returns the owner of a valid, unused token without spending it.
+// It returns ErrNotFound when the token is unknown, expired or spent.
+func (r *sqliteUserTokenRepository) FindToken(ctx context.Context, purpose string, tokenHash string) (int64, error) {
+	userID, err := r.queries.GetUserTokenOwner(ctx, sqlite.GetUserTokenOwnerParams{
+		TokenHash: tokenHash,
+		Purpose:   purpose,
+		Now:       sqliteNow(),
+	})
+	if err != nil {
+		return 0, dbError(err, "token")
+	}
+	return userID, nil
+}
+
+// ConsumeToken marks a valid, unused token as used and returns its owner.
+// It returns ErrNotFound when the token is unknown, expired or spent.
+func (r *sqliteUserTokenRepository) ConsumeToken(ctx context.Context, purpose string, tokenHash string) (int64, error) {
+	userID, err := r.queries.ConsumeUserToken(ctx, sqlite.ConsumeUserTokenParams{
+		TokenHash: tokenHash,
+		Purpose:   purpose,
+		Now:       sql.NullTime{Time: sqliteNow(), Valid: true},
+	})
+	if err != nil {
+		return 0, dbError(err, "token")
+	}
+	return userID, nil
+}
+
+func (r *sqliteUserTokenRepository) DeleteTokens(ctx context.Context, userID int64, purpose string) error {
+	return r.queries.DeleteUserTokens(ctx, sqlite.DeleteUserTokensParams{
+		UserID:  userID,
+		Purpose: purpose,
+	})
+}
+
+func (r *sqliteUserTokenRepository) DeleteExpiredTokens(ctx context.Context) (int64, error) {
+	return r.queries.DeleteExpiredUserTokens(ctx, sqliteNow())
+}
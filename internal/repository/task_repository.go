@@ -6,75 +6,117 @@ import (
 	"fmt"
 	"tasked/internal/database"
 	"tasked/internal/domain"
+	apperrors "tasked/internal/errors"
 	"time"
 )
 
+// TaskRepository writes only apply to the given version of a task and
+// return ErrNotFound when it has moved on. Version 0 matches any version.
 type TaskRepository interface {
 	GetTaskById(ctx context.Context, id int64) (*domain.Task, error)
 	ListTaskByUser(ctx context.Context, id int64) ([]domain.Task, error)
-	UpdateTask(ctx context.Context, id int64, title string, description string, status string, priority string, dueDate string) (*domain.Task, error)
-	DeleteTask(ctx context.Context, id int64) error
-	UpdateStatus(ctx context.Context, id int64, status string) (*domain.Task, error)
+	UpdateTask(ctx context.Context, id int64, version int64, title string, description string, status string, priority string, dueDate string) (*domain.Task, error)
+	DeleteTask(ctx context.Context, id int64, version int64) error
+	PatchTask(ctx context.Context, id int64, version int64, patch domain.TaskPatch) (*domain.Task, error)
+	UpdateStatus(ctx context.Context, id int64, version int64, status string) (*domain.Task, error)
 	CreateTask(ctx context.Context, title string, description string, status string, priority string, userId int64, dueDate string) (*domain.Task, error)
+	GetTaskStats(ctx context.Context) (*domain.TaskStats, error)
+	WithTx(ctx context.Context, fn func(tx TaskTx) error) error
+}
+
+// TaskTx is a TaskRepository whose calls all run inside one database
+// transaction. Savepoint lets a caller undo a single step without losing
+// the rest of the transaction.
+type TaskTx interface {
+	TaskRepository
+	Savepoint(ctx context.Context, fn func() error) error
 }
 
 type taskRepository struct {
-	queries *database.Queries
+	db         *sql.DB
+	tx         *sql.Tx
+	queries    *database.Queries
+	savepoints int
 }
 
 func NewTaskRepository(db *sql.DB) TaskRepository {
 	return &taskRepository{
+		db:      db,
 		queries: database.New(db),
 	}
 }
 
+// WithTx runs fn in a transaction that is committed when fn returns nil and
+// rolled back otherwise. Calling it on a repository that is already bound to
+// a transaction reuses that transaction.
+func (r *taskRepository) WithTx(ctx context.Context, fn func(tx TaskTx) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	txRepo := &taskRepository{
+		db:      r.db,
+		tx:      tx,
+		queries: r.queries.WithTx(tx),
+	}
+	if err := fn(txRepo); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *taskRepository) Savepoint(ctx context.Context, fn func() error) error {
+	if r.tx == nil {
+		return fn()
+	}
+
+	r.savepoints++
+	name := fmt.Sprintf("sp_%d", r.savepoints)
+	if _, err := r.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		if _, rbErr := r.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return rbErr
+		}
+		return err
+	}
+	_, err := r.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
 func (r *taskRepository) GetTaskById(ctx context.Context, id int64) (*domain.Task, error) {
 	dbTask, err := r.queries.GetTaskByID(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, dbError(err, "task")
 	}
-	return &domain.Task{
-		Id:          dbTask.ID,
-		Title:       dbTask.Title,
-		Description: dbTask.Description.String,
-		Status:      dbTask.Status.String,
-		Priority:    dbTask.Priority.String,
-		Userid:      dbTask.UserID,
-		Duedate:     dbTask.DueDate.Time,
-		CompletedAt: dbTask.CompletedAt.Time,
-		UpdatedAt:   dbTask.UpdatedAt.Time,
-	}, nil
+	return toDomainTask(dbTask), nil
 }
 
 func (r *taskRepository) ListTaskByUser(ctx context.Context, id int64) ([]domain.Task, error) {
 	dbTasks, err := r.queries.ListTasksByUser(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, dbError(err, "task")
 	}
 	tasks := make([]domain.Task, 0, len(dbTasks))
-	for _, t := range dbTasks {
-		task := domain.Task{
-			Id:          t.ID,
-			Title:       t.Title,
-			Description: t.Description.String,
-			Status:      t.Status.String,
-			Priority:    t.Priority.String,
-			Userid:      t.UserID,
-			Duedate:     t.DueDate.Time,
-			CompletedAt: t.CompletedAt.Time,
-			UpdatedAt:   t.UpdatedAt.Time,
-		}
-		tasks = append(tasks, task)
+	for _, dbTask := range dbTasks {
+		tasks = append(tasks, *toDomainTask(dbTask))
 	}
 	return tasks, nil
 }
 
-func (r *taskRepository) UpdateTask(ctx context.Context, id int64, title string, description string, status string, priority string, dueDate string) (*domain.Task, error) {
+func (r *taskRepository) UpdateTask(ctx context.Context, id int64, version int64, title string, description string, status string, priority string, dueDate string) (*domain.Task, error) {
 	var nullDueDate sql.NullTime
 	if dueDate != "" {
 		parsedDate, err := time.Parse("2006-01-02", dueDate)
 		if err != nil {
-			return nil, fmt.Errorf("formato de fecha inválido: %w", err)
+			return nil, invalidDueDate(err)
 		}
 		nullDueDate = sql.NullTime{
 			Time:  parsedDate,
@@ -98,51 +140,82 @@ func (r *taskRepository) UpdateTask(ctx context.Context, id int64, title string,
 			Valid:  priority != "",
 		},
 		DueDate: nullDueDate,
+		Version: version,
 	})
 	if err != nil {
-		return nil, err
+		return nil, dbError(err, "task")
 	}
 
-	return &domain.Task{
-		Id:          dbTask.ID,
-		Title:       dbTask.Title,
-		Description: dbTask.Description.String,
-		Status:      dbTask.Status.String,
-		Priority:    dbTask.Priority.String,
-		Userid:      dbTask.UserID,
-		Duedate:     dbTask.DueDate.Time,
-		CompletedAt: dbTask.CompletedAt.Time,
-		UpdatedAt:   dbTask.UpdatedAt.Time,
-	}, nil
+	return toDomainTask(dbTask), nil
+}
+
+func (r *taskRepository) PatchTask(ctx context.Context, id int64, version int64, patch domain.TaskPatch) (*domain.Task, error) {
+	params := database.PatchTaskParams{
+		ID:             id,
+		Version:        version,
+		SetDescription: patch.SetDescription,
+		SetPriority:    patch.SetPriority,
+		SetDueDate:     patch.SetDueDate,
+	}
+	if patch.Title != nil {
+		params.Title = sql.NullString{String: *patch.Title, Valid: true}
+	}
+	if patch.Status != nil {
+		params.Status = sql.NullString{String: *patch.Status, Valid: true}
+	}
+	if patch.Description != nil {
+		params.Description = sql.NullString{String: *patch.Description, Valid: true}
+	}
+	if patch.Priority != nil {
+		params.Priority = sql.NullString{String: *patch.Priority, Valid: true}
+	}
+	if patch.DueDate != nil {
+		parsedDate, err := time.Parse("2006-01-02", *patch.DueDate)
+		if err != nil {
+			return nil, invalidDueDate(err)
+		}
+		params.DueDate = sql.NullTime{
+			Time:  parsedDate,
+			Valid: true,
+		}
+	}
+
+	dbTask, err := r.queries.PatchTask(ctx, params)
+	if err != nil {
+		return nil, dbError(err, "task")
+	}
+
+	return toDomainTask(dbTask), nil
 }
 
-func (r *taskRepository) DeleteTask(ctx context.Context, id int64) error {
-	return r.queries.DeleteTask(ctx, id)
+func (r *taskRepository) DeleteTask(ctx context.Context, id int64, version int64) error {
+	rows, err := r.queries.DeleteTask(ctx, database.DeleteTaskParams{
+		ID:      id,
+		Version: version,
+	})
+	if err != nil {
+		return dbError(err, "task")
+	}
+	if rows == 0 {
+		return notFound("task")
+	}
+	return nil
 }
 
-func (r *taskRepository) UpdateStatus(ctx context.Context, id int64, status string) (*domain.Task, error) {
+func (r *taskRepository) UpdateStatus(ctx context.Context, id int64, version int64, status string) (*domain.Task, error) {
 	dbTask, err := r.queries.UpdateTaskStatus(ctx, database.UpdateTaskStatusParams{
 		ID: id,
 		Status: sql.NullString{
 			String: status,
 			Valid:  status != "",
 		},
+		Version: version,
 	})
 	if err != nil {
-		return nil, err
+		return nil, dbError(err, "task")
 	}
 
-	return &domain.Task{
-		Id:          dbTask.ID,
-		Title:       dbTask.Title,
-		Description: dbTask.Description.String,
-		Status:      dbTask.Status.String,
-		Priority:    dbTask.Priority.String,
-		Userid:      dbTask.UserID,
-		Duedate:     dbTask.DueDate.Time,
-		CompletedAt: dbTask.CompletedAt.Time,
-		UpdatedAt:   dbTask.UpdatedAt.Time,
-	}, nil
+	return toDomainTask(dbTask), nil
 }
 
 func (r *taskRepository) CreateTask(ctx context.Context, title string, description string, status string, priority string, userId int64, dueDate string) (*domain.Task, error) {
@@ -150,7 +223,7 @@ func (r *taskRepository) CreateTask(ctx context.Context, title string, descripti
 	if dueDate != "" {
 		parsedDate, err := time.Parse("2006-01-02", dueDate)
 		if err != nil {
-			return nil, fmt.Errorf("formato de fecha inválido: %w", err)
+			return nil, invalidDueDate(err)
 		}
 		nullDueDate = sql.NullTime{
 			Time:  parsedDate,
@@ -176,9 +249,33 @@ func (r *taskRepository) CreateTask(ctx context.Context, title string, descripti
 		DueDate: nullDueDate,
 	})
 	if err != nil {
-		return nil, err
+		return nil, dbError(err, "task")
 	}
 
+	return toDomainTask(dbTask), nil
+}
+
+func (r *taskRepository) GetTaskStats(ctx context.Context) (*domain.TaskStats, error) {
+	rows, err := r.queries.CountTasksByStatus(ctx)
+	if err != nil {
+		return nil, dbError(err, "task")
+	}
+	overdue, err := r.queries.CountOverdueTasks(ctx)
+	if err != nil {
+		return nil, dbError(err, "task")
+	}
+
+	stats := &domain.TaskStats{
+		ByStatus: make(map[string]int64, len(rows)),
+		Overdue:  overdue,
+	}
+	for _, row := range rows {
+		stats.ByStatus[row.Status] += row.Count
+	}
+	return stats, nil
+}
+
+func toDomainTask(dbTask database.Task) *domain.Task {
 	return &domain.Task{
 		Id:          dbTask.ID,
 		Title:       dbTask.Title,
@@ -189,5 +286,13 @@ func (r *taskRepository) CreateTask(ctx context.Context, title string, descripti
 		Duedate:     dbTask.DueDate.Time,
 		CompletedAt: dbTask.CompletedAt.Time,
 		UpdatedAt:   dbTask.UpdatedAt.Time,
-	}, nil
+		Version:     dbTask.Version,
+	}
+}
+
+func invalidDueDate(err error) error {
+	return apperrors.ErrBadRequest.
+		WithMessage("due_date must use the YYYY-MM-DD format").
+		WithDetail("field", "due_date").
+		Wrap(err)
 }
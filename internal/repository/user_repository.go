@@ -7,12 +7,17 @@ import (
 	"tasked/internal/domain"
 )
 
+// UserRepository updates and deletes only apply to the given version of a
+// user and return ErrNotFound when it has moved on. Version 0 matches any
+// version.
 type UserRepository interface {
 	GetUserById(ctx context.Context, id int64) (*domain.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*domain.User, error)
 	CreateUser(ctx context.Context, username string, email string, password string) (*domain.User, error)
-	UpdateUser(ctx context.Context, id int64, username string, email string) (*domain.User, error)
-	DeleteUser(ctx context.Context, id int64) error
+	UpdateUser(ctx context.Context, id int64, version int64, username string, email string) (*domain.User, error)
+	DeleteUser(ctx context.Context, id int64, version int64) error
+	UpdatePassword(ctx context.Context, id int64, password string) error
+	MarkEmailVerified(ctx context.Context, id int64) error
 }
 
 type userRepository struct {
@@ -28,32 +33,18 @@ func NewUserRepository(db *sql.DB) UserRepository {
 func (r *userRepository) GetUserById(ctx context.Context, id int64) (*domain.User, error) {
 	dbUser, err := r.queries.GetUserByID(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, dbError(err, "user")
 	}
-	return &domain.User{
-		ID:        dbUser.ID,
-		Username:  dbUser.Username,
-		Email:     dbUser.Email,
-		Password:  dbUser.Password,
-		CreatedAt: dbUser.CreatedAt.Time,
-		UpdatedAt: dbUser.UpdatedAt.Time,
-	}, nil
+	return toDomainUser(dbUser), nil
 }
 
 func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
 	dbUser, err := r.queries.GetUserByEmail(ctx, email)
 	if err != nil {
-		return nil, err
+		return nil, dbError(err, "user")
 	}
 
-	return &domain.User{
-		ID:        dbUser.ID,
-		Username:  dbUser.Username,
-		Email:     dbUser.Email,
-		Password:  dbUser.Password,
-		CreatedAt: dbUser.CreatedAt.Time,
-		UpdatedAt: dbUser.UpdatedAt.Time,
-	}, nil
+	return toDomainUser(dbUser), nil
 }
 
 func (r *userRepository) CreateUser(ctx context.Context, username string, email string, password string) (*domain.User, error) {
@@ -63,38 +54,60 @@ func (r *userRepository) CreateUser(ctx context.Context, username string, email
 		Password: password,
 	})
 	if err != nil {
-		return nil, err
+		return nil, dbError(err, "user")
 	}
 
-	return &domain.User{
-		ID:        dbUser.ID,
-		Username:  dbUser.Username,
-		Email:     dbUser.Email,
-		Password:  dbUser.Password,
-		CreatedAt: dbUser.CreatedAt.Time,
-		UpdatedAt: dbUser.UpdatedAt.Time,
-	}, nil
+	return toDomainUser(dbUser), nil
 }
 
-func (r *userRepository) UpdateUser(ctx context.Context, id int64, username string, email string) (*domain.User, error) {
+func (r *userRepository) UpdateUser(ctx context.Context, id int64, version int64, username string, email string) (*domain.User, error) {
 	dbUser, err := r.queries.UpdateUser(ctx, database.UpdateUserParams{
 		ID:       id,
 		Username: username,
 		Email:    email,
+		Version:  version,
 	})
 	if err != nil {
-		return nil, err
+		return nil, dbError(err, "user")
 	}
-	return &domain.User{
-		ID:        dbUser.ID,
-		Username:  dbUser.Username,
-		Email:     dbUser.Email,
-		Password:  dbUser.Password,
-		CreatedAt: dbUser.CreatedAt.Time,
-		UpdatedAt: dbUser.UpdatedAt.Time,
-	}, nil
+	return toDomainUser(dbUser), nil
+}
+
+func (r *userRepository) DeleteUser(ctx context.Context, id int64, version int64) error {
+	rows, err := r.queries.DeleteUser(ctx, database.DeleteUserParams{
+		ID:      id,
+		Version: version,
+	})
+	if err != nil {
+		return dbError(err, "user")
+	}
+	if rows == 0 {
+		return notFound("user")
+	}
+	return nil
 }
 
-func (r *userRepository) DeleteUser(ctx context.Context, id int64) error {
-	return r.queries.DeleteUser(ctx, id)
+func (r *userRepository) UpdatePassword(ctx context.Context, id int64, password string) error {
+	return r.queries.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+		ID:       id,
+		Password: password,
+	})
+}
+
+func (r *userRepository) MarkEmailVerified(ctx context.Context, id int64) error {
+	return r.queries.MarkUserEmailVerified(ctx, id)
+}
+
+func toDomainUser(dbUser database.User) *domain.User {
+	return &domain.User{
+		ID:              dbUser.ID,
+		Username:        dbUser.Username,
+		Email:           dbUser.Email,
+		Password:        dbUser.Password,
+		CreatedAt:       dbUser.CreatedAt.Time,
+		UpdatedAt:       dbUser.UpdatedAt.Time,
+		Version:         dbUser.Version,
+		EmailVerifiedAt: dbUser.EmailVerifiedAt.Time,
+		MFAEnabled:      dbUser.TotpEnabledAt.Valid,
+	}
 }
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"tasked/internal/database"
+	"time"
+)
+
+type UserTokenRepository interface {
+	CreateToken(ctx context.Context, userID int64, purpose string, tokenHash string, expiresAt time.Time) error
+	FindToken(ctx context.Context, purpose string, tokenHash string) (int64, error)
+	ConsumeToken(ctx context.Context, purpose string, tokenHash string) (int64, error)
+	DeleteTokens(ctx context.Context, userID int64, purpose string) error
+	DeleteExpiredTokens(ctx context.Context) (int64, error)
+}
+
+type userTokenRepository struct {
+	queries *database.Queries
+}
+
+func NewUserTokenRepository(db *sql.DB) UserTokenRepository {
+	return &userTokenRepository{
+		queries: database.New(db),
+	}
+}
+
+func (r *userTokenRepository) CreateToken(ctx context.Context, userID int64, purpose string, tokenHash string, expiresAt time.Time) error {
+	return r.queries.CreateUserToken(ctx, database.CreateUserTokenParams{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// FindToken returns the owner of a valid, unused token without spending it.
+// It returns ErrNotFound when the token is unknown, expired or spent.
+func (r *userTokenRepository) FindToken(ctx context.Context, purpose string, tokenHash string) (int64, error) {
+	userID, err := r.queries.GetUserTokenOwner(ctx, database.GetUserTokenOwnerParams{
+		TokenHash: tokenHash,
+		Purpose:   purpose,
+	})
+	if err != nil {
+		return 0, dbError(err, "token")
+	}
+	return userID, nil
+}
+
+// ConsumeToken marks a valid, unused token as used and returns its owner.
+// It returns ErrNotFound when the token is unknown, expired or spent.
+func (r *userTokenRepository) ConsumeToken(ctx context.Context, purpose string, tokenHash string) (int64, error) {
+	userID, err := r.queries.ConsumeUserToken(ctx, database.ConsumeUserTokenParams{
+		TokenHash: tokenHash,
+		Purpose:   purpose,
+	})
+	if err != nil {
+		return 0, dbError(err, "token")
+	}
+	return userID, nil
+}
+
+func (r *userTokenRepository) DeleteTokens(ctx context.Context, userID int64, purpose string) error {
+	return r.queries.DeleteUserTokens(ctx, database.DeleteUserTokensParams{
+		UserID:  userID,
+		Purpose: purpose,
+	})
+}
+
+func (r *userTokenRepository) DeleteExpiredTokens(ctx context.Context) (int64, error) {
+	return r.queries.DeleteExpiredUserTokens(ctx)
+}
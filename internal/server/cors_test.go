@@ -0,0 +1,87 @@
+package server_test
+
+import (
+	"net/http"
+	"tasked/internal/config"
+	"tasked/internal/server"
+	"testing"
+	"time"
+)
+
+func preflight(origin, method string) request {
+	return request{method: "OPTIONS", path: "/tasks", headers: map[string]string{
+		"Origin":                         origin,
+		"Access-Control-Request-Method":  method,
+		"Access-Control-Request-Headers": "Authorization, Content-Type",
+	}}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	a := newTestApp(t, server.Dependencies{}, func(cfg *config.Config) {
+		cfg.CORS.AllowedOrigins = []string{"https://app.example.com", "https://*.preview.example.com", "http://localhost:*"}
+		cfg.CORS.MaxAge = 10 * time.Minute
+	})
+
+	for _, origin := range []string{"https://app.example.com", "https://pr-42.preview.example.com", "http://localhost:5173"} {
+		t.Run(origin, func(t *testing.T) {
+			w := a.expect(t, preflight(origin, "POST"), http.StatusNoContent, nil)
+			header := w.Header()
+			if got := header.Get("Access-Control-Allow-Origin"); got != origin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, origin)
+			}
+			if got := header.Get("Access-Control-Allow-Methods"); got != "GET,POST,PUT,PATCH,DELETE" {
+				t.Errorf("Access-Control-Allow-Methods = %q", got)
+			}
+			if got := header.Get("Access-Control-Allow-Headers"); got == "" {
+				t.Error("no Access-Control-Allow-Headers")
+			}
+			if got := header.Get("Access-Control-Max-Age"); got != "600" {
+				t.Errorf("Access-Control-Max-Age = %q, want 600", got)
+			}
+			if got := header.Get("Access-Control-Allow-Credentials"); got != "" {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want none", got)
+			}
+		})
+	}
+
+	for _, origin := range []string{"https://evil.example.com", "https://app.example.com.evil.com", "https://preview.example.com", "http://localhost.evil.com"} {
+		t.Run(origin, func(t *testing.T) {
+			w := a.expect(t, preflight(origin, "POST"), http.StatusForbidden, nil)
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+				t.Errorf("Access-Control-Allow-Origin = %q, want none", got)
+			}
+		})
+	}
+
+	w := a.expect(t, request{method: "GET", path: "/healthz", headers: map[string]string{
+		"Origin": "https://app.example.com",
+	}}, http.StatusOK, nil)
+	if got := w.Header().Get("Access-Control-Expose-Headers"); got == "" {
+		t.Error("no Access-Control-Expose-Headers on a simple request")
+	}
+}
+
+func TestCORSCredentials(t *testing.T) {
+	a := newTestApp(t, server.Dependencies{}, func(cfg *config.Config) {
+		cfg.CORS.AllowedOrigins = []string{"https://app.example.com"}
+		cfg.CORS.AllowCredentials = true
+	})
+	w := a.expect(t, preflight("https://app.example.com", "DELETE"), http.StatusNoContent, nil)
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want true", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want the origin rather than *", got)
+	}
+}
+
+func TestCORSDisabled(t *testing.T) {
+	a := newTestApp(t, server.Dependencies{}, func(cfg *config.Config) {
+		cfg.CORS.AllowedOrigins = nil
+	})
+	w := a.do(t, preflight("https://app.example.com", "POST"))
+	if w.Code == http.StatusNoContent || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("preflight = %d with Access-Control-Allow-Origin %q, want it refused",
+			w.Code, w.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
@@ -0,0 +1,134 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"tasked/internal/domain"
+	"tasked/internal/handler"
+	"tasked/internal/server"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bodyRecorder copies the response body as it is written.
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w bodyRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+var bcryptHash = regexp.MustCompile(`\$2[aby]\$\d\d\$`)
+
+// TestNoCredentialsInResponses walks every route that returns a user, or
+// could by mistake, and checks that no response carries a password or its
+// hash.
+func TestNoCredentialsInResponses(t *testing.T) {
+	const newPassword = "N3w-passw0rd"
+	for _, s := range storages {
+		t.Run(s.name, func(t *testing.T) {
+			type response struct {
+				route string
+				body  *bytes.Buffer
+			}
+			var responses []response
+			deps := s.open(t)
+			a := newTestApp(t, deps, nil, server.WithMiddleware(func(c *gin.Context) {
+				body := &bytes.Buffer{}
+				responses = append(responses, response{c.Request.Method + " " + c.Request.URL.Path, body})
+				c.Writer = bodyRecorder{c.Writer, body}
+			}))
+
+			ana, token := a.signUp(t, "ana")
+			bea, _ := a.signUp(t, "bea")
+			anaPath := fmt.Sprintf("/users/%d", ana.ID)
+			beaPath := fmt.Sprintf("/users/%d", bea.ID)
+			ifMatchAny := map[string]string{"If-Match": "*"}
+
+			var own map[string]any
+			a.expect(t, request{method: "GET", path: anaPath, token: token}, http.StatusOK, &own)
+			if own["email"] != "ana@example.com" {
+				t.Errorf("own user = %v, want the email included", own)
+			}
+			var other map[string]any
+			a.expect(t, request{method: "GET", path: beaPath, token: token}, http.StatusOK, &other)
+			if _, ok := other["email"]; ok || other["username"] != "bea" {
+				t.Errorf("other user = %v, want the public fields only", other)
+			}
+
+			signUp := request{method: "POST", path: "/users", headers: map[string]string{"Idempotency-Key": "signup-cleo"},
+				body: handler.CreateUserRequest{Username: "cleo", Email: "cleo@example.com", Password: testPassword}}
+			a.expect(t, signUp, http.StatusCreated, nil)
+			if w := a.expect(t, signUp, http.StatusCreated, nil); w.Header().Get("Idempotent-Replayed") != "true" {
+				t.Error("sign-up was not replayed")
+			}
+
+			var task domain.Task
+			a.expect(t, request{method: "POST", path: "/tasks", token: token, body: handler.CreateTaskRequest{
+				Title: "report", UserID: ana.ID,
+			}}, http.StatusCreated, &task)
+			for _, r := range []request{
+				{method: "PUT", path: anaPath, token: token, headers: ifMatchAny, body: handler.UpdateUserRequest{Username: "ana2", Email: "ana@example.com"}},
+				{method: "GET", path: fmt.Sprintf("/tasks/%d", task.Id), token: token},
+				{method: "GET", path: anaPath + "/tasks", token: token},
+				{method: "POST", path: "/tokens", token: token, body: handler.CreateAccessTokenRequest{Name: "ci", Scopes: []string{domain.ScopeTasksRead}, ExpiresInDays: 1}},
+				{method: "GET", path: "/tokens", token: token},
+				{method: "GET", path: "/sessions", token: token},
+				{method: "POST", path: "/auth/mfa/enroll", token: token},
+				{method: "POST", path: "/auth/forgot-password", body: handler.ForgotPasswordRequest{Email: "ana@example.com"}},
+			} {
+				if w := a.do(t, r); w.Code >= 300 {
+					t.Fatalf("%s %s = %d: %s", r.method, r.path, w.Code, w.Body)
+				}
+			}
+			a.expect(t, request{method: "PUT", path: anaPath + "/password", token: token, body: handler.ChangePasswordRequest{
+				CurrentPassword: testPassword, NewPassword: newPassword,
+			}}, http.StatusOK, nil)
+			a.expect(t, request{method: "POST", path: "/login", body: handler.LoginRequest{
+				Email: "ana@example.com", Password: testPassword,
+			}}, http.StatusUnauthorized, nil)
+			a.login(t, "ana@example.com", newPassword)
+
+			secrets := []string{testPassword, newPassword}
+			for _, id := range []int64{ana.ID, bea.ID} {
+				user, err := deps.Repositories.Users.GetUserById(context.Background(), id)
+				if err != nil {
+					t.Fatalf("get user %d: %v", id, err)
+				}
+				secrets = append(secrets, user.Password)
+			}
+			for _, r := range responses {
+				body := r.body.String()
+				if bcryptHash.MatchString(body) || strings.Contains(body, `"password"`) {
+					t.Errorf("%s answered with a password hash: %s", r.route, body)
+				}
+				for _, secret := range secrets {
+					if strings.Contains(body, secret) || strings.Contains(body, jsonString(secret)) {
+						t.Errorf("%s answered with a credential: %s", r.route, body)
+					}
+				}
+			}
+		})
+	}
+}
+
+// jsonString is s as encoding/json writes it, escaping characters such as
+// the + and / of a hash.
+func jsonString(s string) string {
+	data, _ := json.Marshal(s)
+	return strings.Trim(string(data), `"`)
+}
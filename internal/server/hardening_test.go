@@ -0,0 +1,119 @@
+package server_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"tasked/internal/config"
+	"tasked/internal/server"
+	"testing"
+	"time"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	a := newTestApp(t, server.Dependencies{}, nil)
+	for _, path := range []string{"/healthz", "/missing"} {
+		w := a.do(t, request{method: "GET", path: path})
+		for name, want := range map[string]string{
+			"X-Content-Type-Options": "nosniff",
+			"X-Frame-Options":        "DENY",
+			"Referrer-Policy":        "no-referrer",
+		} {
+			if got := w.Header().Get(name); got != want {
+				t.Errorf("GET %s: %s = %q, want %q", path, name, got, want)
+			}
+		}
+		if got := w.Header().Get("Content-Security-Policy"); !strings.HasPrefix(got, "default-src 'none'") {
+			t.Errorf("GET %s: Content-Security-Policy = %q, want one allowing nothing", path, got)
+		}
+		if got := w.Header().Get("Strict-Transport-Security"); got != "" {
+			t.Errorf("GET %s: Strict-Transport-Security = %q without HSTS configured", path, got)
+		}
+	}
+
+	w := a.do(t, request{method: "GET", path: "/swagger/index.html"})
+	if got := w.Header().Get("Content-Security-Policy"); !strings.Contains(got, "script-src 'self'") {
+		t.Errorf("swagger Content-Security-Policy = %q, want one allowing its scripts", got)
+	}
+
+	a = newTestApp(t, server.Dependencies{}, func(cfg *config.Config) {
+		cfg.Server.HSTSMaxAge = 24 * time.Hour
+	})
+	w = a.do(t, request{method: "GET", path: "/healthz"})
+	if got := w.Header().Get("Strict-Transport-Security"); got != "max-age=86400; includeSubDomains" {
+		t.Errorf("Strict-Transport-Security = %q", got)
+	}
+}
+
+// send posts body as is, hiding its length when chunked is set.
+func (a *testApp) send(t *testing.T, method, path, contentType, body string, chunked bool, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader = strings.NewReader(body)
+	if chunked {
+		reader = io.MultiReader(reader)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if chunked {
+		req.ContentLength = -1
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	a.Handler().ServeHTTP(w, req)
+	return w
+}
+
+func TestBodyLimit(t *testing.T) {
+	a := newTestApp(t, server.Dependencies{}, func(cfg *config.Config) {
+		cfg.Server.MaxBodyBytes = 256
+	})
+	user, token := a.signUp(t, "ana")
+	large := `{"title":"` + strings.Repeat("x", 512) + `"}`
+
+	tests := []struct {
+		name    string
+		path    string
+		headers map[string]string
+	}{
+		{"handler", "/users", nil},
+		{"login rate limit", "/login", nil},
+		{"idempotency", "/tasks", map[string]string{"Authorization": "Bearer " + token, "Idempotency-Key": "large"}},
+	}
+	for _, tt := range tests {
+		for _, chunked := range []bool{false, true} {
+			w := a.send(t, "POST", tt.path, "application/json", large, chunked, tt.headers)
+			if w.Code != http.StatusRequestEntityTooLarge {
+				t.Errorf("%s, chunked %t: POST %s = %d, want 413: %s", tt.name, chunked, tt.path, w.Code, w.Body)
+			}
+		}
+	}
+
+	w := a.send(t, "POST", "/tasks", "application/json", fmt.Sprintf(`{"title":"small","user_id":%d}`, user.ID), true,
+		map[string]string{"Authorization": "Bearer " + token})
+	if w.Code != http.StatusCreated {
+		t.Errorf("POST /tasks under the limit = %d, want 201: %s", w.Code, w.Body)
+	}
+}
+
+func TestRequireJSON(t *testing.T) {
+	a := newTestApp(t, server.Dependencies{}, nil)
+	body := `{"username":"ana","email":"ana@example.com","password":"` + testPassword + `"}`
+
+	for _, contentType := range []string{"", "text/plain", "application/x-www-form-urlencoded", "application/xml", "application/json;charset"} {
+		w := a.send(t, "POST", "/users", contentType, body, false, nil)
+		if w.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("POST /users as %q = %d, want 415: %s", contentType, w.Code, w.Body)
+		}
+	}
+
+	w := a.send(t, "POST", "/users", "application/json; charset=utf-8", body, false, nil)
+	if w.Code != http.StatusCreated {
+		t.Errorf("POST /users as JSON = %d, want 201: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"tasked/internal/clock"
+	"tasked/internal/config"
+	"tasked/internal/mailer"
+	"tasked/internal/ratelimit"
+	"tasked/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+type options struct {
+	repos          *repository.Repositories
+	mailer         mailer.Mailer
+	clock          clock.Clock
+	rateLimitStore ratelimit.Store
+	middleware     []gin.HandlerFunc
+}
+
+type Option func(*options)
+
+// WithRepositories replaces Dependencies.Repositories.
+func WithRepositories(repos *repository.Repositories) Option {
+	return func(o *options) {
+		o.repos = repos
+	}
+}
+
+// WithMailer replaces the mailer that delivers password reset and email
+// verification messages.
+func WithMailer(m mailer.Mailer) Option {
+	return func(o *options) {
+		o.mailer = m
+	}
+}
+
+// WithClock replaces the clock used for tokens, sessions, rate limits and
+// expiry checks. Repositories keep their own time; give in-memory ones the
+// same clock.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithRateLimitStore replaces the in-process store of login rate limits and
+// lockouts, for instance with one shared between instances.
+func WithRateLimitStore(store ratelimit.Store) Option {
+	return func(o *options) {
+		o.rateLimitStore = store
+	}
+}
+
+// WithMiddleware adds handlers that run on every request after the built-in
+// middleware and before the route's own.
+func WithMiddleware(handlers ...gin.HandlerFunc) Option {
+	return func(o *options) {
+		o.middleware = append(o.middleware, handlers...)
+	}
+}
+
+func newOptions(cfg *config.Config, deps Dependencies, opts []Option) options {
+	o := options{
+		repos: deps.Repositories,
+		clock: clock.System,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.repos == nil {
+		o.repos = repository.NewMemoryRepositories(o.clock)
+	}
+	if o.mailer == nil {
+		o.mailer = mailer.NewLogMailer(cfg.Mail.LogPath)
+		if cfg.Mail.Mailer == "smtp" {
+			o.mailer = mailer.NewSMTPMailer(cfg.Mail.SMTPHost, cfg.Mail.SMTPPort, cfg.Mail.SMTPUsername, cfg.Mail.SMTPPassword, cfg.Mail.From)
+		}
+	}
+	if o.rateLimitStore == nil {
+		o.rateLimitStore = ratelimit.NewMemoryStore(o.clock)
+	}
+	return o
+}
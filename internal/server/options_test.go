@@ -0,0 +1,149 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"tasked/internal/clock"
+	"tasked/internal/config"
+	"tasked/internal/domain"
+	"tasked/internal/handler"
+	"tasked/internal/repository"
+	"tasked/internal/server"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWithClock(t *testing.T) {
+	now := clock.NewFake(time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC))
+	a := newTestApp(t, server.Dependencies{}, nil,
+		server.WithRepositories(repository.NewMemoryRepositories(now)),
+		server.WithClock(now),
+	)
+	_, token := a.signUp(t, "ana")
+
+	var created handler.CreateAccessTokenResponse
+	a.expect(t, request{method: "POST", path: "/tokens", token: token, body: handler.CreateAccessTokenRequest{
+		Name: "ci", Scopes: []string{domain.ScopeAdmin}, ExpiresInDays: 2,
+	}}, http.StatusCreated, &created)
+	if want := now.Now().Add(48 * time.Hour); !created.AccessToken.ExpiresAt.Equal(want) {
+		t.Errorf("token expires at %v, want %v", created.AccessToken.ExpiresAt, want)
+	}
+
+	// testConfig issues access tokens for an hour.
+	now.Advance(2 * time.Hour)
+	a.expect(t, request{method: "GET", path: "/sessions", token: token}, http.StatusUnauthorized, nil)
+	a.expect(t, request{method: "GET", path: "/sessions", token: created.Token}, http.StatusOK, nil)
+
+	now.Advance(2 * 24 * time.Hour)
+	a.expect(t, request{method: "GET", path: "/sessions", token: created.Token}, http.StatusUnauthorized, nil)
+}
+
+func TestIdempotencyFollowsClock(t *testing.T) {
+	for _, s := range storages {
+		t.Run(s.name, func(t *testing.T) {
+			now := clock.NewFake(time.Now().Add(365 * 24 * time.Hour))
+			a := newTestApp(t, s.open(t), func(cfg *config.Config) {
+				cfg.Idempotency.TTL = time.Minute
+			}, server.WithClock(now))
+			user, token := a.signUp(t, "ana")
+
+			create := request{method: "POST", path: "/tasks", token: token, body: handler.CreateTaskRequest{
+				Title: "Informe", UserID: user.ID,
+			}, headers: map[string]string{"Idempotency-Key": "create-informe"}}
+			var first, second domain.Task
+			a.expect(t, create, http.StatusCreated, &first)
+			w := a.expect(t, create, http.StatusCreated, &second)
+			if w.Header().Get("Idempotent-Replayed") != "true" || second.Id != first.Id {
+				t.Fatal("repeated create was not replayed")
+			}
+
+			// The key expires by the injected clock, however far it is
+			// from the database's.
+			now.Advance(2 * time.Minute)
+			w = a.expect(t, create, http.StatusCreated, &second)
+			if w.Header().Get("Idempotent-Replayed") != "" || second.Id == first.Id {
+				t.Fatalf("create after the key expired replayed task %d", first.Id)
+			}
+		})
+	}
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	now := clock.NewFake(time.Now())
+	repos := repository.NewMemoryRepositories(now)
+	tasks := &heldTasks{TaskRepository: repos.Tasks, started: make(chan struct{}), release: make(chan struct{})}
+	repos.Tasks = tasks
+	a := newTestApp(t, server.Dependencies{}, nil, server.WithRepositories(repos), server.WithClock(now))
+	user, token := a.signUp(t, "ana")
+
+	create := request{method: "POST", path: "/tasks", token: token, body: handler.CreateTaskRequest{
+		Title: "Informe", UserID: user.ID,
+	}, headers: map[string]string{"Idempotency-Key": "create-informe"}}
+	first := make(chan *httptest.ResponseRecorder)
+	go func() { first <- a.do(t, create) }()
+	<-tasks.started
+
+	// A duplicate gives up after a bounded wait even though the injected
+	// clock never moves.
+	duplicate := make(chan *httptest.ResponseRecorder)
+	go func() { duplicate <- a.do(t, create) }()
+	select {
+	case w := <-duplicate:
+		if w.Code != http.StatusConflict || w.Header().Get("Retry-After") == "" {
+			t.Fatalf("duplicate in flight = %d (Retry-After %q), want 409", w.Code, w.Header().Get("Retry-After"))
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("duplicate still waiting for the request in flight")
+	}
+
+	// One arriving just before the original finishes gets its response.
+	go func() { duplicate <- a.do(t, create) }()
+	time.Sleep(300 * time.Millisecond)
+	close(tasks.release)
+	original, replayed := <-first, <-duplicate
+	if original.Code != http.StatusCreated || replayed.Code != http.StatusCreated {
+		t.Fatalf("original = %d, duplicate = %d, want 201", original.Code, replayed.Code)
+	}
+	if replayed.Header().Get("Idempotent-Replayed") != "true" || replayed.Body.String() != original.Body.String() {
+		t.Errorf("duplicate was not replayed: %s", replayed.Body)
+	}
+}
+
+// heldTasks blocks the first task creation until release is closed.
+type heldTasks struct {
+	repository.TaskRepository
+	once    sync.Once
+	started chan struct{}
+	release chan struct{}
+}
+
+func (r *heldTasks) CreateTask(ctx context.Context, title string, description string, status string, priority string, userId int64, dueDate string) (*domain.Task, error) {
+	r.once.Do(func() {
+		close(r.started)
+		<-r.release
+	})
+	return r.TaskRepository.CreateTask(ctx, title, description, status, priority, userId, dueDate)
+}
+
+func TestWithMiddleware(t *testing.T) {
+	var routes []string
+	a := newTestApp(t, server.Dependencies{}, nil, server.WithMiddleware(
+		func(c *gin.Context) {
+			routes = append(routes, c.FullPath())
+			c.Header("X-Embedded", "yes")
+		},
+	))
+
+	w := a.expect(t, request{method: "GET", path: "/healthz"}, http.StatusOK, nil)
+	if w.Header().Get("X-Embedded") != "yes" {
+		t.Error("middleware did not run")
+	}
+	a.expect(t, request{method: "GET", path: "/tasks/1"}, http.StatusUnauthorized, nil)
+	if len(routes) != 2 || routes[1] != "/tasks/:id" {
+		t.Errorf("middleware saw routes %q, want /healthz and /tasks/:id", routes)
+	}
+}
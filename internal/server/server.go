@@ -0,0 +1,300 @@
+// Package server assembles the tasked API: it wires the services and
+// handlers over a set of repositories and serves them over HTTP. It is what
+// the tasked command runs, and lets other binaries and tests embed the API.
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"tasked/internal/auth"
+	"tasked/internal/config"
+	"tasked/internal/domain"
+	apperrors "tasked/internal/errors"
+	"tasked/internal/handler"
+	"tasked/internal/health"
+	"tasked/internal/metrics"
+	"tasked/internal/middleware"
+	"tasked/internal/ratelimit"
+	"tasked/internal/repository"
+	"tasked/internal/services"
+	"tasked/internal/utils"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+)
+
+// Dependencies are the resources the server uses but does not own. The
+// caller opens them before New and closes them after Shutdown.
+type Dependencies struct {
+	// DB, when set, has its connection pool reported in /metrics.
+	DB *sql.DB
+	// Repositories hold the data; the server keeps it in memory when nil.
+	Repositories *repository.Repositories
+	// Checks must pass for /readyz to report the server ready.
+	Checks []health.Check
+}
+
+type Server struct {
+	router   *gin.Engine
+	http     *http.Server
+	health   *handler.HealthHandler
+	accounts *services.AccountService
+	// drainDelay is how long Shutdown keeps serving after failing the
+	// readiness probe.
+	drainDelay time.Duration
+	// purge deletes expired idempotency keys, rate limit state, account
+	// tokens, OIDC logins and sessions.
+	purge   func(ctx context.Context)
+	workers sync.WaitGroup
+}
+
+// New builds a server listening on cfg.Server.Port. Options replace the defaults:
+// the mailer chosen in cfg, the system clock and an in-process rate limit
+// store.
+func New(cfg *config.Config, deps Dependencies, opts ...Option) *Server {
+	o := newOptions(cfg, deps, opts)
+	s := &Server{drainDelay: cfg.Server.DrainDelay}
+
+	tokenManager := auth.NewTokenManager(cfg.JWT.Secret, cfg.JWT.Expiry, o.clock)
+
+	passwordPolicy := utils.PasswordPolicy{
+		MinLength:      cfg.Account.PasswordMinLength,
+		MinClasses:     cfg.Account.PasswordMinClasses,
+		CheckBlocklist: cfg.Account.PasswordBlocklist,
+	}
+
+	userRepo := o.repos.Users
+	userTokenRepo := o.repos.UserTokens
+	accountService := services.NewAccountService(userRepo, userTokenRepo, o.mailer, passwordPolicy, cfg.Mail.AppBaseURL, cfg.Account.EmailVerification, o.clock)
+	s.accounts = accountService
+	userService := services.NewUserService(userRepo, passwordPolicy)
+	sessionRepo := o.repos.Sessions
+	sessionService := services.NewSessionService(sessionRepo, o.clock)
+	sessionHandler := handler.NewSessionHandler(sessionService)
+
+	mfaRepo := o.repos.MFA
+	mfaService := services.NewMFAService(userRepo, mfaRepo, userTokenRepo, cfg.Account.TOTPIssuer, o.clock)
+
+	userHandler := handler.NewUserHandler(userService, accountService, sessionService, mfaService, tokenManager)
+	authHandler := handler.NewAuthHandler(accountService)
+	mfaHandler := handler.NewMFAHandler(mfaService, sessionService, tokenManager)
+
+	oidcRepo := o.repos.OIDC
+
+	accessTokenRepo := o.repos.AccessTokens
+	accessTokenService := services.NewAccessTokenService(accessTokenRepo, userRepo, o.clock)
+	accessTokenHandler := handler.NewAccessTokenHandler(accessTokenService)
+
+	taskRepo := o.repos.Tasks
+	taskService := services.NewTaskService(taskRepo)
+	taskHandler := handler.NewTaskHandler(taskService)
+
+	idempotencyRepo := o.repos.Idempotency
+
+	loginLimiter := ratelimit.NewLimiter(o.rateLimitStore,
+		ratelimit.Limit{Requests: cfg.RateLimit.LoginIPLimit, Window: time.Minute},
+		ratelimit.Limit{Requests: cfg.RateLimit.LoginAccountLimit, Window: time.Minute},
+		ratelimit.Lockout{
+			Threshold: cfg.RateLimit.LockoutThreshold,
+			Base:      cfg.RateLimit.LockoutBase,
+			Max:       cfg.RateLimit.LockoutMax,
+			Window:    15 * time.Minute,
+		},
+		o.clock,
+	)
+	// Reset requests never fail or lock anything; they only spend tokens.
+	// The login limiter's cleanup also purges these buckets, which refill
+	// within its idle time.
+	resetLimiter := ratelimit.NewLimiter(ratelimit.Prefix(o.rateLimitStore, "reset:"),
+		ratelimit.Limit{Requests: cfg.RateLimit.ResetIPLimit, Window: 15 * time.Minute},
+		ratelimit.Limit{Requests: cfg.RateLimit.ResetEmailLimit, Window: 15 * time.Minute},
+		ratelimit.Lockout{},
+		o.clock,
+	)
+
+	s.purge = func(ctx context.Context) {
+		if _, err := idempotencyRepo.DeleteExpiredKeys(ctx, o.clock.Now()); err != nil {
+			slog.Error("failed to purge idempotency keys", "error", err)
+		}
+		if err := loginLimiter.Cleanup(ctx); err != nil {
+			slog.Error("failed to purge rate limit state", "error", err)
+		}
+		if _, err := accountService.PurgeExpiredTokens(ctx); err != nil {
+			slog.Error("failed to purge account tokens", "error", err)
+		}
+		if _, err := oidcRepo.DeleteExpiredLoginStates(ctx); err != nil {
+			slog.Error("failed to purge oidc login states", "error", err)
+		}
+		if _, err := sessionService.PurgeStale(ctx); err != nil {
+			slog.Error("failed to purge sessions", "error", err)
+		}
+	}
+
+	router := gin.New()
+	var trustedProxies []string
+	if len(cfg.Server.TrustedProxies) > 0 {
+		trustedProxies = cfg.Server.TrustedProxies
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		// Validate rejects anything SetTrustedProxies would.
+		panic(fmt.Sprintf("server: trusted proxies: %v", err))
+	}
+	router.Use(otelgin.Middleware(cfg.Tracing.ServiceName), middleware.RequestID(), middleware.Logger(), middleware.Metrics(), middleware.Errors(), middleware.Recovery(), middleware.SecurityHeaders(cfg.Server.HSTSMaxAge))
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		router.Use(cors.New(cors.Config{
+			AllowOrigins:     cfg.CORS.AllowedOrigins,
+			AllowWildcard:    true,
+			AllowMethods:     cfg.CORS.AllowedMethods,
+			AllowHeaders:     cfg.CORS.AllowedHeaders,
+			ExposeHeaders:    cfg.CORS.ExposedHeaders,
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			MaxAge:           cfg.CORS.MaxAge,
+		}))
+	}
+	router.Use(middleware.BodyLimit(int64(cfg.Server.MaxBodyBytes)), middleware.RequireJSON())
+	router.Use(o.middleware...)
+
+	s.health = handler.NewHealthHandler(deps.Checks...)
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(func(c *gin.Context) {
+		c.Error(apperrors.ErrNotFound.WithMessage("route not found"))
+	})
+	router.NoMethod(func(c *gin.Context) {
+		c.Error(apperrors.ErrMethodNotAllowed)
+	})
+
+	router.GET("/healthz", s.health.Liveness)
+	router.GET("/readyz", s.health.Readiness)
+
+	if cfg.Features.Swagger {
+		router.GET("/swagger/*any", middleware.ContentSecurityPolicy(middleware.SwaggerCSP), ginSwagger.WrapHandler(swaggerFiles.Handler))
+	}
+	if cfg.Features.Metrics {
+		router.GET("/metrics", gin.WrapH(metrics.Handler(metrics.NewRegistry(deps.DB, taskService))))
+	}
+
+	authMiddleware := middleware.AuthRequired(tokenManager, sessionService, accessTokenService)
+	idempotency := middleware.Idempotency(idempotencyRepo, cfg.Idempotency.TTL, o.clock)
+	verified := func(c *gin.Context) { c.Next() }
+	if cfg.Account.EmailVerification == services.VerificationRestricted {
+		verified = middleware.RequireVerifiedEmail(accountService)
+	}
+
+	tasksRead := middleware.RequireScope(domain.ScopeTasksRead)
+	tasksWrite := middleware.RequireScope(domain.ScopeTasksWrite)
+	admin := middleware.RequireScope(domain.ScopeAdmin)
+
+	router.POST("/login", middleware.LoginRateLimit(loginLimiter, middleware.LoginEmail), userHandler.Login)
+	router.POST("/users", idempotency, userHandler.CreateUser)
+
+	router.POST("/auth/forgot-password", middleware.LoginRateLimit(resetLimiter, middleware.LoginEmail), authHandler.ForgotPassword)
+	router.POST("/auth/reset-password", authHandler.ResetPassword)
+	router.POST("/auth/verify-email", authHandler.VerifyEmail)
+	router.POST("/auth/resend-verification", authMiddleware, admin, authHandler.ResendVerification)
+
+	router.POST("/auth/mfa", middleware.LoginRateLimit(loginLimiter, middleware.MFAChallengeUser(tokenManager)), mfaHandler.Challenge)
+	router.POST("/auth/mfa/enroll", authMiddleware, admin, mfaHandler.Enroll)
+	router.POST("/auth/mfa/verify", authMiddleware, admin, mfaHandler.VerifyEnrollment)
+	router.POST("/auth/mfa/disable", authMiddleware, admin, mfaHandler.Disable)
+
+	if cfg.OIDC.Issuer != "" {
+		oidcProvider := auth.NewOIDCProvider(cfg.OIDC.Issuer, cfg.OIDC.ClientID, cfg.OIDC.ClientSecret, cfg.OIDC.RedirectURL)
+		oidcService := services.NewOIDCService(oidcProvider, userRepo, oidcRepo, cfg.OIDC.AutoCreate, o.clock)
+		oidcHandler := handler.NewOIDCHandler(oidcService, sessionService, mfaService, tokenManager)
+
+		router.GET("/auth/oidc/login", oidcHandler.Login)
+		router.GET("/auth/oidc/callback", oidcHandler.Callback)
+	}
+
+	router.POST("/tokens", authMiddleware, admin, accessTokenHandler.CreateToken)
+	router.GET("/tokens", authMiddleware, admin, accessTokenHandler.ListTokens)
+	router.DELETE("/tokens/:id", authMiddleware, admin, accessTokenHandler.DeleteToken)
+
+	router.GET("/sessions", authMiddleware, admin, sessionHandler.ListSessions)
+	router.DELETE("/sessions", authMiddleware, admin, sessionHandler.RevokeOtherSessions)
+	router.DELETE("/sessions/:id", authMiddleware, admin, sessionHandler.RevokeSession)
+
+	router.GET("/users/:id", authMiddleware, admin, userHandler.GetUser)
+	router.PUT("/users/:id", authMiddleware, admin, verified, userHandler.UpdateUser)
+	router.DELETE("/users/:id", authMiddleware, admin, verified, userHandler.DeleteUser)
+	router.PUT("/users/:id/password", authMiddleware, admin, middleware.LoginRateLimit(loginLimiter, middleware.AuthenticatedUser), userHandler.ChangePassword)
+
+	router.GET("/tasks/:id", authMiddleware, tasksRead, taskHandler.GetTask)
+	router.GET("/users/:id/tasks", authMiddleware, tasksRead, taskHandler.ListTasksByUser)
+	router.PUT("/tasks/:id", authMiddleware, tasksWrite, verified, taskHandler.UpdateTask)
+	router.PATCH("/tasks/:id", authMiddleware, tasksWrite, verified, taskHandler.PatchTask)
+	router.PATCH("/tasks/:id/status", authMiddleware, tasksWrite, verified, taskHandler.UpdateStatus)
+	router.DELETE("/tasks/:id", authMiddleware, tasksWrite, verified, taskHandler.DeleteTask)
+	router.POST("/tasks", authMiddleware, tasksWrite, verified, idempotency, taskHandler.CreateTask)
+	router.POST("/tasks/batch", authMiddleware, tasksWrite, verified, idempotency, taskHandler.BatchTasks)
+
+	s.router = router
+	s.http = &http.Server{
+		Addr:              ":" + cfg.Server.Port,
+		Handler:           router,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+	return s
+}
+
+// Handler returns the API for mounting in another server or calling from
+// tests. Requests it serves are not drained by Shutdown.
+func (s *Server) Handler() http.Handler {
+	return s.router
+}
+
+// Start serves HTTP and purges expired data every hour until ctx is done.
+// It blocks until the server fails or Shutdown is called, returning nil in
+// the latter case.
+func (s *Server) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s.workers.Go(func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			s.purge(ctx)
+		}
+	})
+
+	err := s.http.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown fails the readiness probe and keeps serving for the configured
+// drain delay, then stops accepting connections and waits until in-flight
+// requests finish or ctx is done, then for mail still being sent in the
+// background.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.health.Drain()
+	select {
+	case <-time.After(s.drainDelay):
+	case <-ctx.Done():
+	}
+	err := s.http.Shutdown(ctx)
+	s.workers.Wait()
+	s.accounts.Wait()
+	return err
+}
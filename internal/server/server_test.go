@@ -0,0 +1,912 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"tasked/internal/auth"
+	"tasked/internal/auth/oidctest"
+	"tasked/internal/clock"
+	"tasked/internal/config"
+	"tasked/internal/domain"
+	"tasked/internal/handler"
+	"tasked/internal/health"
+	"tasked/internal/mailer"
+	"tasked/internal/pgtest"
+	"tasked/internal/repository"
+	"tasked/internal/server"
+	"tasked/internal/services"
+	"tasked/internal/storage"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testPassword = "S3cure-passw0rd"
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	os.Exit(pgtest.Main(m))
+}
+
+// storages opens a fresh store of each kind for every test.
+var storages = []struct {
+	name string
+	open func(t *testing.T) server.Dependencies
+}{
+	{"memory", func(t *testing.T) server.Dependencies {
+		return server.Dependencies{Repositories: repository.NewMemoryRepositories(clock.System)}
+	}},
+	{"sqlite", func(t *testing.T) server.Dependencies {
+		return openStorage(t, "sqlite://"+filepath.Join(t.TempDir(), "tasked.db"))
+	}},
+	{"postgres", func(t *testing.T) server.Dependencies {
+		return openStorage(t, pgtest.New(t))
+	}},
+}
+
+func openStorage(t *testing.T, url string) server.Dependencies {
+	t.Helper()
+	ctx := context.Background()
+	db, err := storage.Open(ctx, url)
+	if err != nil {
+		t.Fatalf("open %s: %v", url, err)
+	}
+	t.Cleanup(func() { db.Close() })
+	migrator, err := db.Migrator()
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return server.Dependencies{
+		DB:           db.DB,
+		Repositories: db.Repositories(),
+		Checks:       []health.Check{health.Database(db.DB), health.Migrations(migrator)},
+	}
+}
+
+// forEachStorage runs fn against an app on every storage.
+func forEachStorage(t *testing.T, configure func(cfg *config.Config), fn func(t *testing.T, app *testApp)) {
+	for _, s := range storages {
+		t.Run(s.name, func(t *testing.T) {
+			fn(t, newTestApp(t, s.open(t), configure))
+		})
+	}
+}
+
+type testApp struct {
+	*server.Server
+	mail *capturingMailer
+}
+
+func testConfig() *config.Config {
+	cfg := config.Default()
+	cfg.JWT.Secret = "test-secret-test-secret-test-secret"
+	cfg.JWT.Expiry = time.Hour
+	cfg.Idempotency.TTL = time.Hour
+	cfg.RateLimit.LoginIPLimit = 1000
+	cfg.RateLimit.LoginAccountLimit = 1000
+	cfg.RateLimit.LockoutThreshold = 1000
+	cfg.Mail.AppBaseURL = "http://tasked.test"
+	cfg.Account.EmailVerification = services.VerificationRestricted
+	cfg.Account.PasswordBlocklist = false
+	return cfg
+}
+
+func newTestApp(t *testing.T, deps server.Dependencies, configure func(cfg *config.Config), opts ...server.Option) *testApp {
+	t.Helper()
+	cfg := testConfig()
+	if configure != nil {
+		configure(cfg)
+	}
+	mail := &capturingMailer{}
+	opts = append([]server.Option{server.WithMailer(mail)}, opts...)
+	s := server.New(cfg, deps, opts...)
+	// Let mail sent in the background finish before the storage goes away.
+	t.Cleanup(func() { s.Shutdown(context.Background()) })
+	return &testApp{Server: s, mail: mail}
+}
+
+type capturingMailer struct {
+	mu       sync.Mutex
+	messages []mailer.Message
+}
+
+func (m *capturingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+var mailedToken = regexp.MustCompile(`/([a-z-]+)\?token=(\S+)`)
+
+// token returns the token in the latest link to page mailed to address,
+// waiting a little for mail sent in the background.
+func (m *capturingMailer) token(t *testing.T, address, page string) string {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if token := m.latest(address, page); token != "" {
+			return token
+		}
+	}
+	t.Fatalf("no %s link mailed to %s", page, address)
+	return ""
+}
+
+func (m *capturingMailer) latest(address, page string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To != address {
+			continue
+		}
+		if match := mailedToken.FindStringSubmatch(m.messages[i].Body); match != nil && match[1] == page {
+			return match[2]
+		}
+	}
+	return ""
+}
+
+type request struct {
+	method  string
+	path    string
+	token   string
+	body    any
+	headers map[string]string
+}
+
+func (a *testApp) do(t *testing.T, r request) *httptest.ResponseRecorder {
+	t.Helper()
+	var body io.Reader
+	if r.body != nil {
+		data, err := json.Marshal(r.body)
+		if err != nil {
+			t.Fatalf("encode body: %v", err)
+		}
+		body = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(r.method, r.path, body)
+	if r.body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+	for name, value := range r.headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	a.Handler().ServeHTTP(w, req)
+	return w
+}
+
+// expect performs r and fails the test unless it answers with status. It
+// decodes the body into out when out is not nil.
+func (a *testApp) expect(t *testing.T, r request, status int, out any) *httptest.ResponseRecorder {
+	t.Helper()
+	w := a.do(t, r)
+	if w.Code != status {
+		t.Fatalf("%s %s = %d, want %d: %s", r.method, r.path, w.Code, status, w.Body)
+	}
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decode %s: %v", r.method, r.path, w.Body, err)
+		}
+	}
+	return w
+}
+
+// signUp creates a user, verifies its email and logs it in, returning the
+// user and an access token.
+func (a *testApp) signUp(t *testing.T, name string) (*handler.UserResponse, string) {
+	t.Helper()
+	email := name + "@example.com"
+	var user handler.UserResponse
+	a.expect(t, request{method: "POST", path: "/users", body: handler.CreateUserRequest{
+		Username: name, Email: email, Password: testPassword,
+	}}, http.StatusCreated, &user)
+	a.expect(t, request{method: "POST", path: "/auth/verify-email", body: handler.VerifyEmailRequest{
+		Token: a.mail.token(t, email, "verify-email"),
+	}}, http.StatusOK, nil)
+	return &user, a.login(t, email, testPassword)
+}
+
+func (a *testApp) login(t *testing.T, email, password string) string {
+	t.Helper()
+	var login handler.LoginResponse
+	a.expect(t, request{method: "POST", path: "/login", body: handler.LoginRequest{
+		Email: email, Password: password,
+	}}, http.StatusOK, &login)
+	return login.Token
+}
+
+func TestStartAndShutdown(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+	l.Close()
+
+	a := newTestApp(t, server.Dependencies{}, func(cfg *config.Config) {
+		cfg.Server.Port = port
+		cfg.Server.DrainDelay = 500 * time.Millisecond
+	})
+	started := make(chan error, 1)
+	go func() { started <- a.Start(context.Background()) }()
+
+	url := "http://127.0.0.1:" + port + "/healthz"
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not come up: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- a.Shutdown(ctx) }()
+
+	// During the drain delay the listener still answers, but not ready.
+	ready := "http://127.0.0.1:" + port + "/readyz"
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		resp, err := http.Get(ready)
+		if err != nil {
+			t.Fatalf("listener closed before the drain delay: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusServiceUnavailable {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("readiness probe still passes while draining")
+		}
+	}
+	select {
+	case err := <-shutdown:
+		t.Fatalf("shutdown returned %v before the drain delay", err)
+	default:
+	}
+
+	if err := <-shutdown; err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if err := <-started; err != nil {
+		t.Fatalf("start returned %v after shutdown, want nil", err)
+	}
+	if _, err := http.Get(url); err == nil {
+		t.Fatal("server still accepts connections after shutdown")
+	}
+}
+
+func TestOperationalRoutes(t *testing.T) {
+	forEachStorage(t, nil, func(t *testing.T, a *testApp) {
+		a.expect(t, request{method: "GET", path: "/healthz"}, http.StatusOK, nil)
+		a.expect(t, request{method: "GET", path: "/readyz"}, http.StatusOK, nil)
+		a.expect(t, request{method: "GET", path: "/swagger/index.html"}, http.StatusOK, nil)
+		a.expect(t, request{method: "BREW", path: "/healthz"}, http.StatusMethodNotAllowed, nil)
+
+		w := a.expect(t, request{method: "GET", path: "/metrics"}, http.StatusOK, nil)
+		if !strings.Contains(w.Body.String(), "tasked_tasks") {
+			t.Errorf("metrics lack the task gauges:\n%s", w.Body)
+		}
+		if strings.Contains(w.Body.String(), "BREW") || !strings.Contains(w.Body.String(), `method="OTHER"`) {
+			t.Error("metrics label requests with an arbitrary method")
+		}
+
+		var problem handler.Problem
+		a.expect(t, request{method: "GET", path: "/nowhere"}, http.StatusNotFound, &problem)
+		if problem.Code != "not_found" {
+			t.Errorf("unknown route code = %q, want not_found", problem.Code)
+		}
+		a.expect(t, request{method: "PATCH", path: "/healthz"}, http.StatusMethodNotAllowed, nil)
+
+		if err := a.Shutdown(context.Background()); err != nil {
+			t.Fatalf("shutdown: %v", err)
+		}
+		a.expect(t, request{method: "GET", path: "/readyz"}, http.StatusServiceUnavailable, nil)
+		a.expect(t, request{method: "GET", path: "/healthz"}, http.StatusOK, nil)
+	})
+}
+
+func TestUserRoutes(t *testing.T) {
+	forEachStorage(t, nil, func(t *testing.T, a *testApp) {
+		var user handler.UserResponse
+		a.expect(t, request{method: "POST", path: "/users", body: handler.CreateUserRequest{
+			Username: "ana", Email: "ana@example.com", Password: testPassword,
+		}}, http.StatusCreated, &user)
+		a.expect(t, request{method: "POST", path: "/users", body: handler.CreateUserRequest{
+			Username: "other", Email: "ana@example.com", Password: testPassword,
+		}}, http.StatusConflict, nil)
+		a.expect(t, request{method: "POST", path: "/login", body: handler.LoginRequest{
+			Email: "ana@example.com", Password: "wrong-passw0rd",
+		}}, http.StatusUnauthorized, nil)
+
+		// Unverified users can log in and read but not change anything.
+		token := a.login(t, "ana@example.com", testPassword)
+		userPath := fmt.Sprintf("/users/%d", user.ID)
+		update := handler.UpdateUserRequest{Username: "ana2", Email: "ana@example.com"}
+		a.expect(t, request{method: "PUT", path: userPath, token: token, body: update, headers: map[string]string{"If-Match": "*"}}, http.StatusForbidden, nil)
+
+		a.expect(t, request{method: "POST", path: "/auth/resend-verification", token: token}, http.StatusAccepted, nil)
+		a.expect(t, request{method: "POST", path: "/auth/verify-email", body: handler.VerifyEmailRequest{
+			Token: a.mail.token(t, "ana@example.com", "verify-email"),
+		}}, http.StatusOK, nil)
+		a.expect(t, request{method: "POST", path: "/auth/verify-email", body: handler.VerifyEmailRequest{
+			Token: a.mail.token(t, "ana@example.com", "verify-email"),
+		}}, http.StatusBadRequest, nil)
+
+		a.expect(t, request{method: "GET", path: userPath}, http.StatusUnauthorized, nil)
+		w := a.expect(t, request{method: "GET", path: userPath, token: token}, http.StatusOK, nil)
+		etag := w.Header().Get("ETag")
+		a.expect(t, request{method: "GET", path: userPath, token: token, headers: map[string]string{"If-None-Match": etag}}, http.StatusNotModified, nil)
+
+		a.expect(t, request{method: "PUT", path: userPath, token: token, body: update}, http.StatusPreconditionRequired, nil)
+		a.expect(t, request{method: "PUT", path: userPath, token: token, body: update, headers: map[string]string{"If-Match": etag}}, http.StatusOK, &user)
+		if user.Username != "ana2" {
+			t.Errorf("username = %q after update, want ana2", user.Username)
+		}
+		a.expect(t, request{method: "PUT", path: userPath, token: token, body: update, headers: map[string]string{"If-Match": etag}}, http.StatusPreconditionFailed, nil)
+
+		var changed handler.ChangePasswordResponse
+		a.expect(t, request{method: "PUT", path: userPath + "/password", token: token, body: handler.ChangePasswordRequest{
+			CurrentPassword: testPassword, NewPassword: "N3w-passw0rd",
+		}}, http.StatusOK, &changed)
+		a.login(t, "ana@example.com", "N3w-passw0rd")
+
+		a.expect(t, request{method: "POST", path: "/auth/forgot-password", body: handler.ForgotPasswordRequest{
+			Email: "ana@example.com",
+		}}, http.StatusAccepted, nil)
+		a.expect(t, request{method: "POST", path: "/auth/forgot-password", body: handler.ForgotPasswordRequest{
+			Email: "nobody@example.com",
+		}}, http.StatusAccepted, nil)
+		// A password the policy refuses leaves the token usable.
+		reset := a.mail.token(t, "ana@example.com", "reset-password")
+		a.expect(t, request{method: "POST", path: "/auth/reset-password", body: handler.ResetPasswordRequest{
+			Token: reset, Password: "Ana2-passw0rd",
+		}}, http.StatusBadRequest, nil)
+		a.expect(t, request{method: "POST", path: "/auth/reset-password", body: handler.ResetPasswordRequest{
+			Token: reset, Password: "R3set-passw0rd",
+		}}, http.StatusOK, nil)
+		token = a.login(t, "ana@example.com", "R3set-passw0rd")
+
+		a.expect(t, request{method: "DELETE", path: userPath, token: token, headers: map[string]string{"If-Match": "*"}}, http.StatusOK, nil)
+		a.expect(t, request{method: "POST", path: "/login", body: handler.LoginRequest{
+			Email: "ana@example.com", Password: "R3set-passw0rd",
+		}}, http.StatusUnauthorized, nil)
+	})
+}
+
+// stuckMailer holds every message until released and then fails to
+// deliver it.
+type stuckMailer struct {
+	release chan struct{}
+}
+
+func (m stuckMailer) Send(ctx context.Context, msg mailer.Message) error {
+	select {
+	case <-m.release:
+	case <-ctx.Done():
+	}
+	return errors.New("smtp: connection refused")
+}
+
+func TestForgotPasswordHidesDelivery(t *testing.T) {
+	mail := stuckMailer{release: make(chan struct{})}
+	a := newTestApp(t, server.Dependencies{}, nil, server.WithMailer(mail))
+	defer close(mail.release)
+
+	go func() { mail.release <- struct{}{} }() // the verification email
+	a.expect(t, request{method: "POST", path: "/users", body: handler.CreateUserRequest{
+		Username: "ana", Email: "ana@example.com", Password: testPassword,
+	}}, http.StatusCreated, nil)
+
+	for _, email := range []string{"ana@example.com", "nobody@example.com"} {
+		a.expect(t, request{method: "POST", path: "/auth/forgot-password", body: handler.ForgotPasswordRequest{
+			Email: email,
+		}}, http.StatusAccepted, nil)
+	}
+}
+
+func TestForgotPasswordLimit(t *testing.T) {
+	limits := func(cfg *config.Config) {
+		cfg.RateLimit.ResetIPLimit = 4
+		cfg.RateLimit.ResetEmailLimit = 2
+	}
+	forEachStorage(t, limits, func(t *testing.T, a *testApp) {
+		forgot := func(email string, status int) {
+			t.Helper()
+			a.expect(t, request{method: "POST", path: "/auth/forgot-password", body: handler.ForgotPasswordRequest{
+				Email: email,
+			}}, status, nil)
+		}
+
+		forgot("ana@example.com", http.StatusAccepted)
+		forgot("ANA@example.com", http.StatusAccepted)
+		forgot("ana@example.com", http.StatusTooManyRequests)
+		forgot("bea@example.com", http.StatusAccepted)
+		forgot("carla@example.com", http.StatusTooManyRequests)
+
+		// Reset requests have buckets of their own.
+		a.expect(t, request{method: "POST", path: "/login", body: handler.LoginRequest{
+			Email: "ana@example.com", Password: testPassword,
+		}}, http.StatusUnauthorized, nil)
+	})
+}
+
+func TestMFARoutes(t *testing.T) {
+	forEachStorage(t, nil, func(t *testing.T, a *testApp) {
+		_, token := a.signUp(t, "ana")
+
+		secret, recovery := a.enrollMFA(t, token)
+		step := auth.TOTPStep(time.Now())
+
+		challenge := a.mfaChallenge(t, "ana@example.com")
+		a.expect(t, request{method: "GET", path: "/sessions", token: challenge.MFAToken}, http.StatusUnauthorized, nil)
+		a.expect(t, request{method: "POST", path: "/auth/mfa", body: handler.MFAChallengeRequest{
+			MFAToken: challenge.MFAToken, Code: "000000",
+		}}, http.StatusUnauthorized, nil)
+
+		var login handler.LoginResponse
+		a.expect(t, request{method: "POST", path: "/auth/mfa", body: handler.MFAChallengeRequest{
+			MFAToken: challenge.MFAToken, Code: recovery[0],
+		}}, http.StatusOK, &login)
+		a.expect(t, request{method: "POST", path: "/auth/mfa", body: handler.MFAChallengeRequest{
+			MFAToken: challenge.MFAToken, Code: recovery[1],
+		}}, http.StatusUnauthorized, nil)
+
+		a.expect(t, request{method: "POST", path: "/auth/mfa/disable", token: login.Token, body: handler.MFADisableRequest{
+			Password: testPassword, Code: totpCode(t, secret, step+1),
+		}}, http.StatusOK, nil)
+		a.login(t, "ana@example.com", testPassword)
+	})
+}
+
+func TestMFALockout(t *testing.T) {
+	lockAfterThree := func(cfg *config.Config) {
+		cfg.RateLimit.LockoutThreshold = 3
+	}
+	forEachStorage(t, lockAfterThree, func(t *testing.T, a *testApp) {
+		_, token := a.signUp(t, "ana")
+		_, recovery := a.enrollMFA(t, token)
+
+		// Each guess comes with a fresh challenge, as an attacker who knows
+		// the password would do.
+		for range 3 {
+			a.expect(t, request{method: "POST", path: "/auth/mfa", body: handler.MFAChallengeRequest{
+				MFAToken: a.mfaChallenge(t, "ana@example.com").MFAToken, Code: "000000",
+			}}, http.StatusUnauthorized, nil)
+		}
+		w := a.expect(t, request{method: "POST", path: "/auth/mfa", body: handler.MFAChallengeRequest{
+			MFAToken: a.mfaChallenge(t, "ana@example.com").MFAToken, Code: recovery[0],
+		}}, http.StatusTooManyRequests, nil)
+		if w.Header().Get("Retry-After") == "" {
+			t.Error("lockout without Retry-After")
+		}
+	})
+}
+
+func TestChangePasswordLockout(t *testing.T) {
+	lockAfterThree := func(cfg *config.Config) {
+		cfg.RateLimit.LockoutThreshold = 3
+	}
+	forEachStorage(t, lockAfterThree, func(t *testing.T, a *testApp) {
+		user, token := a.signUp(t, "ana")
+		change := func(current string, status int) *httptest.ResponseRecorder {
+			return a.expect(t, request{method: "PUT", path: fmt.Sprintf("/users/%d/password", user.ID), token: token, body: handler.ChangePasswordRequest{
+				CurrentPassword: current, NewPassword: "N3w-passw0rd",
+			}}, status, nil)
+		}
+
+		// A stolen access token is not enough to guess the password.
+		for range 3 {
+			change("wrong-passw0rd", http.StatusForbidden)
+		}
+		w := change(testPassword, http.StatusTooManyRequests)
+		if w.Header().Get("Retry-After") == "" {
+			t.Error("lockout without Retry-After")
+		}
+	})
+}
+
+func TestForwardedFor(t *testing.T) {
+	twoPerIP := func(cfg *config.Config) {
+		cfg.RateLimit.LoginIPLimit = 2
+	}
+	attempt := func(t *testing.T, a *testApp, i int, forwardedFor string, status int) {
+		t.Helper()
+		a.expect(t, request{method: "POST", path: "/login", body: handler.LoginRequest{
+			Email: fmt.Sprintf("nobody%d@example.com", i), Password: testPassword,
+		}, headers: map[string]string{"X-Forwarded-For": forwardedFor}}, status, nil)
+	}
+
+	t.Run("forged", func(t *testing.T) {
+		forEachStorage(t, twoPerIP, func(t *testing.T, a *testApp) {
+			// A client cannot get a fresh bucket by claiming another address.
+			for i := range 2 {
+				attempt(t, a, i, fmt.Sprintf("198.51.100.%d", i), http.StatusUnauthorized)
+			}
+			attempt(t, a, 2, "198.51.100.2", http.StatusTooManyRequests)
+		})
+	})
+
+	t.Run("trusted proxy", func(t *testing.T) {
+		behindProxy := func(cfg *config.Config) {
+			twoPerIP(cfg)
+			cfg.Server.TrustedProxies = []string{"192.0.2.1"}
+		}
+		forEachStorage(t, behindProxy, func(t *testing.T, a *testApp) {
+			for i := range 2 {
+				attempt(t, a, i, "198.51.100.1", http.StatusUnauthorized)
+			}
+			attempt(t, a, 2, "198.51.100.1", http.StatusTooManyRequests)
+			attempt(t, a, 3, "198.51.100.2", http.StatusUnauthorized)
+		})
+	})
+}
+
+// enrollMFA turns on two-factor authentication for the token's user and
+// returns the TOTP secret and the recovery codes.
+func (a *testApp) enrollMFA(t *testing.T, token string) (string, []string) {
+	t.Helper()
+	var enroll handler.MFAEnrollResponse
+	a.expect(t, request{method: "POST", path: "/auth/mfa/enroll", token: token}, http.StatusOK, &enroll)
+	var recovery handler.MFARecoveryCodesResponse
+	a.expect(t, request{method: "POST", path: "/auth/mfa/verify", token: token, body: handler.MFACodeRequest{
+		Code: totpCode(t, enroll.Secret, auth.TOTPStep(time.Now())),
+	}}, http.StatusOK, &recovery)
+	if len(recovery.RecoveryCodes) < 2 {
+		t.Fatalf("got %d recovery codes", len(recovery.RecoveryCodes))
+	}
+	return enroll.Secret, recovery.RecoveryCodes
+}
+
+// mfaChallenge logs in with the test password and returns the challenge.
+func (a *testApp) mfaChallenge(t *testing.T, email string) handler.MFAChallengeResponse {
+	t.Helper()
+	var challenge handler.MFAChallengeResponse
+	a.expect(t, request{method: "POST", path: "/login", body: handler.LoginRequest{
+		Email: email, Password: testPassword,
+	}}, http.StatusOK, &challenge)
+	if !challenge.MFARequired || challenge.MFAToken == "" {
+		t.Fatalf("login with 2FA = %+v, want a challenge", challenge)
+	}
+	return challenge
+}
+
+func totpCode(t *testing.T, secret string, step int64) string {
+	t.Helper()
+	code, err := auth.TOTPCode(secret, step)
+	if err != nil {
+		t.Fatalf("totp code: %v", err)
+	}
+	return code
+}
+
+// newOIDCProvider starts a provider that signs everyone in as ana, and
+// returns it with the configuration pointing tasked at it.
+func newOIDCProvider(t *testing.T) func(cfg *config.Config) {
+	t.Helper()
+	var provider *oidctest.Server
+	issuer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provider.ServeHTTP(w, r)
+	}))
+	t.Cleanup(issuer.Close)
+	provider, err := oidctest.New(issuer.URL, "tasked", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.SetUser(oidctest.User{
+		Subject:           "sso-ana",
+		Email:             "ana@example.com",
+		EmailVerified:     true,
+		PreferredUsername: "ana",
+	})
+
+	return func(cfg *config.Config) {
+		cfg.OIDC.Issuer = issuer.URL
+		cfg.OIDC.ClientID = "tasked"
+		cfg.OIDC.ClientSecret = "secret"
+		cfg.OIDC.RedirectURL = "http://tasked.test/auth/oidc/callback"
+		cfg.OIDC.AutoCreate = true
+	}
+}
+
+// oidcLogin signs in through the provider and returns the response of the
+// callback.
+func (a *testApp) oidcLogin(t *testing.T) *httptest.ResponseRecorder {
+	t.Helper()
+	w := a.expect(t, request{method: "GET", path: "/auth/oidc/login"}, http.StatusFound, nil)
+	cookies := w.Result().Cookies()
+	if len(cookies) == 0 {
+		t.Fatal("login set no state cookie")
+	}
+
+	// The provider signs the user in at once and sends the browser back
+	// with a code.
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || callback.Query().Get("code") == "" {
+		t.Fatalf("provider redirected to %q", resp.Header.Get("Location"))
+	}
+
+	path := "/auth/oidc/callback?" + callback.RawQuery
+	a.expect(t, request{method: "GET", path: path}, http.StatusBadRequest, nil)
+	return a.do(t, request{method: "GET", path: path, headers: map[string]string{
+		"Cookie": cookies[0].Name + "=" + cookies[0].Value,
+	}})
+}
+
+func TestOIDCRoutes(t *testing.T) {
+	forEachStorage(t, newOIDCProvider(t), func(t *testing.T, a *testApp) {
+		w := a.oidcLogin(t)
+		var login handler.LoginResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &login); w.Code != http.StatusOK || err != nil {
+			t.Fatalf("callback = %d: %s", w.Code, w.Body)
+		}
+		if login.Token == "" || login.User.Email != "ana@example.com" {
+			t.Fatalf("callback = %+v, want a token for ana@example.com", login)
+		}
+		a.expect(t, request{method: "GET", path: "/sessions", token: login.Token}, http.StatusOK, nil)
+	})
+}
+
+// TestOIDCUnverifiedAccount has someone register ana's email with their own
+// password before ana first signs in through the provider.
+func TestOIDCUnverifiedAccount(t *testing.T) {
+	forEachStorage(t, newOIDCProvider(t), func(t *testing.T, a *testApp) {
+		var squatter handler.UserResponse
+		a.expect(t, request{method: "POST", path: "/users", body: handler.CreateUserRequest{
+			Username: "squatter", Email: "ana@example.com", Password: testPassword,
+		}}, http.StatusCreated, &squatter)
+
+		if w := a.oidcLogin(t); w.Code != http.StatusConflict {
+			t.Fatalf("callback = %d, want 409 for an unverified account: %s", w.Code, w.Body)
+		}
+		token := a.login(t, "ana@example.com", testPassword)
+		var own handler.UserResponse
+		a.expect(t, request{method: "GET", path: fmt.Sprintf("/users/%d", squatter.ID), token: token}, http.StatusOK, &own)
+		if own.EmailVerifiedAt != nil {
+			t.Fatalf("email verified at %v by a refused link", own.EmailVerifiedAt)
+		}
+
+		// Ana proves she owns the address by resetting the password, which
+		// locks the registrant out and lets the provider link.
+		a.expect(t, request{method: "POST", path: "/auth/forgot-password", body: handler.ForgotPasswordRequest{
+			Email: "ana@example.com",
+		}}, http.StatusAccepted, nil)
+		a.expect(t, request{method: "POST", path: "/auth/reset-password", body: handler.ResetPasswordRequest{
+			Token: a.mail.token(t, "ana@example.com", "reset-password"), Password: "An4s-own-passw0rd",
+		}}, http.StatusOK, nil)
+		a.expect(t, request{method: "POST", path: "/login", body: handler.LoginRequest{
+			Email: "ana@example.com", Password: testPassword,
+		}}, http.StatusUnauthorized, nil)
+
+		w := a.oidcLogin(t)
+		var login handler.LoginResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &login); w.Code != http.StatusOK || err != nil {
+			t.Fatalf("callback after verifying = %d: %s", w.Code, w.Body)
+		}
+		if login.User.ID != squatter.ID {
+			t.Errorf("signed in as user %d, want the linked account %d", login.User.ID, squatter.ID)
+		}
+	})
+}
+
+func TestAccessTokenRoutes(t *testing.T) {
+	forEachStorage(t, nil, func(t *testing.T, a *testApp) {
+		user, token := a.signUp(t, "ana")
+
+		var created handler.CreateAccessTokenResponse
+		a.expect(t, request{method: "POST", path: "/tokens", token: token, body: handler.CreateAccessTokenRequest{
+			Name: "ci", Scopes: []string{domain.ScopeTasksRead}, ExpiresInDays: 30,
+		}}, http.StatusCreated, &created)
+		pat := created.Token
+
+		tasksPath := fmt.Sprintf("/users/%d/tasks", user.ID)
+		a.expect(t, request{method: "GET", path: tasksPath, token: pat}, http.StatusOK, nil)
+		a.expect(t, request{method: "POST", path: "/tasks", token: pat, body: handler.CreateTaskRequest{
+			Title: "no", UserID: user.ID,
+		}}, http.StatusForbidden, nil)
+		a.expect(t, request{method: "GET", path: "/tokens", token: pat}, http.StatusForbidden, nil)
+
+		var tokens []domain.PersonalAccessToken
+		a.expect(t, request{method: "GET", path: "/tokens", token: token}, http.StatusOK, &tokens)
+		if len(tokens) != 1 || tokens[0].Name != "ci" {
+			t.Fatalf("tokens = %+v, want the ci token", tokens)
+		}
+
+		tokenPath := fmt.Sprintf("/tokens/%d", created.AccessToken.ID)
+		a.expect(t, request{method: "DELETE", path: tokenPath, token: token}, http.StatusOK, nil)
+		a.expect(t, request{method: "DELETE", path: tokenPath, token: token}, http.StatusNotFound, nil)
+		a.expect(t, request{method: "GET", path: tasksPath, token: pat}, http.StatusUnauthorized, nil)
+	})
+}
+
+func TestSessionRoutes(t *testing.T) {
+	forEachStorage(t, nil, func(t *testing.T, a *testApp) {
+		_, first := a.signUp(t, "ana")
+		second := a.login(t, "ana@example.com", testPassword)
+		third := a.login(t, "ana@example.com", testPassword)
+
+		var sessions []handler.SessionResponse
+		a.expect(t, request{method: "GET", path: "/sessions", token: first}, http.StatusOK, &sessions)
+		if len(sessions) != 3 {
+			t.Fatalf("%d sessions, want 3", len(sessions))
+		}
+		var other string
+		for _, session := range sessions {
+			if !session.Current {
+				other = session.ID
+			}
+		}
+
+		a.expect(t, request{method: "DELETE", path: "/sessions/" + other, token: first}, http.StatusOK, nil)
+		a.expect(t, request{method: "DELETE", path: "/sessions/" + other, token: first}, http.StatusNotFound, nil)
+
+		var revoked handler.RevokeSessionsResponse
+		a.expect(t, request{method: "DELETE", path: "/sessions", token: first}, http.StatusOK, &revoked)
+		if revoked.Revoked != 1 {
+			t.Errorf("revoked %d sessions, want 1", revoked.Revoked)
+		}
+		for _, token := range []string{second, third} {
+			a.expect(t, request{method: "GET", path: "/sessions", token: token}, http.StatusUnauthorized, nil)
+		}
+		a.expect(t, request{method: "GET", path: "/sessions", token: first}, http.StatusOK, nil)
+	})
+}
+
+func TestTaskRoutes(t *testing.T) {
+	forEachStorage(t, nil, func(t *testing.T, a *testApp) {
+		user, token := a.signUp(t, "ana")
+
+		create := request{method: "POST", path: "/tasks", token: token, body: handler.CreateTaskRequest{
+			Title: "Informe", Priority: "high", UserID: user.ID, DueDate: "2030-01-31",
+		}, headers: map[string]string{"Idempotency-Key": "create-informe"}}
+		var task domain.Task
+		a.expect(t, create, http.StatusCreated, &task)
+		replay := a.expect(t, create, http.StatusCreated, nil)
+		if replay.Header().Get("Idempotent-Replayed") != "true" {
+			t.Error("repeated create was not replayed")
+		}
+		a.expect(t, request{method: "POST", path: "/tasks", token: token, body: handler.CreateTaskRequest{
+			Title: "Huérfana", UserID: user.ID + 1000,
+		}}, http.StatusBadRequest, nil)
+
+		taskPath := fmt.Sprintf("/tasks/%d", task.Id)
+		w := a.expect(t, request{method: "GET", path: taskPath, token: token}, http.StatusOK, nil)
+		etag := w.Header().Get("ETag")
+		a.expect(t, request{method: "GET", path: taskPath, token: token, headers: map[string]string{"If-None-Match": etag}}, http.StatusNotModified, nil)
+		a.expect(t, request{method: "GET", path: "/tasks/999999", token: token}, http.StatusNotFound, nil)
+
+		var tasks []domain.Task
+		a.expect(t, request{method: "GET", path: fmt.Sprintf("/users/%d/tasks", user.ID), token: token}, http.StatusOK, &tasks)
+		if len(tasks) != 1 {
+			t.Fatalf("%d tasks listed, want 1", len(tasks))
+		}
+
+		update := handler.UpdateTaskRequest{Title: "Informe mensual", Status: "in_progress"}
+		a.expect(t, request{method: "PUT", path: taskPath, token: token, body: update}, http.StatusPreconditionRequired, nil)
+		w = a.expect(t, request{method: "PUT", path: taskPath, token: token, body: update, headers: map[string]string{"If-Match": etag}}, http.StatusOK, &task)
+		a.expect(t, request{method: "PUT", path: taskPath, token: token, body: update, headers: map[string]string{"If-Match": etag}}, http.StatusPreconditionFailed, nil)
+		etag = w.Header().Get("ETag")
+
+		description := "Con gráficas"
+		w = a.expect(t, request{method: "PATCH", path: taskPath, token: token, body: handler.PatchTaskRequest{
+			Description: &description,
+		}, headers: map[string]string{"If-Match": etag}}, http.StatusOK, &task)
+		if task.Title != "Informe mensual" || task.Description != description {
+			t.Errorf("patched task = %+v", task)
+		}
+		etag = w.Header().Get("ETag")
+
+		a.expect(t, request{method: "PATCH", path: taskPath + "/status", token: token, body: handler.UpdateStatusRequest{
+			Status: "completed",
+		}, headers: map[string]string{"If-Match": etag}}, http.StatusOK, nil)
+
+		var batch handler.BatchTasksResponse
+		a.expect(t, request{method: "POST", path: "/tasks/batch", token: token, body: handler.BatchTasksRequest{
+			Mode: "atomic",
+			Operations: []handler.BatchOperationRequest{
+				{Op: "create", Title: "Revisar", UserID: user.ID},
+				{Op: "status", ID: task.Id, Status: "pending"},
+			},
+		}}, http.StatusOK, &batch)
+		if !batch.Committed || len(batch.Results) != 2 {
+			t.Fatalf("batch = %+v, want two committed results", batch)
+		}
+		a.expect(t, request{method: "GET", path: fmt.Sprintf("/users/%d/tasks", user.ID), token: token}, http.StatusOK, &tasks)
+		if len(tasks) != 2 {
+			t.Fatalf("%d tasks after batch, want 2", len(tasks))
+		}
+
+		a.expect(t, request{method: "DELETE", path: taskPath, token: token}, http.StatusPreconditionRequired, nil)
+		a.expect(t, request{method: "DELETE", path: taskPath, token: token, headers: map[string]string{"If-Match": "*"}}, http.StatusOK, nil)
+		a.expect(t, request{method: "GET", path: taskPath, token: token}, http.StatusNotFound, nil)
+		a.expect(t, request{method: "GET", path: taskPath}, http.StatusUnauthorized, nil)
+	})
+}
+
+func TestIfMatch(t *testing.T) {
+	forEachStorage(t, nil, func(t *testing.T, a *testApp) {
+		user, token := a.signUp(t, "ana")
+		var task domain.Task
+		a.expect(t, request{method: "POST", path: "/tasks", token: token, body: handler.CreateTaskRequest{
+			Title: "Informe", UserID: user.ID,
+		}}, http.StatusCreated, &task)
+		taskPath := fmt.Sprintf("/tasks/%d", task.Id)
+		update := handler.UpdateTaskRequest{Title: "Informe mensual"}
+
+		for _, tc := range []struct {
+			ifMatch string
+			status  int
+		}{
+			{`1`, http.StatusBadRequest},
+			{`"1`, http.StatusBadRequest},
+			{`"1"x`, http.StatusBadRequest},
+			{`*, "1"`, http.StatusBadRequest},
+			{`W/"1"`, http.StatusPreconditionFailed},
+			{`"draft", W/"1"`, http.StatusPreconditionFailed},
+			{`"7", "8"`, http.StatusPreconditionFailed},
+			{`"7", W/"2", "1"`, http.StatusOK},
+			{`"2"`, http.StatusOK},
+			{`*`, http.StatusOK},
+		} {
+			w := a.do(t, request{method: "PUT", path: taskPath, token: token, body: update, headers: map[string]string{"If-Match": tc.ifMatch}})
+			if w.Code != tc.status {
+				t.Errorf("If-Match: %s = %d, want %d: %s", tc.ifMatch, w.Code, tc.status, w.Body)
+			}
+		}
+
+		// "*" matches whatever version a concurrent writer leaves behind.
+		var wg sync.WaitGroup
+		codes := make([]int, 8)
+		for i := range codes {
+			wg.Go(func() {
+				w := a.do(t, request{method: "PUT", path: taskPath, token: token, body: update, headers: map[string]string{"If-Match": "*"}})
+				codes[i] = w.Code
+			})
+		}
+		wg.Wait()
+		for _, code := range codes {
+			if code != http.StatusOK {
+				t.Errorf("concurrent If-Match: * answered %v, want all 200", codes)
+				break
+			}
+		}
+
+		a.expect(t, request{method: "DELETE", path: "/tasks/999999", token: token, headers: map[string]string{"If-Match": "*"}}, http.StatusNotFound, nil)
+		a.expect(t, request{method: "DELETE", path: "/tasks/999999", token: token, headers: map[string]string{"If-Match": `"1", "2"`}}, http.StatusNotFound, nil)
+	})
+}
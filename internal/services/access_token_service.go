@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"tasked/internal/clock"
+	"tasked/internal/domain"
+	apperrors "tasked/internal/errors"
+	"tasked/internal/repository"
+	"tasked/internal/utils"
+	"time"
+)
+
+const (
+	maxAccessTokenNameLength = 100
+	maxAccessTokenLifetime   = 366 * 24 * time.Hour
+)
+
+// AccessTokenService manages personal access tokens: long-lived, scoped
+// credentials for scripts and CI. Only a hash of each token is stored.
+type AccessTokenService struct {
+	tokens repository.AccessTokenRepository
+	users  repository.UserRepository
+	clock  clock.Clock
+}
+
+func NewAccessTokenService(tokens repository.AccessTokenRepository, users repository.UserRepository, clock clock.Clock) *AccessTokenService {
+	return &AccessTokenService{
+		tokens: tokens,
+		users:  users,
+		clock:  clock,
+	}
+}
+
+// Create issues a token valid for lifetime and returns it in clear text.
+// This is the only time it is available.
+func (s *AccessTokenService) Create(ctx context.Context, userID int64, name string, scopes []string, lifetime time.Duration) (string, *domain.PersonalAccessToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > maxAccessTokenNameLength {
+		return "", nil, apperrors.BadRequest("name must be between 1 and %d characters", maxAccessTokenNameLength)
+	}
+	if len(scopes) == 0 {
+		return "", nil, apperrors.ErrBadRequest.WithMessage("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(domain.Scopes, scope) {
+			return "", nil, apperrors.BadRequest("unknown scope %q", scope)
+		}
+	}
+	if lifetime <= 0 || lifetime > maxAccessTokenLifetime {
+		return "", nil, apperrors.ErrBadRequest.WithMessage("expiry must be in the future and at most a year away")
+	}
+
+	secret, err := utils.GenerateSecureToken()
+	if err != nil {
+		return "", nil, err
+	}
+	token := domain.AccessTokenPrefix + secret
+	expiresAt := s.clock.Now().Add(lifetime)
+
+	slices.Sort(scopes)
+	pat, err := s.tokens.CreateToken(ctx, userID, name, utils.HashToken(token), slices.Compact(scopes), expiresAt)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, pat, nil
+}
+
+func (s *AccessTokenService) List(ctx context.Context, userID int64) ([]*domain.PersonalAccessToken, error) {
+	return s.tokens.ListTokens(ctx, userID)
+}
+
+func (s *AccessTokenService) Delete(ctx context.Context, userID int64, id int64) error {
+	err := s.tokens.DeleteToken(ctx, id, userID)
+	return err
+}
+
+// Authenticate resolves a token presented as a bearer credential to its
+// owner and records that it was used.
+func (s *AccessTokenService) Authenticate(ctx context.Context, token string) (*domain.PersonalAccessToken, *domain.User, error) {
+	pat, err := s.tokens.GetTokenByHash(ctx, utils.HashToken(token))
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return nil, nil, apperrors.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.users.GetUserById(ctx, pat.UserID)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return nil, nil, apperrors.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.tokens.TouchToken(ctx, pat.ID); err != nil {
+		return nil, nil, err
+	}
+	return pat, user, nil
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"tasked/internal/clock"
+	"tasked/internal/domain"
+	apperrors "tasked/internal/errors"
+	"tasked/internal/mailer"
+	"tasked/internal/repository"
+	"tasked/internal/utils"
+	"time"
+)
+
+// Email verification policies: with "login" unverified users cannot sign in,
+// with "restricted" they can sign in but not modify anything.
+const (
+	VerificationOff        = "off"
+	VerificationLogin      = "login"
+	VerificationRestricted = "restricted"
+)
+
+const (
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 48 * time.Hour
+
+	backgroundMailTimeout = time.Minute
+)
+
+// AccountService implements the flows that prove control of an email
+// address: password recovery and email verification.
+type AccountService struct {
+	users              repository.UserRepository
+	tokens             repository.UserTokenRepository
+	mailer             mailer.Mailer
+	policy             utils.PasswordPolicy
+	baseURL            string
+	verificationPolicy string
+	clock              clock.Clock
+	background         sync.WaitGroup
+}
+
+func NewAccountService(users repository.UserRepository, tokens repository.UserTokenRepository, m mailer.Mailer, policy utils.PasswordPolicy, baseURL string, verificationPolicy string, clock clock.Clock) *AccountService {
+	return &AccountService{
+		users:              users,
+		tokens:             tokens,
+		mailer:             m,
+		policy:             policy,
+		baseURL:            baseURL,
+		verificationPolicy: verificationPolicy,
+		clock:              clock,
+	}
+}
+
+// ForgotPassword mails a reset link in the background when the email
+// belongs to an account. It returns at once whether or not the account
+// exists, so neither the response nor its timing reveals which addresses
+// are registered. Failures are logged.
+func (s *AccountService) ForgotPassword(ctx context.Context, email string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), backgroundMailTimeout)
+	s.background.Go(func() {
+		defer cancel()
+		if err := s.sendPasswordReset(ctx, email); err != nil {
+			slog.ErrorContext(ctx, "failed to send password reset email", "error", err)
+		}
+	})
+}
+
+// Wait blocks until mail sent in the background has been handed over or
+// given up on.
+func (s *AccountService) Wait() {
+	s.background.Wait()
+}
+
+func (s *AccountService) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := s.users.GetUserByEmail(ctx, email)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := s.issueToken(ctx, user.ID, domain.TokenPasswordReset, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Restablece tu contraseña de Tasked",
+		Body: fmt.Sprintf("Hola %s,\n\nPara elegir una nueva contraseña abre este enlace:\n\n%s/reset-password?token=%s\n\nEl enlace caduca en una hora. Si no lo solicitaste, ignora este mensaje.\n",
+			user.Username, s.baseURL, token),
+	})
+}
+
+// ResetPassword spends a reset token and replaces the password, revoking
+// existing sessions. Since the token arrived by email, it also counts as
+// proof that the email is valid.
+func (s *AccountService) ResetPassword(ctx context.Context, token, password string) error {
+	tokenHash := utils.HashToken(token)
+	// The policy needs the user's name and email, so look the token up
+	// first and spend it only once the password is acceptable.
+	owner, err := s.tokens.FindToken(ctx, domain.TokenPasswordReset, tokenHash)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return apperrors.ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+	user, err := s.users.GetUserById(ctx, owner)
+	if err != nil {
+		return err
+	}
+	if err := s.policy.Validate(password, user.Username, user.Email); err != nil {
+		return apperrors.ErrBadRequest.WithMessage(err.Error()).WithDetail("field", "password")
+	}
+
+	userID, err := s.tokens.ConsumeToken(ctx, domain.TokenPasswordReset, tokenHash)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return apperrors.ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+
+	hashed, err := utils.HashedPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := s.users.UpdatePassword(ctx, userID, hashed); err != nil {
+		return err
+	}
+	if err := s.tokens.DeleteTokens(ctx, userID, domain.TokenPasswordReset); err != nil {
+		return err
+	}
+	return s.users.MarkEmailVerified(ctx, userID)
+}
+
+// SendVerification mails a verification link unless the email is already
+// verified. Earlier links for the same user stop working.
+func (s *AccountService) SendVerification(ctx context.Context, user *domain.User) error {
+	if !user.EmailVerifiedAt.IsZero() {
+		return nil
+	}
+
+	token, err := s.issueToken(ctx, user.ID, domain.TokenEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Confirma tu correo en Tasked",
+		Body: fmt.Sprintf("Hola %s,\n\nConfirma tu dirección de correo abriendo este enlace:\n\n%s/verify-email?token=%s\n\nEl enlace caduca en 48 horas.\n",
+			user.Username, s.baseURL, token),
+	})
+}
+
+func (s *AccountService) ResendVerification(ctx context.Context, userID int64) error {
+	user, err := s.users.GetUserById(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.SendVerification(ctx, user)
+}
+
+func (s *AccountService) VerifyEmail(ctx context.Context, token string) error {
+	userID, err := s.tokens.ConsumeToken(ctx, domain.TokenEmailVerification, utils.HashToken(token))
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return apperrors.ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.users.MarkEmailVerified(ctx, userID); err != nil {
+		return err
+	}
+	return s.tokens.DeleteTokens(ctx, userID, domain.TokenEmailVerification)
+}
+
+// LoginAllowed applies the "login" verification policy.
+func (s *AccountService) LoginAllowed(user *domain.User) bool {
+	return s.verificationPolicy != VerificationLogin || !user.EmailVerifiedAt.IsZero()
+}
+
+func (s *AccountService) IsEmailVerified(ctx context.Context, userID int64) (bool, error) {
+	user, err := s.users.GetUserById(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return !user.EmailVerifiedAt.IsZero(), nil
+}
+
+func (s *AccountService) PurgeExpiredTokens(ctx context.Context) (int64, error) {
+	return s.tokens.DeleteExpiredTokens(ctx)
+}
+
+func (s *AccountService) issueToken(ctx context.Context, userID int64, purpose string, ttl time.Duration) (string, error) {
+	if err := s.tokens.DeleteTokens(ctx, userID, purpose); err != nil {
+		return "", err
+	}
+	token, err := utils.GenerateSecureToken()
+	if err != nil {
+		return "", err
+	}
+	if err := s.tokens.CreateToken(ctx, userID, purpose, utils.HashToken(token), s.clock.Now().Add(ttl)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
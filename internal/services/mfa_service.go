@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+	"tasked/internal/auth"
+	"tasked/internal/clock"
+	"tasked/internal/domain"
+	apperrors "tasked/internal/errors"
+	"tasked/internal/repository"
+	"tasked/internal/utils"
+)
+
+const recoveryCodeCount = 10
+
+// MFAService manages opt-in TOTP two-factor authentication.
+type MFAService struct {
+	users  repository.UserRepository
+	mfa    repository.MFARepository
+	tokens repository.UserTokenRepository
+	issuer string
+	clock  clock.Clock
+}
+
+func NewMFAService(users repository.UserRepository, mfa repository.MFARepository, tokens repository.UserTokenRepository, issuer string, clock clock.Clock) *MFAService {
+	return &MFAService{
+		users:  users,
+		mfa:    mfa,
+		tokens: tokens,
+		issuer: issuer,
+		clock:  clock,
+	}
+}
+
+// Enroll creates a new TOTP secret for the user. It only takes effect once
+// Activate confirms that the user's authenticator produces valid codes.
+func (s *MFAService) Enroll(ctx context.Context, userID int64) (secret string, uri string, err error) {
+	user, err := s.users.GetUserById(ctx, userID)
+	if err != nil {
+		return "", "", err
+	}
+	if user.MFAEnabled {
+		return "", "", apperrors.ErrConflict.WithMessage("two-factor authentication is already enabled")
+	}
+
+	secret, err = auth.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.mfa.SetPendingSecret(ctx, userID, secret); err != nil {
+		return "", "", err
+	}
+	return secret, auth.TOTPProvisioningURI(s.issuer, user.Email, secret), nil
+}
+
+// Activate enables two-factor authentication after checking a code from the
+// pending secret. The returned recovery codes are never shown again.
+func (s *MFAService) Activate(ctx context.Context, userID int64, code string) ([]string, error) {
+	mfa, err := s.mfa.GetMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !mfa.EnabledAt.IsZero() {
+		return nil, apperrors.ErrConflict.WithMessage("two-factor authentication is already enabled")
+	}
+	if mfa.Secret == "" {
+		return nil, apperrors.ErrBadRequest.WithMessage("enrollment has not been started")
+	}
+
+	if err := s.checkTOTP(ctx, userID, mfa.Secret, code); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		codes[i] = newRecoveryCode()
+		hashes[i] = utils.HashToken(normalizeRecoveryCode(codes[i]))
+	}
+	if err := s.mfa.EnableMFA(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify accepts either a current TOTP code or an unused recovery code.
+func (s *MFAService) Verify(ctx context.Context, userID int64, code string) error {
+	mfa, err := s.mfa.GetMFA(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if mfa.EnabledAt.IsZero() {
+		return apperrors.ErrBadRequest.WithMessage("two-factor authentication is not enabled")
+	}
+
+	if len(strings.TrimSpace(code)) == 6 {
+		return s.checkTOTP(ctx, userID, mfa.Secret, code)
+	}
+
+	used, err := s.mfa.UseRecoveryCode(ctx, userID, utils.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return apperrors.ErrInvalidCredentials.WithMessage("invalid code")
+	}
+	return nil
+}
+
+// StartChallenge records a pending second factor for the user and returns
+// its ID, to be carried in the challenge token.
+func (s *MFAService) StartChallenge(ctx context.Context, userID int64) (string, error) {
+	id, err := utils.GenerateSecureToken()
+	if err != nil {
+		return "", err
+	}
+	expiresAt := s.clock.Now().Add(auth.MFAChallengeTTL)
+	if err := s.tokens.CreateToken(ctx, userID, domain.TokenMFAChallenge, utils.HashToken(id), expiresAt); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// CompleteChallenge checks the code and spends the challenge, so a challenge
+// token cannot be redeemed for a second session.
+func (s *MFAService) CompleteChallenge(ctx context.Context, userID int64, challengeID, code string) error {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+	owner, err := s.tokens.ConsumeToken(ctx, domain.TokenMFAChallenge, utils.HashToken(challengeID))
+	if errors.Is(err, apperrors.ErrNotFound) || err == nil && owner != userID {
+		return apperrors.ErrInvalidCredentials.WithMessage("mfa token already used")
+	}
+	return err
+}
+
+// Disable turns two-factor authentication off. It asks for the password as
+// well as a code so a stolen session alone cannot weaken the account.
+func (s *MFAService) Disable(ctx context.Context, userID int64, password, code string) error {
+	user, err := s.users.GetUserById(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !utils.VerifyPassword(user.Password, password) {
+		return apperrors.ErrInvalidCredentials.WithMessage("invalid password")
+	}
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+	return s.mfa.DisableMFA(ctx, userID)
+}
+
+func (s *MFAService) checkTOTP(ctx context.Context, userID int64, secret, code string) error {
+	step, ok := auth.ValidateTOTP(secret, code, s.clock.Now())
+	if !ok {
+		return apperrors.ErrInvalidCredentials.WithMessage("invalid code")
+	}
+	fresh, err := s.mfa.UseTOTPStep(ctx, userID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return apperrors.ErrInvalidCredentials.WithMessage("invalid code")
+	}
+	return nil
+}
+
+// Recovery codes look like "k3f9x-2mq7p": about 50 random bits, written
+// without characters that are easy to confuse.
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+func newRecoveryCode() string {
+	size := big.NewInt(int64(len(recoveryAlphabet)))
+	b := make([]byte, 10)
+	for i := range b {
+		// Uniform, unlike a random byte modulo the alphabet size. Reading
+		// from rand.Reader never fails.
+		n, _ := rand.Int(rand.Reader, size)
+		b[i] = recoveryAlphabet[n.Int64()]
+	}
+	return string(b[:5]) + "-" + string(b[5:])
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"tasked/internal/auth"
+	"tasked/internal/clock"
+	"tasked/internal/domain"
+	apperrors "tasked/internal/errors"
+	"tasked/internal/repository"
+	"tasked/internal/utils"
+	"time"
+)
+
+const (
+	oidcLoginStateTTL = 10 * time.Minute
+	maxUsernameLength = 50
+)
+
+// OIDCService signs users in through an external OpenID Connect provider.
+// Identities are linked to users by (issuer, subject); the first login of an
+// identity links it to the user with the same email, provided both the
+// provider and the local account have verified that email.
+type OIDCService struct {
+	provider   *auth.OIDCProvider
+	users      repository.UserRepository
+	oidc       repository.OIDCRepository
+	autoCreate bool
+	clock      clock.Clock
+}
+
+func NewOIDCService(provider *auth.OIDCProvider, users repository.UserRepository, oidc repository.OIDCRepository, autoCreate bool, clock clock.Clock) *OIDCService {
+	return &OIDCService{
+		provider:   provider,
+		users:      users,
+		oidc:       oidc,
+		autoCreate: autoCreate,
+		clock:      clock,
+	}
+}
+
+// Begin starts a login and returns the provider URL to redirect to and the
+// state, which the caller must bind to the browser and pass back to Complete.
+func (s *OIDCService) Begin(ctx context.Context) (authURL string, state string, err error) {
+	state, err = utils.GenerateSecureToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := utils.GenerateSecureToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier := auth.GenerateCodeVerifier()
+
+	err = s.oidc.SaveLoginState(ctx, utils.HashToken(state), verifier, nonce, s.clock.Now().Add(oidcLoginStateTTL))
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err = s.provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// Complete redeems the authorization code of the login identified by state
+// and returns the user it signs in, linking or creating one if needed.
+func (s *OIDCService) Complete(ctx context.Context, state, code string) (*domain.User, error) {
+	verifier, nonce, err := s.oidc.ConsumeLoginState(ctx, utils.HashToken(state))
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return nil, apperrors.ErrInvalidToken.WithMessage("invalid or expired state")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := s.provider.Exchange(ctx, code, verifier, nonce)
+	if err != nil {
+		return nil, apperrors.ErrInvalidCredentials.WithMessage("identity provider login failed").Wrap(err)
+	}
+
+	user, err := s.oidc.GetUserByIdentity(ctx, identity.Issuer, identity.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, apperrors.ErrNotFound) {
+		return nil, err
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, apperrors.ErrForbidden.WithMessage("the identity provider has not verified this email")
+	}
+
+	user, err = s.users.GetUserByEmail(ctx, identity.Email)
+	if err == nil {
+		// Anyone can register an address they do not own. Linking such an
+		// account would hand the provider's user an account whose password
+		// the registrant still knows.
+		if user.EmailVerifiedAt.IsZero() {
+			return nil, apperrors.ErrConflict.WithMessage("an account with this email has not verified it; reset its password to prove ownership, then sign in again")
+		}
+		return s.link(ctx, user, identity)
+	}
+	if !errors.Is(err, apperrors.ErrNotFound) {
+		return nil, err
+	}
+
+	if !s.autoCreate {
+		return nil, apperrors.ErrForbidden.WithMessage("no account is registered with this email")
+	}
+	return s.create(ctx, identity)
+}
+
+func (s *OIDCService) link(ctx context.Context, user *domain.User, identity *auth.OIDCIdentity) (*domain.User, error) {
+	if err := s.oidc.LinkIdentity(ctx, user.ID, identity.Issuer, identity.Subject, identity.Email); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// create registers a user for an identity that matches no account. The
+// password is random and never shown, so signing in with a password requires
+// going through password recovery first.
+func (s *OIDCService) create(ctx context.Context, identity *auth.OIDCIdentity) (*domain.User, error) {
+	username, err := s.availableUsername(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := utils.GenerateSecureToken()
+	if err != nil {
+		return nil, err
+	}
+	passwordHashed, err := utils.HashedPassword(password)
+	if err != nil {
+		return nil, apperrors.ErrInternalServer.Wrap(err)
+	}
+
+	return s.oidc.CreateUserWithIdentity(ctx, username, identity.Email, passwordHashed, identity.Issuer, identity.Subject)
+}
+
+// availableUsername derives a username from the identity, adding a random
+// suffix when it is already taken.
+func (s *OIDCService) availableUsername(ctx context.Context, identity *auth.OIDCIdentity) (string, error) {
+	base := identity.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(identity.Email, "@")
+	}
+	base = truncate(base, maxUsernameLength)
+
+	candidate := base
+	for range 5 {
+		exists, err := s.oidc.UsernameExists(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+
+		suffix := make([]byte, 3)
+		if _, err := rand.Read(suffix); err != nil {
+			return "", err
+		}
+		candidate = truncate(base, maxUsernameLength-7) + "-" + hex.EncodeToString(suffix)
+	}
+	return "", apperrors.ErrConflict.WithMessage("could not find a free username")
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
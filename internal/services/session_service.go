@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"tasked/internal/clock"
+	"tasked/internal/domain"
+	apperrors "tasked/internal/errors"
+	"tasked/internal/repository"
+	"tasked/internal/utils"
+	"time"
+)
+
+const (
+	// How long a validated session is trusted without asking the database.
+	// Revocations made by another instance, or by a password change, take
+	// up to this long to be enforced here.
+	sessionCacheTTL = 30 * time.Second
+	// Above this many entries, expired ones are dropped on insert.
+	sessionCacheSweepSize = 10000
+	maxUserAgentLength    = 512
+)
+
+type cachedSession struct {
+	session  *domain.Session
+	cachedAt time.Time
+}
+
+// SessionService tracks the sign-ins behind issued JWTs so they can be
+// listed and revoked individually.
+type SessionService struct {
+	repo  repository.SessionRepository
+	clock clock.Clock
+
+	mu    sync.Mutex
+	cache map[string]cachedSession
+}
+
+func NewSessionService(repo repository.SessionRepository, clock clock.Clock) *SessionService {
+	return &SessionService{
+		repo:  repo,
+		clock: clock,
+		cache: make(map[string]cachedSession),
+	}
+}
+
+// Create records a sign-in that stays valid for ttl.
+func (s *SessionService) Create(ctx context.Context, userID int64, userAgent, ip string, ttl time.Duration) (*domain.Session, error) {
+	id, err := utils.GenerateSecureToken()
+	if err != nil {
+		return nil, err
+	}
+	session, err := s.repo.CreateSession(ctx, id, userID, truncate(userAgent, maxUserAgentLength), ip, s.clock.Now().Add(ttl))
+	if err != nil {
+		return nil, err
+	}
+	s.put(session)
+	return session, nil
+}
+
+// Validate checks that a session is still active and belongs to userID.
+func (s *SessionService) Validate(ctx context.Context, id string, userID int64) error {
+	session, ok := s.get(id)
+	if !ok {
+		var err error
+		session, err = s.repo.TouchActiveSession(ctx, id)
+		if errors.Is(err, apperrors.ErrNotFound) {
+			return apperrors.ErrInvalidToken
+		}
+		if err != nil {
+			return err
+		}
+		s.put(session)
+	}
+
+	if session.UserID != userID || !session.ExpiresAt.After(s.clock.Now()) {
+		return apperrors.ErrInvalidToken
+	}
+	return nil
+}
+
+func (s *SessionService) List(ctx context.Context, userID int64) ([]*domain.Session, error) {
+	return s.repo.ListActiveSessions(ctx, userID)
+}
+
+func (s *SessionService) Revoke(ctx context.Context, userID int64, id string) error {
+	err := s.repo.RevokeSession(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	s.forget(id)
+	return nil
+}
+
+// RevokeOthers signs the user out everywhere except currentID, which may be
+// empty to sign out everywhere.
+func (s *SessionService) RevokeOthers(ctx context.Context, userID int64, currentID string) (int, error) {
+	ids, err := s.repo.RevokeOtherSessions(ctx, userID, currentID)
+	if err != nil {
+		return 0, err
+	}
+	s.forget(ids...)
+	return len(ids), nil
+}
+
+// PurgeStale deletes expired sessions and those revoked over a day ago.
+func (s *SessionService) PurgeStale(ctx context.Context) (int64, error) {
+	return s.repo.DeleteStaleSessions(ctx, s.clock.Now().Add(-24*time.Hour))
+}
+
+func (s *SessionService) get(id string) (*domain.Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.cache[id]
+	if !ok || s.clock.Now().Sub(entry.cachedAt) > sessionCacheTTL {
+		return nil, false
+	}
+	return entry.session, true
+}
+
+func (s *SessionService) put(session *domain.Session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	if len(s.cache) >= sessionCacheSweepSize {
+		for id, entry := range s.cache {
+			if now.Sub(entry.cachedAt) > sessionCacheTTL {
+				delete(s.cache, id)
+			}
+		}
+	}
+	s.cache[session.ID] = cachedSession{session: session, cachedAt: now}
+}
+
+func (s *SessionService) forget(ids ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		delete(s.cache, id)
+	}
+}
@@ -2,8 +2,14 @@ package services
 
 import (
 	"context"
+	"errors"
 	"tasked/internal/domain"
+	apperrors "tasked/internal/errors"
 	"tasked/internal/repository"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type TaskService struct {
@@ -14,26 +20,186 @@ func NewTaskService(repo repository.TaskRepository) *TaskService {
 	return &TaskService{repo: repo}
 }
 
-func (s *TaskService) GetTaskById(ctx context.Context, id int64) (*domain.Task, error) {
+func (s *TaskService) GetTaskById(ctx context.Context, id int64) (task *domain.Task, err error) {
+	ctx, span := tracer.Start(ctx, "TaskService.GetTaskById", trace.WithAttributes(attribute.Int64("task.id", id)))
+	defer endSpan(span, &err)
+
 	return s.repo.GetTaskById(ctx, id)
 }
 
-func (s *TaskService) ListTaskByUser(ctx context.Context, userId int64) ([]domain.Task, error) {
+func (s *TaskService) ListTaskByUser(ctx context.Context, userId int64) (tasks []domain.Task, err error) {
+	ctx, span := tracer.Start(ctx, "TaskService.ListTaskByUser", trace.WithAttributes(attribute.Int64("user.id", userId)))
+	defer endSpan(span, &err)
+
 	return s.repo.ListTaskByUser(ctx, userId)
 }
 
-func (s *TaskService) UpdateTask(ctx context.Context, id int64, title string, description string, status string, priority string, dueDate string) (*domain.Task, error) {
-	return s.repo.UpdateTask(ctx, id, title, description, status, priority, dueDate)
+func (s *TaskService) TaskStats(ctx context.Context) (stats *domain.TaskStats, err error) {
+	ctx, span := tracer.Start(ctx, "TaskService.TaskStats")
+	defer endSpan(span, &err)
+
+	return s.repo.GetTaskStats(ctx)
+}
+
+// UpdateTask and the other mutating methods take the version the caller last
+// saw; a version of 0 means "any version" and resolves to the current one.
+func (s *TaskService) UpdateTask(ctx context.Context, id int64, version int64, title string, description string, status string, priority string, dueDate string) (task *domain.Task, err error) {
+	ctx, span := tracer.Start(ctx, "TaskService.UpdateTask", trace.WithAttributes(attribute.Int64("task.id", id)))
+	defer endSpan(span, &err)
+
+	task, err = s.repo.UpdateTask(ctx, id, version, title, description, status, priority, dueDate)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return nil, s.conflict(ctx, id)
+	}
+	return task, err
+}
+
+func (s *TaskService) PatchTask(ctx context.Context, id int64, version int64, patch domain.TaskPatch) (task *domain.Task, err error) {
+	ctx, span := tracer.Start(ctx, "TaskService.PatchTask", trace.WithAttributes(attribute.Int64("task.id", id)))
+	defer endSpan(span, &err)
+
+	task, err = s.repo.PatchTask(ctx, id, version, patch)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return nil, s.conflict(ctx, id)
+	}
+	return task, err
 }
 
-func (s *TaskService) DeleteTask(ctx context.Context, id int64) error {
-	return s.repo.DeleteTask(ctx, id)
+func (s *TaskService) DeleteTask(ctx context.Context, id int64, version int64) (err error) {
+	ctx, span := tracer.Start(ctx, "TaskService.DeleteTask", trace.WithAttributes(attribute.Int64("task.id", id)))
+	defer endSpan(span, &err)
+
+	err = s.repo.DeleteTask(ctx, id, version)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return s.conflict(ctx, id)
+	}
+	return err
 }
 
-func (s *TaskService) UpdateStatus(ctx context.Context, id int64, status string) (*domain.Task, error) {
-	return s.repo.UpdateStatus(ctx, id, status)
+func (s *TaskService) UpdateStatus(ctx context.Context, id int64, version int64, status string) (task *domain.Task, err error) {
+	ctx, span := tracer.Start(ctx, "TaskService.UpdateStatus", trace.WithAttributes(attribute.Int64("task.id", id)))
+	defer endSpan(span, &err)
+
+	task, err = s.repo.UpdateStatus(ctx, id, version, status)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return nil, s.conflict(ctx, id)
+	}
+	return task, err
 }
 
-func (s *TaskService) CreateTask(ctx context.Context, title string, description string, status string, priority string, userId int64, dueDate string) (*domain.Task, error) {
+func (s *TaskService) CreateTask(ctx context.Context, title string, description string, status string, priority string, userId int64, dueDate string) (task *domain.Task, err error) {
+	ctx, span := tracer.Start(ctx, "TaskService.CreateTask", trace.WithAttributes(attribute.Int64("user.id", userId)))
+	defer endSpan(span, &err)
+
 	return s.repo.CreateTask(ctx, title, description, status, priority, userId, dueDate)
 }
+
+// RunBatch applies ops inside a single transaction. In atomic mode the first
+// failing operation rolls everything back: its result carries the cause, the
+// others carry ErrBatchAborted, and that cause is returned as well. Otherwise
+// each operation runs in its own savepoint and failures are only reported in
+// the results. Any other returned error comes from the transaction itself.
+func (s *TaskService) RunBatch(ctx context.Context, ops []domain.TaskOperation, atomic bool) (_ []domain.TaskOperationResult, err error) {
+	ctx, span := tracer.Start(ctx, "TaskService.RunBatch", trace.WithAttributes(
+		attribute.Int("batch.size", len(ops)),
+		attribute.Bool("batch.atomic", atomic),
+	))
+	defer endSpan(span, &err)
+
+	results := make([]domain.TaskOperationResult, len(ops))
+	for i, op := range ops {
+		results[i] = domain.TaskOperationResult{Index: i, Op: op.Op}
+	}
+
+	var failed error
+	err = s.repo.WithTx(ctx, func(tx repository.TaskTx) error {
+		txService := &TaskService{repo: tx}
+		for i, op := range ops {
+			if atomic {
+				task, err := txService.apply(ctx, op)
+				if err != nil {
+					results[i].Err = err
+					failed = err
+					return err
+				}
+				results[i].Task = task
+				continue
+			}
+
+			var task *domain.Task
+			var opErr error
+			err := tx.Savepoint(ctx, func() error {
+				task, opErr = txService.apply(ctx, op)
+				return opErr
+			})
+			if opErr != nil {
+				if err != opErr {
+					return err
+				}
+				results[i].Err = opErr
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			results[i].Task = task
+		}
+		return nil
+	})
+
+	if failed != nil {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i].Task = nil
+				results[i].Err = apperrors.ErrBatchAborted
+			}
+		}
+		return results, failed
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *TaskService) apply(ctx context.Context, op domain.TaskOperation) (*domain.Task, error) {
+	if op.DueDate != "" {
+		if _, err := time.Parse("2006-01-02", op.DueDate); err != nil {
+			return nil, apperrors.ErrBadRequest.WithMessage("due_date must use the YYYY-MM-DD format")
+		}
+	}
+	if op.Op != "create" && op.ID == 0 {
+		return nil, apperrors.ErrBadRequest.WithMessage("id is required")
+	}
+
+	switch op.Op {
+	case "create":
+		if op.Title == "" || op.UserID == 0 {
+			return nil, apperrors.ErrBadRequest.WithMessage("title and user_id are required")
+		}
+		return s.CreateTask(ctx, op.Title, op.Description, op.Status, op.Priority, op.UserID, op.DueDate)
+	case "update":
+		if op.Title == "" {
+			return nil, apperrors.ErrBadRequest.WithMessage("title is required")
+		}
+		return s.UpdateTask(ctx, op.ID, op.Version, op.Title, op.Description, op.Status, op.Priority, op.DueDate)
+	case "status":
+		if op.Status == "" {
+			return nil, apperrors.ErrBadRequest.WithMessage("status is required")
+		}
+		return s.UpdateStatus(ctx, op.ID, op.Version, op.Status)
+	case "delete":
+		return nil, s.DeleteTask(ctx, op.ID, op.Version)
+	default:
+		return nil, apperrors.BadRequest("unknown operation %q", op.Op)
+	}
+}
+
+// conflict explains why a versioned write matched no rows: either the task
+// is gone or somebody else updated it first.
+func (s *TaskService) conflict(ctx context.Context, id int64) error {
+	if _, err := s.repo.GetTaskById(ctx, id); err != nil {
+		return err
+	}
+	return apperrors.ErrPreconditionFailed.WithMessage("task has been modified")
+}
@@ -7,49 +7,110 @@ import (
 	apperrors "tasked/internal/errors"
 	"tasked/internal/repository"
 	"tasked/internal/utils"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type UserService struct {
-	repo repository.UserRepository
+	repo   repository.UserRepository
+	policy utils.PasswordPolicy
 }
 
-func NewUserService(repo repository.UserRepository) *UserService {
-	return &UserService{repo: repo}
+func NewUserService(repo repository.UserRepository, policy utils.PasswordPolicy) *UserService {
+	return &UserService{repo: repo, policy: policy}
 }
 
-func (s *UserService) GetUser(ctx context.Context, id int64) (*domain.User, error) {
-	user, err := s.repo.GetUserById(ctx, id)
-	if err != nil {
-		return nil, apperrors.ErrNotFound
-	}
-	return user, nil
+func (s *UserService) GetUser(ctx context.Context, id int64) (user *domain.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUser", trace.WithAttributes(attribute.Int64("user.id", id)))
+	defer endSpan(span, &err)
+
+	return s.repo.GetUserById(ctx, id)
 }
 
-func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
+func (s *UserService) GetUserByEmail(ctx context.Context, email string) (user *domain.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUserByEmail")
+	defer endSpan(span, &err)
+
 	if !utils.ValidateEmail(email) {
-		return nil, errors.New("Invalid email format")
+		return nil, apperrors.ErrBadRequest.WithMessage("invalid email format").WithDetail("field", "email")
 	}
 	return s.repo.GetUserByEmail(ctx, email)
 }
 
-func (s *UserService) CreateUser(ctx context.Context, username, email, password string) (*domain.User, error) {
+func (s *UserService) CreateUser(ctx context.Context, username, email, password string) (user *domain.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.CreateUser")
+	defer endSpan(span, &err)
+
 	if !utils.ValidateEmail(email) {
-		return nil, apperrors.ErrBadRequest
+		return nil, apperrors.ErrBadRequest.WithMessage("invalid email format").WithDetail("field", "email")
+	}
+	if err := s.policy.Validate(password, username, email); err != nil {
+		return nil, apperrors.ErrBadRequest.WithMessage(err.Error()).WithDetail("field", "password")
 	}
 	passwordHashed, err := utils.HashedPassword(password)
 	if err != nil {
-		return nil, apperrors.ErrInternalServer
+		return nil, apperrors.ErrInternalServer.Wrap(err)
 	}
 	return s.repo.CreateUser(ctx, username, email, passwordHashed)
 }
 
-func (s *UserService) UpdateUser(ctx context.Context, id int64, username, email string) (*domain.User, error) {
+func (s *UserService) UpdateUser(ctx context.Context, id int64, version int64, username, email string) (user *domain.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.UpdateUser", trace.WithAttributes(attribute.Int64("user.id", id)))
+	defer endSpan(span, &err)
+
 	if !utils.ValidateEmail(email) {
-		return nil, errors.New("Invalid email format")
+		return nil, apperrors.ErrBadRequest.WithMessage("invalid email format").WithDetail("field", "email")
+	}
+	user, err = s.repo.UpdateUser(ctx, id, version, username, email)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return nil, s.conflict(ctx, id)
+	}
+	return user, err
+}
+
+func (s *UserService) DeleteUser(ctx context.Context, id int64, version int64) (err error) {
+	ctx, span := tracer.Start(ctx, "UserService.DeleteUser", trace.WithAttributes(attribute.Int64("user.id", id)))
+	defer endSpan(span, &err)
+
+	err = s.repo.DeleteUser(ctx, id, version)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return s.conflict(ctx, id)
 	}
-	return s.repo.UpdateUser(ctx, id, username, email)
+	return err
 }
 
-func (s *UserService) DeleteUser(ctx context.Context, id int64) error {
-	return s.repo.DeleteUser(ctx, id)
+// ChangePassword replaces the password after checking the current one.
+// Storing the new hash also revokes every token issued before the change.
+func (s *UserService) ChangePassword(ctx context.Context, id int64, currentPassword, newPassword string) (err error) {
+	ctx, span := tracer.Start(ctx, "UserService.ChangePassword", trace.WithAttributes(attribute.Int64("user.id", id)))
+	defer endSpan(span, &err)
+
+	user, err := s.repo.GetUserById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !utils.VerifyPassword(user.Password, currentPassword) {
+		return apperrors.ErrInvalidCredentials.WithMessage("current password is incorrect")
+	}
+	if currentPassword == newPassword {
+		return apperrors.ErrBadRequest.WithMessage("new password must be different from the current one")
+	}
+	if err := s.policy.Validate(newPassword, user.Username, user.Email); err != nil {
+		return apperrors.ErrBadRequest.WithMessage(err.Error()).WithDetail("field", "password")
+	}
+
+	hashed, err := utils.HashedPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	return s.repo.UpdatePassword(ctx, id, hashed)
+}
+
+func (s *UserService) conflict(ctx context.Context, id int64) error {
+	if _, err := s.repo.GetUserById(ctx, id); err != nil {
+		return err
+	}
+	return apperrors.ErrPreconditionFailed.WithMessage("user has been modified")
 }
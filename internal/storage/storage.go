@@ -0,0 +1,109 @@
+// Package storage opens the database named by DATABASE_URL and pairs it
+// with the migrations and repositories of its engine, chosen by the URL
+// scheme: postgres:// or postgresql:// for Postgres, sqlite:// for SQLite.
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"tasked/internal/database/migrations"
+	sqlitemigrations "tasked/internal/database/sqlite/migrations"
+	"tasked/internal/migrate"
+	"tasked/internal/repository"
+	"tasked/internal/tracing"
+
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+// sqliteOptions make the driver store times as sortable UTC text and turn
+// on foreign keys, which SQLite leaves off by default.
+const sqliteOptions = "_time_format=sqlite&_timezone=UTC&_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+
+type Database struct {
+	*sql.DB
+	Dialect Dialect
+}
+
+// Open connects to the database at rawURL. SQLite URLs name a file, as in
+// sqlite:///var/lib/tasked.db or sqlite://tasked.db for a relative path,
+// or sqlite://:memory: for a database that lives as long as the process.
+func Open(ctx context.Context, rawURL string) (*Database, error) {
+	scheme, rest, ok := strings.Cut(rawURL, "://")
+	if !ok {
+		return nil, fmt.Errorf("DATABASE_URL must start with postgres:// or sqlite://")
+	}
+
+	var (
+		db      *sql.DB
+		dialect Dialect
+		err     error
+	)
+	switch scheme {
+	case "postgres", "postgresql":
+		dialect = Postgres
+		db, err = tracing.OpenDB("postgres", rawURL)
+	case "sqlite":
+		dialect = SQLite
+		db, err = tracing.OpenDB("sqlite", sqliteDSN(rest))
+		if err == nil {
+			// One connection serializes writes, which SQLite allows one at
+			// a time anyway, and keeps a :memory: database alive.
+			db.SetMaxOpenConns(1)
+			db.SetConnMaxIdleTime(0)
+			db.SetConnMaxLifetime(0)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported DATABASE_URL scheme %q, use postgres or sqlite", scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Database{DB: db, Dialect: dialect}, nil
+}
+
+// sqliteDSN turns the part of a sqlite:// URL after the scheme into a DSN
+// for the driver. Options in the URL override the defaults, and its
+// _pragma values run after the default ones.
+func sqliteDSN(path string) string {
+	path, query, _ := strings.Cut(path, "?")
+	options, _ := url.ParseQuery(query)
+	defaults, _ := url.ParseQuery(sqliteOptions)
+	for key, values := range defaults {
+		if key == "_pragma" || options[key] == nil {
+			options[key] = append(values, options[key]...)
+		}
+	}
+	return path + "?" + options.Encode()
+}
+
+// Migrator returns the migrations of the database's engine.
+func (d *Database) Migrator() (*migrate.Migrator, error) {
+	if d.Dialect == SQLite {
+		return migrate.New(d.DB, sqlitemigrations.FS, migrate.SQLite)
+	}
+	return migrate.New(d.DB, migrations.FS, migrate.Postgres)
+}
+
+// Repositories returns repositories backed by the database.
+func (d *Database) Repositories() *repository.Repositories {
+	if d.Dialect == SQLite {
+		return repository.NewSQLiteRepositories(d.DB)
+	}
+	return repository.NewPostgresRepositories(d.DB)
+}
@@ -0,0 +1,155 @@
+package storage_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"tasked/internal/migrate"
+	"tasked/internal/pgtest"
+	"tasked/internal/storage"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+func TestSQLiteMigrations(t *testing.T) {
+	testMigrations(t, "sqlite://"+filepath.Join(t.TempDir(), "tasked.db"))
+}
+
+func TestPostgresMigrations(t *testing.T) {
+	testMigrations(t, pgtest.New(t))
+}
+
+// testMigrations applies every migration, reverts them all and applies them
+// again, so each down file must undo its up file completely.
+func testMigrations(t *testing.T, url string) {
+	ctx := context.Background()
+	db, err := storage.Open(ctx, url)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	migrator, err := db.Migrator()
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	total := len(statuses)
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if len(applied) != total {
+		t.Fatalf("up applied %d of %d migrations", len(applied), total)
+	}
+	assertPending(t, migrator, 0)
+
+	again, err := migrator.Up(ctx)
+	if err != nil || len(again) != 0 {
+		t.Fatalf("second up = %d migrations, %v; want none", len(again), err)
+	}
+
+	reverted, err := migrator.Down(ctx, total)
+	if err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	if len(reverted) != total {
+		t.Fatalf("down reverted %d of %d migrations", len(reverted), total)
+	}
+	assertPending(t, migrator, total)
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("up after down: %v", err)
+	}
+	assertPending(t, migrator, 0)
+}
+
+func assertPending(t *testing.T, migrator *migrate.Migrator, want int) {
+	t.Helper()
+	migrations, err := migrator.Pending(context.Background())
+	if err != nil {
+		t.Fatalf("pending: %v", err)
+	}
+	if len(migrations) != want {
+		t.Fatalf("%d pending migrations, want %d", len(migrations), want)
+	}
+}
+
+func TestSQLitePasswordNotUnique(t *testing.T) {
+	testPasswordNotUnique(t, "sqlite://"+filepath.Join(t.TempDir(), "tasked.db"))
+}
+
+func TestPostgresPasswordNotUnique(t *testing.T) {
+	testPasswordNotUnique(t, pgtest.New(t))
+}
+
+// testPasswordNotUnique applies the migration dropping UNIQUE from
+// users.password over existing rows. On SQLite it rebuilds users, which must
+// not cascade to the tasks referencing them.
+func testPasswordNotUnique(t *testing.T, url string) {
+	ctx := context.Background()
+	db, err := storage.Open(ctx, url)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	migrator, err := db.Migrator()
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	i := slices.IndexFunc(statuses, func(s migrate.Status) bool { return s.Name == "users_password_not_unique" })
+	if i < 0 {
+		t.Fatal("no users_password_not_unique migration")
+	}
+	if _, err := migrator.Down(ctx, len(statuses)-i); err != nil {
+		t.Fatalf("down: %v", err)
+	}
+
+	insertUser := func(name string) error {
+		_, err := db.ExecContext(ctx, `INSERT INTO users (username, email, password, created_at, updated_at)
+			VALUES ($1, $2, 'same-hash', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`, name, name+"@example.com")
+		return err
+	}
+	if err := insertUser("ana"); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `INSERT INTO tasks (title, user_id, created_at, updated_at)
+		SELECT 'report', id, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP FROM users`); err != nil {
+		t.Fatalf("insert task: %v", err)
+	}
+	if err := insertUser("bea"); err == nil {
+		t.Fatal("second user with the same password inserted before the migration")
+	}
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	var tasks int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM tasks`).Scan(&tasks); err != nil || tasks != 1 {
+		t.Fatalf("%d tasks after the migration (%v), want 1", tasks, err)
+	}
+	if err := insertUser("bea"); err != nil {
+		t.Fatalf("second user with the same password: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `DELETE FROM users WHERE username = 'ana'`); err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM tasks`).Scan(&tasks); err != nil || tasks != 0 {
+		t.Fatalf("%d tasks after deleting their user (%v), want the cascade to remove it", tasks, err)
+	}
+}
@@ -0,0 +1,81 @@
+// Package tracing configures OpenTelemetry: W3C trace context propagation,
+// the OTLP exporter and the instrumented database driver.
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/XSAM/otelsql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+)
+
+// Setup installs the global propagator and tracer provider. Inbound
+// traceparent and baggage headers are always honored; spans are only
+// exported when endpoint, the base URL of an OTLP/HTTP collector such as
+// http://localhost:4318, is set. The returned function flushes pending spans.
+func Setup(ctx context.Context, endpoint, serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpointURL(strings.TrimSuffix(endpoint, "/")+"/v1/traces"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// OpenDB opens a database whose queries, including those run inside
+// transactions, are traced. Spans are named after the sqlc query.
+func OpenDB(driverName, dsn string) (*sql.DB, error) {
+	system := semconv.DBSystemNamePostgreSQL
+	if driverName == "sqlite" {
+		system = semconv.DBSystemNameSQLite
+	}
+	return otelsql.Open(driverName, dsn,
+		otelsql.WithAttributes(system),
+		otelsql.WithSpanNameFormatter(spanName),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitRows:             true,
+		}),
+	)
+}
+
+// spanName turns the "-- name: GetTaskByID :one" header sqlc puts on every
+// query into "GetTaskByID", falling back to the driver method.
+func spanName(_ context.Context, method otelsql.Method, query string) string {
+	if name, ok := strings.CutPrefix(query, "-- name: "); ok {
+		if name, _, ok := strings.Cut(name, " "); ok {
+			return name
+		}
+	}
+	return string(method)
+}
@@ -1,9 +1,17 @@
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"errors"
+	"tasked/internal/metrics"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
 
 func HashedPassword(password string) (string, error) {
+	start := time.Now()
 	passwordHashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	observeBcrypt(metrics.BcryptHash, start, err)
 	if err != nil {
 		return "", err
 	}
@@ -11,10 +19,25 @@ func HashedPassword(password string) (string, error) {
 }
 
 func CheckPassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	start := time.Now()
+	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	observeBcrypt(metrics.BcryptCompare, start, err)
+	return err
 }
 
 func VerifyPassword(hashedPassword, password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
-	return err == nil
+	return CheckPassword(hashedPassword, password) == nil
+}
+
+func observeBcrypt(op string, start time.Time, err error) {
+	metrics.BcryptDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+
+	result := metrics.BcryptOK
+	switch {
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		result = metrics.BcryptMismatch
+	case err != nil:
+		result = metrics.BcryptError
+	}
+	metrics.BcryptOperations.WithLabelValues(op, result).Inc()
 }
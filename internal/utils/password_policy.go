@@ -0,0 +1,92 @@
+package utils
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsList string
+
+var commonPasswords = func() map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, line := range strings.Split(commonPasswordsList, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			set[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	return set
+}()
+
+// bcrypt ignores everything past 72 bytes, so longer passwords would give a
+// false sense of security.
+const maxPasswordBytes = 72
+
+// PasswordPolicy describes what a new password must satisfy. MinClasses
+// counts how many of lowercase, uppercase, digits and symbols must appear.
+type PasswordPolicy struct {
+	MinLength      int
+	MinClasses     int
+	CheckBlocklist bool
+}
+
+// Validate checks password against the policy. userInputs are values such
+// as the username or email that the password must not contain.
+func (p PasswordPolicy) Validate(password string, userInputs ...string) error {
+	if len([]rune(password)) < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters long", p.MinLength)
+	}
+	if len(password) > maxPasswordBytes {
+		return fmt.Errorf("password must be at most %d bytes long", maxPasswordBytes)
+	}
+
+	if classes := characterClasses(password); classes < p.MinClasses {
+		return fmt.Errorf("password must mix at least %d of lowercase letters, uppercase letters, digits and symbols", p.MinClasses)
+	}
+
+	lower := strings.ToLower(password)
+	if p.CheckBlocklist {
+		if _, found := commonPasswords[lower]; found {
+			return errors.New("password is too common")
+		}
+	}
+
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		if at := strings.Index(input, "@"); at >= 0 {
+			input = input[:at]
+		}
+		if len(input) >= 3 && strings.Contains(lower, input) {
+			return errors.New("password must not contain your username or email")
+		}
+	}
+
+	return nil
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+	return classes
+}